@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package adc provides a common interface to analog to digital converters,
+// with conversion to voltages, calibration, oversampling and continuous
+// sampling.
+package adc
+
+import (
+	"errors"
+)
+
+// ADC is an analog to digital converter.
+//
+// It is implemented by the drivers in the spi subpackages, such as adc0832 and
+// mcp3w0c.
+type ADC interface {
+	// Channels returns the number of single ended channels.
+	Channels() int
+
+	// Resolution returns the number of bits in the values read.
+	Resolution() uint
+
+	// Read returns the raw value read from a single ended channel.
+	Read(ch int) (int, error)
+}
+
+// Calibration corrects the raw values read from an ADC channel.
+//
+// The corrected value is (raw - Offset) * Gain.
+type Calibration struct {
+	// The raw value read when the input is zero.
+	Offset float64
+
+	// The scaling applied to the value after the offset is removed.
+	Gain float64
+}
+
+// Converter converts the raw values read from an ADC into calibrated values
+// and voltages.
+type Converter struct {
+	adc ADC
+
+	// the reference voltage, corresponding to full scale
+	vref float64
+
+	// 2^resolution
+	fullScale float64
+
+	// the number of reads averaged for each value
+	oversampling int
+
+	cals []Calibration
+
+	// Error from construction options
+	err error
+}
+
+// New creates a Converter for the ADC with the reference voltage.
+//
+// The reference voltage is the voltage corresponding to a raw value of 2^N,
+// where N is the resolution of the ADC.
+func New(a ADC, vref float64, options ...Option) (*Converter, error) {
+	if vref <= 0 {
+		return nil, ErrInvalidVref
+	}
+	c := Converter{
+		adc:          a,
+		vref:         vref,
+		fullScale:    float64(uint64(1) << a.Resolution()),
+		oversampling: 1,
+		cals:         make([]Calibration, a.Channels()),
+	}
+	for i := range c.cals {
+		c.cals[i].Gain = 1
+	}
+	for _, option := range options {
+		option(&c)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &c, nil
+}
+
+// ADC returns the ADC being converted.
+func (c *Converter) ADC() ADC {
+	return c.adc
+}
+
+// Vref returns the reference voltage of the ADC.
+func (c *Converter) Vref() float64 {
+	return c.vref
+}
+
+// Value returns the calibrated value of a channel.
+//
+// If oversampling, this is the mean of the samples read.
+func (c *Converter) Value(ch int) (float64, error) {
+	if ch < 0 || ch >= len(c.cals) {
+		return 0, ErrInvalidChannel
+	}
+	sum := 0
+	for i := 0; i < c.oversampling; i++ {
+		v, err := c.adc.Read(ch)
+		if err != nil {
+			return 0, err
+		}
+		sum += v
+	}
+	cal := c.cals[ch]
+	return (float64(sum)/float64(c.oversampling) - cal.Offset) * cal.Gain, nil
+}
+
+// Voltage returns the calibrated voltage of a channel.
+func (c *Converter) Voltage(ch int) (float64, error) {
+	v, err := c.Value(ch)
+	if err != nil {
+		return 0, err
+	}
+	return c.ToVoltage(v), nil
+}
+
+// ToVoltage converts a calibrated value to a voltage.
+func (c *Converter) ToVoltage(v float64) float64 {
+	return v * c.vref / c.fullScale
+}
+
+// Option specifies a construction option for the Converter.
+type Option func(*Converter)
+
+// WithCalibration sets the calibration for the channels.
+//
+// If no channels are specified then the calibration applies to all channels.
+//
+// The default is no correction, i.e. an Offset of 0 and a Gain of 1.
+func WithCalibration(cal Calibration, channels ...int) Option {
+	return func(c *Converter) {
+		if cal.Gain == 0 {
+			c.err = ErrInvalidGain
+			return
+		}
+		if len(channels) == 0 {
+			for i := range c.cals {
+				c.cals[i] = cal
+			}
+			return
+		}
+		for _, ch := range channels {
+			if ch < 0 || ch >= len(c.cals) {
+				c.err = ErrInvalidChannel
+				return
+			}
+			c.cals[ch] = cal
+		}
+	}
+}
+
+// WithOversampling sets the number of reads averaged to produce each value.
+//
+// The default is 1.
+func WithOversampling(n int) Option {
+	return func(c *Converter) {
+		if n < 1 {
+			c.err = ErrInvalidOversampling
+			return
+		}
+		c.oversampling = n
+	}
+}
+
+var (
+	// ErrClosed indicates the Sampler has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrInvalidChannel indicates the channel is not supported by the ADC.
+	ErrInvalidChannel = errors.New("invalid channel")
+
+	// ErrInvalidGain indicates a calibration gain of zero.
+	ErrInvalidGain = errors.New("invalid gain")
+
+	// ErrInvalidOversampling indicates the oversampling is less than 1.
+	ErrInvalidOversampling = errors.New("invalid oversampling")
+
+	// ErrInvalidRate indicates the sampling rate is not positive.
+	ErrInvalidRate = errors.New("invalid rate")
+
+	// ErrInvalidSize indicates the buffer size is less than 1.
+	ErrInvalidSize = errors.New("invalid size")
+
+	// ErrInvalidVref indicates the reference voltage is not positive.
+	ErrInvalidVref = errors.New("invalid vref")
+)
@@ -0,0 +1,292 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package adc_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod/adc"
+	"github.com/warthog618/gpiod/spi/adc0832"
+	"github.com/warthog618/gpiod/spi/mcp3w0c"
+)
+
+// the existing drivers implement the interface
+var (
+	_ adc.ADC = (*adc0832.ADC0832)(nil)
+	_ adc.ADC = (*mcp3w0c.MCP3w0c)(nil)
+)
+
+func TestNew(t *testing.T) {
+	a := &fakeADC{channels: 4, resolution: 10}
+
+	patterns := []struct {
+		name    string
+		vref    float64
+		options []adc.Option
+		err     error
+	}{
+		{"zero vref", 0, nil, adc.ErrInvalidVref},
+		{"negative vref", -3.3, nil, adc.ErrInvalidVref},
+		{"zero gain", 3.3, []adc.Option{adc.WithCalibration(adc.Calibration{})}, adc.ErrInvalidGain},
+		{"negative channel", 3.3, []adc.Option{adc.WithCalibration(adc.Calibration{Gain: 1}, -1)}, adc.ErrInvalidChannel},
+		{"high channel", 3.3, []adc.Option{adc.WithCalibration(adc.Calibration{Gain: 1}, 4)}, adc.ErrInvalidChannel},
+		{"zero oversampling", 3.3, []adc.Option{adc.WithOversampling(0)}, adc.ErrInvalidOversampling},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			c, err := adc.New(a, p.vref, p.options...)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, c)
+		}
+		t.Run(p.name, tf)
+	}
+
+	c, err := adc.New(a, 3.3)
+	require.Nil(t, err)
+	assert.Equal(t, a, c.ADC())
+	assert.Equal(t, 3.3, c.Vref())
+}
+
+func TestValue(t *testing.T) {
+	a := &fakeADC{channels: 4, resolution: 10, values: []int{0, 256, 512, 1023}}
+
+	patterns := []struct {
+		name    string
+		options []adc.Option
+		ch      int
+		val     float64
+		volts   float64
+	}{
+		{"zero", nil, 0, 0, 0},
+		{"quarter", nil, 1, 256, 0.8},
+		{"half", nil, 2, 512, 1.6},
+		{"offset", []adc.Option{adc.WithCalibration(adc.Calibration{Offset: 12, Gain: 1})}, 1, 244, 0.7625},
+		{"gain", []adc.Option{adc.WithCalibration(adc.Calibration{Gain: 0.5}, 2)}, 2, 256, 0.8},
+		{"offset and gain", []adc.Option{adc.WithCalibration(adc.Calibration{Offset: -256, Gain: 0.5}, 1, 2)}, 2, 384, 1.2},
+		{"other channel", []adc.Option{adc.WithCalibration(adc.Calibration{Offset: 6, Gain: 2}, 2)}, 1, 256, 0.8},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			c, err := adc.New(a, 3.2, p.options...)
+			require.Nil(t, err)
+			v, err := c.Value(p.ch)
+			assert.Nil(t, err)
+			assert.InDelta(t, p.val, v, 1e-9)
+			volts, err := c.Voltage(p.ch)
+			assert.Nil(t, err)
+			assert.InDelta(t, p.volts, volts, 1e-9)
+		}
+		t.Run(p.name, tf)
+	}
+
+	c, err := adc.New(a, 3.2)
+	require.Nil(t, err)
+	_, err = c.Value(-1)
+	assert.Equal(t, adc.ErrInvalidChannel, err)
+	_, err = c.Voltage(4)
+	assert.Equal(t, adc.ErrInvalidChannel, err)
+
+	// errors from the ADC are returned
+	a.err = errors.New("read failed")
+	_, err = c.Value(0)
+	assert.Equal(t, a.err, err)
+	_, err = c.Voltage(0)
+	assert.Equal(t, a.err, err)
+}
+
+func TestOversampling(t *testing.T) {
+	a := &fakeADC{channels: 1, resolution: 8, values: []int{100}, noise: []int{0, 1, 3, 0}}
+	c, err := adc.New(a, 2.56, adc.WithOversampling(4))
+	require.Nil(t, err)
+	v, err := c.Value(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 101.0, v)
+	assert.Equal(t, 4, a.reads)
+}
+
+func TestNewSampler(t *testing.T) {
+	a := &fakeADC{channels: 2, resolution: 8}
+	c, err := adc.New(a, 2.56)
+	require.Nil(t, err)
+
+	patterns := []struct {
+		name    string
+		rate    float64
+		options []adc.SamplerOption
+		err     error
+	}{
+		{"zero rate", 0, nil, adc.ErrInvalidRate},
+		{"negative rate", -1, nil, adc.ErrInvalidRate},
+		{"invalid channel", 100, []adc.SamplerOption{adc.WithChannels(0, 2)}, adc.ErrInvalidChannel},
+		{"zero buffer", 100, []adc.SamplerOption{adc.WithBufferSize(0)}, adc.ErrInvalidSize},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			s, err := adc.NewSampler(c, p.rate, p.options...)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, s)
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	a := &fakeADC{channels: 4, resolution: 8, values: []int{10, 20, 30, 40}}
+	c, err := adc.New(a, 2.56)
+	require.Nil(t, err)
+
+	start := time.Now()
+	s, err := adc.NewSampler(c, 100, adc.WithChannels(3, 1))
+	require.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, s.Close())
+	assert.Equal(t, adc.ErrClosed, s.Close())
+	elapsed := time.Since(start)
+
+	n := s.Len()
+	assert.Equal(t, 0, n%2)
+	// 10ms period, so roughly 10 scans
+	assert.GreaterOrEqual(t, n, 16)
+	assert.LessOrEqual(t, n, 2*int(elapsed/(10*time.Millisecond)+1))
+	assert.Zero(t, s.Overruns())
+	assert.Zero(t, s.Missed())
+	assert.Nil(t, s.Err())
+
+	buf := make([]adc.Sample, n+1)
+	assert.Equal(t, n, s.Read(buf))
+	assert.Zero(t, s.Len())
+	assert.Zero(t, s.Read(buf))
+	for i := 0; i < n; i += 2 {
+		assert.Equal(t, 3, buf[i].Channel)
+		assert.Equal(t, 40.0, buf[i].Value)
+		assert.InDelta(t, 0.4, buf[i].Voltage, 1e-9)
+		assert.Equal(t, 1, buf[i+1].Channel)
+		assert.Equal(t, 20.0, buf[i+1].Value)
+		assert.InDelta(t, 0.2, buf[i+1].Voltage, 1e-9)
+		assert.LessOrEqual(t, int64(buf[i].Timestamp), int64(buf[i+1].Timestamp))
+		if i > 0 {
+			// scans are a period apart, allowing for scheduling jitter
+			assert.InDelta(t, 10*time.Millisecond, buf[i].Timestamp-buf[i-2].Timestamp, float64(5*time.Millisecond))
+		}
+	}
+}
+
+func TestSamplerOverrun(t *testing.T) {
+	a := &fakeADC{channels: 1, resolution: 8, values: []int{10}}
+	c, err := adc.New(a, 2.56)
+	require.Nil(t, err)
+
+	s, err := adc.NewSampler(c, 1000, adc.WithBufferSize(4))
+	require.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, s.Close())
+
+	assert.Equal(t, 4, s.Len())
+	assert.Equal(t, uint64(a.reads-4), s.Overruns())
+
+	// the newest samples are retained, oldest first
+	buf := make([]adc.Sample, 2)
+	assert.Equal(t, 2, s.Read(buf))
+	assert.Less(t, int64(buf[0].Timestamp), int64(buf[1].Timestamp))
+	assert.Equal(t, 2, s.Read(buf))
+	assert.Less(t, int64(buf[0].Timestamp), int64(buf[1].Timestamp))
+	assert.Zero(t, s.Len())
+}
+
+func TestSamplerMissed(t *testing.T) {
+	// reads take longer than the period
+	a := &fakeADC{channels: 1, resolution: 8, values: []int{10}, readTime: 25 * time.Millisecond}
+	c, err := adc.New(a, 2.56)
+	require.Nil(t, err)
+
+	s, err := adc.NewSampler(c, 100)
+	require.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, s.Close())
+
+	assert.Greater(t, s.Missed(), uint64(0))
+	assert.Zero(t, s.Overruns())
+}
+
+func TestSamplerLate(t *testing.T) {
+	// scans run a little over the period, so are late but not skipped
+	a := &fakeADC{channels: 1, resolution: 8, values: []int{10}, readTime: 24 * time.Millisecond}
+	c, err := adc.New(a, 2.56)
+	require.Nil(t, err)
+
+	s, err := adc.NewSampler(c, 50)
+	require.Nil(t, err)
+	time.Sleep(90 * time.Millisecond)
+	assert.Nil(t, s.Close())
+
+	assert.GreaterOrEqual(t, s.Len(), 4)
+	assert.Zero(t, s.Missed())
+}
+
+func TestSamplerErr(t *testing.T) {
+	a := &fakeADC{channels: 1, resolution: 8, values: []int{10}, failAfter: 3, err: errors.New("read failed")}
+	c, err := adc.New(a, 2.56)
+	require.Nil(t, err)
+
+	s, err := adc.NewSampler(c, 1000)
+	require.Nil(t, err)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, a.err, s.Err())
+	assert.Equal(t, 3, s.Len())
+	assert.Nil(t, s.Close())
+}
+
+// fakeADC returns fixed values for each channel, with optional noise added to
+// successive reads.
+type fakeADC struct {
+	mu         sync.Mutex
+	channels   int
+	resolution uint
+	values     []int
+	noise      []int
+	readTime   time.Duration
+	reads      int
+
+	// the error returned after failAfter reads, or immediately if failAfter
+	// is zero
+	failAfter int
+	err       error
+}
+
+func (a *fakeADC) Channels() int {
+	return a.channels
+}
+
+func (a *fakeADC) Resolution() uint {
+	return a.resolution
+}
+
+func (a *fakeADC) Read(ch int) (int, error) {
+	if a.readTime != 0 {
+		time.Sleep(a.readTime)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil && a.reads >= a.failAfter {
+		return 0, a.err
+	}
+	v := 0
+	if ch < len(a.values) {
+		v = a.values[ch]
+	}
+	if len(a.noise) > 0 {
+		v += a.noise[a.reads%len(a.noise)]
+	}
+	a.reads++
+	return v, nil
+}
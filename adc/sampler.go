@@ -0,0 +1,250 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package adc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod/timing"
+)
+
+// Sample is a value read by a Sampler.
+type Sample struct {
+	// The time the read started, as returned by timing.Now.
+	Timestamp time.Duration
+
+	// The channel read.
+	Channel int
+
+	// The calibrated value.
+	Value float64
+
+	// The calibrated voltage.
+	Voltage float64
+}
+
+// Sampler continuously reads channels from a Converter at a target rate,
+// storing the samples in a ring buffer.
+//
+// The channels are read in turn, as a scan, once per period.  If the buffer
+// is full then the oldest samples are overwritten, and if a scan takes longer
+// than the period then the scans that fall due during it are skipped.  Both
+// are counted so the caller can detect that the rate cannot be sustained.
+type Sampler struct {
+	c        *Converter
+	channels []int
+	period   time.Duration
+	delay    *timing.Delay
+	done     chan struct{}
+	stopped  chan struct{}
+
+	// options for the Delay
+	timingOptions []timing.Option
+
+	// Error from construction options
+	optErr error
+
+	mu       sync.Mutex
+	buf      []Sample
+	head     int // index of the oldest sample
+	count    int
+	overruns uint64
+	missed   uint64
+	err      error
+	closed   bool
+}
+
+// NewSampler creates a Sampler that reads the Converter at rate scans per
+// second.
+//
+// The Sampler starts immediately, and runs until it is closed or a read
+// fails.
+func NewSampler(c *Converter, rate float64, options ...SamplerOption) (*Sampler, error) {
+	if rate <= 0 {
+		return nil, ErrInvalidRate
+	}
+	s := Sampler{
+		c:       c,
+		period:  time.Duration(float64(time.Second) / rate),
+		buf:     make([]Sample, 1024),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	for ch := 0; ch < c.adc.Channels(); ch++ {
+		s.channels = append(s.channels, ch)
+	}
+	for _, option := range options {
+		option(&s)
+	}
+	if s.optErr != nil {
+		return nil, s.optErr
+	}
+	if s.period <= 0 {
+		return nil, ErrInvalidRate
+	}
+	var err error
+	s.delay, err = timing.NewDelay(s.timingOptions...)
+	if err != nil {
+		return nil, err
+	}
+	go s.run()
+	return &s, nil
+}
+
+// Close stops the Sampler.
+//
+// Samples remaining in the buffer may still be read.
+func (s *Sampler) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.done)
+	<-s.stopped
+	return s.delay.Close()
+}
+
+// Read removes the oldest samples from the buffer and copies them into buf,
+// returning the number of samples copied.
+func (s *Sampler) Read(buf []Sample) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for n < len(buf) && s.count > 0 {
+		buf[n] = s.buf[s.head]
+		s.head = (s.head + 1) % len(s.buf)
+		s.count--
+		n++
+	}
+	return n
+}
+
+// Len returns the number of samples in the buffer.
+func (s *Sampler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Overruns returns the number of samples overwritten before they were read.
+func (s *Sampler) Overruns() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.overruns
+}
+
+// Missed returns the number of scans skipped as the preceding scan had not
+// completed when they fell due.
+func (s *Sampler) Missed() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.missed
+}
+
+// Err returns the error that stopped the Sampler, if any.
+func (s *Sampler) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *Sampler) run() {
+	defer close(s.stopped)
+	next := timing.Now()
+	for {
+		for _, ch := range s.channels {
+			start := timing.Now()
+			v, err := s.c.Value(ch)
+			s.mu.Lock()
+			if err != nil {
+				s.err = err
+				s.mu.Unlock()
+				return
+			}
+			s.push(Sample{
+				Timestamp: start,
+				Channel:   ch,
+				Value:     v,
+				Voltage:   s.c.ToVoltage(v),
+			})
+			s.mu.Unlock()
+		}
+		next += s.period
+		// a late scan is still made, and only scans that have fallen a
+		// whole period behind are skipped, so jitter does not reduce the
+		// rate.
+		if now := timing.Now(); now-next >= s.period {
+			skipped := (now - next) / s.period
+			next += skipped * s.period
+			s.mu.Lock()
+			s.missed += uint64(skipped)
+			s.mu.Unlock()
+		}
+		if !s.delay.UntilOrDone(next, s.done) {
+			return
+		}
+	}
+}
+
+// push adds a sample to the buffer, overwriting the oldest if the buffer is
+// full.
+//
+// Must be called with the mu held.
+func (s *Sampler) push(sample Sample) {
+	if s.count == len(s.buf) {
+		s.buf[s.head] = sample
+		s.head = (s.head + 1) % len(s.buf)
+		s.overruns++
+		return
+	}
+	s.buf[(s.head+s.count)%len(s.buf)] = sample
+	s.count++
+}
+
+// SamplerOption specifies a construction option for the Sampler.
+type SamplerOption func(*Sampler)
+
+// WithChannels sets the channels read by the Sampler, in the order they are
+// read.
+//
+// The default is all the channels of the ADC.
+func WithChannels(channels ...int) SamplerOption {
+	return func(s *Sampler) {
+		for _, ch := range channels {
+			if ch < 0 || ch >= s.c.adc.Channels() {
+				s.optErr = ErrInvalidChannel
+				return
+			}
+		}
+		s.channels = append([]int(nil), channels...)
+	}
+}
+
+// WithBufferSize sets the number of samples held by the ring buffer.
+//
+// The default is 1024.
+func WithBufferSize(size int) SamplerOption {
+	return func(s *Sampler) {
+		if size < 1 {
+			s.optErr = ErrInvalidSize
+			return
+		}
+		s.buf = make([]Sample, size)
+	}
+}
+
+// WithTiming sets the options for the Delay used to time the scans.
+func WithTiming(options ...timing.Option) SamplerOption {
+	return func(s *Sampler) {
+		s.timingOptions = append(s.timingOptions, options...)
+	}
+}
@@ -0,0 +1,581 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package button detects presses, releases, clicks, multi-clicks, long presses
+// and repeats from a button connected to a line.
+//
+// The button is pressed when the line is active, so buttons that pull the line
+// low when pressed should be requested with gpiod.AsActiveLow.
+//
+// Events are determined from the timestamps of the edges detected by the
+// kernel, rather than when the edges are received, so the events are the same
+// even if the program is heavily loaded.
+package button
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// the maximum time an edge is expected to take to arrive after it occurs.
+const edgeGrace = 10 * time.Millisecond
+
+// EventType indicates the type of a button event.
+type EventType int
+
+const (
+	_ EventType = iota
+
+	// Pressed indicates the button has been pressed.
+	Pressed
+
+	// Released indicates the button has been released.
+	Released
+
+	// Click indicates a single short press and release.
+	Click
+
+	// DoubleClick indicates two clicks in quick succession.
+	DoubleClick
+
+	// MultiClick indicates three or more clicks in quick succession.
+	MultiClick
+
+	// LongPress indicates the button has been held for the long press time.
+	LongPress
+
+	// Repeat indicates the button continues to be held after a long press.
+	Repeat
+)
+
+var eventTypeNames = map[EventType]string{
+	Pressed:     "pressed",
+	Released:    "released",
+	Click:       "click",
+	DoubleClick: "double-click",
+	MultiClick:  "multi-click",
+	LongPress:   "long-press",
+	Repeat:      "repeat",
+}
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	if n, ok := eventTypeNames[t]; ok {
+		return n
+	}
+	return "unknown"
+}
+
+// Event describes a button event.
+type Event struct {
+	// The type of event.
+	Type EventType
+
+	// The time of the event, on the clock of the edge event timestamps.
+	//
+	// For timed events, such as LongPress and Repeat, this is the time the
+	// event became due, not when it was detected.
+	Timestamp time.Duration
+
+	// The number of clicks in a Click, DoubleClick or MultiClick.
+	Clicks int
+
+	// The time the button has been held, for Released, LongPress and Repeat.
+	Held time.Duration
+}
+
+// Handler receives button events.
+type Handler func(Event)
+
+// Button detects events from a button on a line.
+type Button struct {
+	line *gpiod.Line
+
+	// timing parameters
+	debounce    time.Duration
+	clickWindow time.Duration
+	longPress   time.Duration
+	repeat      time.Duration
+
+	handler Handler
+	ch      chan<- Event
+
+	lineOptions []gpiod.LineReqOption
+
+	// emitMu serialises the processing and delivery of events, so they are
+	// delivered in order.
+	emitMu sync.Mutex
+
+	// mu covers the state below
+	mu sync.Mutex
+
+	// true if the button is currently pressed
+	pressed bool
+
+	// true if the release of the current press is not a click, as the press
+	// was a long press or preceded the Button.
+	held bool
+
+	// the time of the last press
+	pressTs time.Duration
+
+	// the number of clicks in the current sequence
+	clicks int
+
+	// the debounce period applied by the Button if the kernel does not
+	// support debouncing.
+	softDebounce time.Duration
+
+	// the timestamp of the last accepted edge, for soft debouncing
+	lastEdge time.Duration
+
+	// the end of the debounce window in which an edge was rejected, at which
+	// the line must be resampled, if any.
+	settle time.Duration
+
+	// the next timed event, if any
+	deadline     time.Duration
+	deadlineType EventType
+
+	timer *time.Timer
+
+	dropped uint64
+
+	closed bool
+
+	// an error from the construction options
+	err error
+}
+
+// New requests the line and starts detecting button events.
+//
+// The line is debounced by the kernel, or by the Button if the kernel does not
+// support debouncing.
+//
+// The edge event timestamps must be from CLOCK_MONOTONIC, which is the default
+// for Linux v5.7 and later.
+func New(chip string, offset int, options ...Option) (*Button, error) {
+	b := newButton(options)
+	if b.err != nil {
+		return nil, b.err
+	}
+	// hold the lock until the initial state is known, so early events are
+	// processed relative to it.
+	b.mu.Lock()
+	lro := append([]gpiod.LineReqOption{gpiod.AsInput}, b.lineOptions...)
+	lro = append(lro, gpiod.WithBothEdges, gpiod.WithEventHandler(b.handleEvent))
+	var l *gpiod.Line
+	var err error
+	if b.debounce > 0 {
+		l, err = gpiod.RequestLine(chip, offset, append(lro, gpiod.WithDebounce(b.debounce))...)
+		if errors.As(err, &gpiod.ErrUapiIncompatibility{}) {
+			b.softDebounce = b.debounce
+			l, err = gpiod.RequestLine(chip, offset, lro...)
+		}
+	} else {
+		l, err = gpiod.RequestLine(chip, offset, lro...)
+	}
+	if err != nil {
+		b.mu.Unlock()
+		return nil, err
+	}
+	v, err := l.Value()
+	if err != nil {
+		b.closed = true
+		// the handler may be waiting on the lock, and Close waits for it.
+		b.mu.Unlock()
+		l.Close()
+		return nil, err
+	}
+	if v == 1 {
+		// pressed before we started, so only the release is reported.
+		b.pressed = true
+		b.held = true
+	}
+	b.lastEdge = timing.Now()
+	b.timer = time.AfterFunc(time.Hour, b.expire)
+	b.timer.Stop()
+	b.line = l
+	b.mu.Unlock()
+	return b, nil
+}
+
+func newButton(options []Option) *Button {
+	b := Button{
+		debounce:    10 * time.Millisecond,
+		clickWindow: 300 * time.Millisecond,
+		longPress:   time.Second,
+	}
+	for _, option := range options {
+		option(&b)
+	}
+	return &b
+}
+
+// Close releases the line.
+//
+// Any pending timed events are discarded.  A channel provided by WithChannel is
+// not closed.
+func (b *Button) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	b.closed = true
+	b.timer.Stop()
+	b.mu.Unlock()
+	return b.line.Close()
+}
+
+// Pressed returns true if the button is currently pressed.
+func (b *Button) Pressed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pressed
+}
+
+// Dropped returns the number of events that could not be sent to the channel
+// provided by WithChannel as it was full.
+func (b *Button) Dropped() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+func (b *Button) handleEvent(evt gpiod.LineEvent) {
+	b.emitMu.Lock()
+	defer b.emitMu.Unlock()
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	ee := b.processEdge(evt)
+	b.arm()
+	b.mu.Unlock()
+	b.deliver(ee)
+}
+
+// processEdge updates the state with an edge event, and returns the resulting
+// events.
+func (b *Button) processEdge(evt gpiod.LineEvent) []Event {
+	// the level before the edge, which was the level at the end of any
+	// debounce window preceding the edge, as edges after the window are not
+	// rejected.
+	v := 1
+	if evt.Type == gpiod.LineEventRisingEdge {
+		v = 0
+	}
+	// timed events that fell due before the edge come first.
+	ee := b.catchUp(evt.Timestamp, v, nil)
+	return b.edge(evt, ee)
+}
+
+// expire handles timed events once they fall due.
+func (b *Button) expire() {
+	b.emitMu.Lock()
+	defer b.emitMu.Unlock()
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	v, err := b.line.Value()
+	if err != nil {
+		v = 0
+		if b.pressed {
+			v = 1
+		}
+	}
+	ee, retry := b.processExpiry(timing.Now(), v)
+	if retry {
+		b.timer.Reset(time.Millisecond)
+	} else {
+		b.arm()
+	}
+	b.mu.Unlock()
+	b.deliver(ee)
+}
+
+// processExpiry generates the timed events due at now, given the line level v,
+// and returns the resulting events.
+//
+// Returns true if processing should be retried shortly, as an edge may be
+// waiting to be processed.
+func (b *Button) processExpiry(now time.Duration, v int) ([]Event, bool) {
+	due := b.next()
+	if due == 0 || due > now {
+		return nil, false
+	}
+	// if the line does not match the state, and no debounce window has
+	// ended, then an edge may be waiting to be processed that precedes the
+	// deadline, so it should be processed first.  The edge is given a
+	// limited time to arrive, in case it has been lost.
+	if (v == 1) != b.pressed && (b.settle == 0 || b.settle > now) && now-due < edgeGrace {
+		return nil, true
+	}
+	return b.catchUp(now, v, nil), false
+}
+
+// catchUp generates the events due up to the time, given the line level v at
+// the end of any debounce window ending before then.
+func (b *Button) catchUp(to time.Duration, v int, ee []Event) []Event {
+	if b.settle != 0 && b.settle <= to {
+		ee = b.advance(b.settle, ee)
+		ee = b.resample(v, ee)
+	}
+	return b.advance(to, ee)
+}
+
+// resample synthesises the edge missed by soft debouncing if the line level at
+// the end of the debounce window, v, does not match the state.
+func (b *Button) resample(v int, ee []Event) []Event {
+	ts := b.settle
+	b.settle = 0
+	if (v == 1) == b.pressed {
+		return ee
+	}
+	typ := gpiod.LineEventFallingEdge
+	if v == 1 {
+		typ = gpiod.LineEventRisingEdge
+	}
+	return b.edge(gpiod.LineEvent{Timestamp: ts, Type: typ}, ee)
+}
+
+// advance generates the timed events due up to the time.
+func (b *Button) advance(to time.Duration, ee []Event) []Event {
+	for b.deadline != 0 && b.deadline <= to {
+		ts := b.deadline
+		switch b.deadlineType {
+		case LongPress:
+			// clicks preceding the long press are complete.
+			ee = b.flushClicks(ts, ee)
+			ee = append(ee, Event{Type: LongPress, Timestamp: ts, Held: ts - b.pressTs})
+			b.held = true
+			b.deadline = 0
+			if b.repeat > 0 {
+				b.deadline = ts + b.repeat
+				b.deadlineType = Repeat
+			}
+		case Repeat:
+			ee = append(ee, Event{Type: Repeat, Timestamp: ts, Held: ts - b.pressTs})
+			b.deadline = ts + b.repeat
+		case Click:
+			ee = b.flushClicks(ts, ee)
+			b.deadline = 0
+		}
+	}
+	return ee
+}
+
+// edge updates the state with an edge event.
+func (b *Button) edge(evt gpiod.LineEvent, ee []Event) []Event {
+	ts := evt.Timestamp
+	if b.softDebounce > 0 {
+		if ts-b.lastEdge < b.softDebounce {
+			// the line must be checked once it has settled, in case this
+			// was the end of a glitch.
+			b.settle = b.lastEdge + b.softDebounce
+			return ee
+		}
+	}
+	switch evt.Type {
+	case gpiod.LineEventRisingEdge:
+		if b.pressed {
+			return ee
+		}
+		b.pressed = true
+		b.pressTs = ts
+		ee = append(ee, Event{Type: Pressed, Timestamp: ts})
+		b.deadline = 0
+		if b.longPress > 0 {
+			b.deadline = ts + b.longPress
+			b.deadlineType = LongPress
+		}
+	case gpiod.LineEventFallingEdge:
+		if !b.pressed {
+			return ee
+		}
+		b.pressed = false
+		ee = append(ee, Event{Type: Released, Timestamp: ts, Held: ts - b.pressTs})
+		b.deadline = 0
+		if b.held {
+			b.held = false
+			break
+		}
+		b.clicks++
+		if b.clickWindow > 0 {
+			b.deadline = ts + b.clickWindow
+			b.deadlineType = Click
+		} else {
+			ee = b.flushClicks(ts, ee)
+		}
+	default:
+		return ee
+	}
+	b.lastEdge = ts
+	return ee
+}
+
+// flushClicks generates the event for the pending clicks, if any.
+func (b *Button) flushClicks(ts time.Duration, ee []Event) []Event {
+	if b.clicks == 0 {
+		return ee
+	}
+	t := MultiClick
+	switch b.clicks {
+	case 1:
+		t = Click
+	case 2:
+		t = DoubleClick
+	}
+	ee = append(ee, Event{Type: t, Timestamp: ts, Clicks: b.clicks})
+	b.clicks = 0
+	return ee
+}
+
+// next returns the time the timer is next required, or 0 if not required.
+func (b *Button) next() time.Duration {
+	if b.settle != 0 && (b.deadline == 0 || b.settle < b.deadline) {
+		return b.settle
+	}
+	return b.deadline
+}
+
+// arm sets the timer for the next timed event.
+func (b *Button) arm() {
+	next := b.next()
+	if next == 0 {
+		b.timer.Stop()
+		return
+	}
+	b.timer.Reset(next - timing.Now())
+}
+
+// deliver passes the events to the handler and channel.
+//
+// Called with emitMu held, but not mu, so the handler may call the Button
+// methods.
+func (b *Button) deliver(ee []Event) {
+	for _, evt := range ee {
+		if b.ch != nil {
+			select {
+			case b.ch <- evt:
+			default:
+				b.mu.Lock()
+				b.dropped++
+				b.mu.Unlock()
+			}
+		}
+		if b.handler != nil {
+			b.handler(evt)
+		}
+	}
+}
+
+// Option specifies a construction option for the Button.
+type Option func(*Button)
+
+// WithDebounce sets the debounce period for the line.
+//
+// The default is 10ms.  A zero period disables debouncing.
+func WithDebounce(period time.Duration) Option {
+	return func(b *Button) {
+		if period < 0 {
+			b.err = ErrInvalidTiming
+			return
+		}
+		b.debounce = period
+	}
+}
+
+// WithClickWindow sets the maximum time between a release and the following
+// press for the clicks to be combined into a DoubleClick or MultiClick.
+//
+// The click events are delayed by this period, to see if further clicks
+// follow.  The default is 300ms.  A zero period disables multi-click
+// detection, and Click events are generated immediately on release.
+func WithClickWindow(window time.Duration) Option {
+	return func(b *Button) {
+		if window < 0 {
+			b.err = ErrInvalidTiming
+			return
+		}
+		b.clickWindow = window
+	}
+}
+
+// WithLongPress sets the time the button must be held to generate a LongPress.
+//
+// The release of a long press does not generate a click.  The default is one
+// second.  A zero period disables long press detection.
+func WithLongPress(period time.Duration) Option {
+	return func(b *Button) {
+		if period < 0 {
+			b.err = ErrInvalidTiming
+			return
+		}
+		b.longPress = period
+	}
+}
+
+// WithRepeat sets the period of Repeat events generated while the button
+// continues to be held after a LongPress.
+//
+// The default is zero, which disables repeats.
+func WithRepeat(period time.Duration) Option {
+	return func(b *Button) {
+		if period < 0 {
+			b.err = ErrInvalidTiming
+			return
+		}
+		b.repeat = period
+	}
+}
+
+// WithLineOptions adds options applied when requesting the line, such as
+// gpiod.AsActiveLow and bias.
+//
+// Edge detection, debouncing and event handling are set by the Button and must
+// not be included.
+func WithLineOptions(options ...gpiod.LineReqOption) Option {
+	return func(b *Button) {
+		b.lineOptions = append(b.lineOptions, options...)
+	}
+}
+
+// WithHandler sets a handler that is called for each button event.
+//
+// The handler is called from the goroutine that reads the edge events, or
+// that of the timer for timed events, and so should be short lived.
+func WithHandler(h Handler) Option {
+	return func(b *Button) {
+		b.handler = h
+	}
+}
+
+// WithChannel sets a channel that is sent each button event.
+//
+// Events are dropped, rather than blocking detection, if the channel is full.
+func WithChannel(ch chan<- Event) Option {
+	return func(b *Button) {
+		b.ch = ch
+	}
+}
+
+var (
+	// ErrClosed indicates the Button has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrInvalidTiming indicates a timing parameter is negative.
+	ErrInvalidTiming = errors.New("invalid timing")
+)
@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package button_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/button"
+	"github.com/warthog618/gpiod/mockup"
+)
+
+const offset = 2
+
+func TestNew(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+
+	patterns := []struct {
+		name   string
+		option button.Option
+	}{
+		{"debounce", button.WithDebounce(-1)},
+		{"click window", button.WithClickWindow(-1)},
+		{"long press", button.WithLongPress(-1)},
+		{"repeat", button.WithRepeat(-1)},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			b, err := button.New(c.Name, offset, p.option)
+			assert.Equal(t, button.ErrInvalidTiming, err)
+			assert.Nil(t, b)
+		}
+		t.Run(p.name, tf)
+	}
+
+	b, err := button.New(c.Name, offset)
+	assert.Nil(t, err)
+	require.NotNil(t, b)
+	assert.False(t, b.Pressed())
+	assert.Nil(t, b.Close())
+	assert.Equal(t, button.ErrClosed, b.Close())
+}
+
+func TestClicks(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+
+	ch := make(chan button.Event, 10)
+	b, err := button.New(c.Name, offset,
+		button.WithDebounce(0),
+		button.WithClickWindow(50*time.Millisecond),
+		button.WithChannel(ch))
+	require.Nil(t, err)
+	defer b.Close()
+
+	click(t, c, 1)
+	expectEvents(t, ch, button.Pressed, button.Released, button.Click)
+
+	click(t, c, 2)
+	evts := expectEvents(t, ch, button.Pressed, button.Released,
+		button.Pressed, button.Released, button.DoubleClick)
+	assert.Equal(t, 2, evts[4].Clicks)
+	// timed events are stamped with when they fell due
+	assert.Equal(t, evts[3].Timestamp+50*time.Millisecond, evts[4].Timestamp)
+
+	click(t, c, 3)
+	evts = expectEvents(t, ch, button.Pressed, button.Released,
+		button.Pressed, button.Released,
+		button.Pressed, button.Released, button.MultiClick)
+	assert.Equal(t, 3, evts[6].Clicks)
+	assert.Zero(t, b.Dropped())
+}
+
+func TestLongPress(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+
+	ch := make(chan button.Event, 10)
+	b, err := button.New(c.Name, offset,
+		button.WithDebounce(0),
+		button.WithLongPress(30*time.Millisecond),
+		button.WithRepeat(20*time.Millisecond),
+		button.WithHandler(func(evt button.Event) {
+			ch <- evt
+		}))
+	require.Nil(t, err)
+	defer b.Close()
+
+	c.SetValue(offset, 1)
+	evts := expectEvents(t, ch, button.Pressed, button.LongPress, button.Repeat)
+	assert.True(t, b.Pressed())
+	c.SetValue(offset, 0)
+	assert.Equal(t, 30*time.Millisecond, evts[1].Held)
+	assert.Equal(t, 50*time.Millisecond, evts[2].Held)
+	// the release of a long press is not a click
+	evt := waitEvent(t, ch)
+	assert.Equal(t, button.Released, evt.Type)
+	assert.GreaterOrEqual(t, evt.Held, 50*time.Millisecond)
+	select {
+	case evt := <-ch:
+		assert.Fail(t, "unexpected event", evt)
+	case <-time.After(400 * time.Millisecond):
+	}
+}
+
+func TestActiveLow(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+
+	// initially pressed
+	c.SetValue(offset, 0)
+	ch := make(chan button.Event, 10)
+	b, err := button.New(c.Name, offset,
+		button.WithClickWindow(0),
+		button.WithLineOptions(gpiod.AsActiveLow),
+		button.WithChannel(ch))
+	require.Nil(t, err)
+	defer b.Close()
+	assert.True(t, b.Pressed())
+
+	// release of a press that preceded the Button is not a click
+	c.SetValue(offset, 1)
+	expectEvents(t, ch, button.Released)
+	assert.False(t, b.Pressed())
+
+	c.SetValue(offset, 0)
+	time.Sleep(20 * time.Millisecond)
+	c.SetValue(offset, 1)
+	expectEvents(t, ch, button.Pressed, button.Released, button.Click)
+}
+
+func TestSoftDebounce(t *testing.T) {
+	b := button.NewUnattached(
+		button.WithDebounce(10*time.Millisecond),
+		button.WithClickWindow(0),
+		button.WithLongPress(100*time.Millisecond))
+	ms := time.Millisecond
+
+	evts := b.ProcessEdge(gpiod.LineEvent{Timestamp: 100 * ms, Type: gpiod.LineEventRisingEdge})
+	assert.Equal(t, []button.EventType{button.Pressed}, eventTypes(evts))
+	assert.Equal(t, 200*ms, b.Next())
+
+	// a glitch shorter than the debounce period is released once settled
+	evts = b.ProcessEdge(gpiod.LineEvent{Timestamp: 102 * ms, Type: gpiod.LineEventFallingEdge})
+	assert.Empty(t, evts)
+	assert.Equal(t, 110*ms, b.Next())
+	evts, retry := b.ProcessExpiry(111*ms, 0)
+	assert.False(t, retry)
+	require.Equal(t, []button.EventType{button.Released, button.Click}, eventTypes(evts))
+	assert.Equal(t, 110*ms, evts[0].Timestamp)
+	assert.False(t, b.Pressed())
+	assert.Zero(t, b.Next())
+
+	// bounces that return to the state generate no events
+	evts = b.ProcessEdge(gpiod.LineEvent{Timestamp: 200 * ms, Type: gpiod.LineEventRisingEdge})
+	assert.Equal(t, []button.EventType{button.Pressed}, eventTypes(evts))
+	evts = b.ProcessEdge(gpiod.LineEvent{Timestamp: 202 * ms, Type: gpiod.LineEventFallingEdge})
+	assert.Empty(t, evts)
+	evts = b.ProcessEdge(gpiod.LineEvent{Timestamp: 204 * ms, Type: gpiod.LineEventRisingEdge})
+	assert.Empty(t, evts)
+	evts, retry = b.ProcessExpiry(210*ms, 1)
+	assert.False(t, retry)
+	assert.Empty(t, evts)
+	assert.True(t, b.Pressed())
+	assert.Equal(t, 300*ms, b.Next())
+	evts, retry = b.ProcessExpiry(300*ms, 1)
+	assert.False(t, retry)
+	assert.Equal(t, []button.EventType{button.LongPress}, eventTypes(evts))
+
+	// a missed release is found from the following edge if the timer is late
+	evts = b.ProcessEdge(gpiod.LineEvent{Timestamp: 400 * ms, Type: gpiod.LineEventFallingEdge})
+	assert.Equal(t, []button.EventType{button.Released}, eventTypes(evts))
+	evts = b.ProcessEdge(gpiod.LineEvent{Timestamp: 500 * ms, Type: gpiod.LineEventRisingEdge})
+	assert.Equal(t, []button.EventType{button.Pressed}, eventTypes(evts))
+	evts = b.ProcessEdge(gpiod.LineEvent{Timestamp: 505 * ms, Type: gpiod.LineEventFallingEdge})
+	assert.Empty(t, evts)
+	evts = b.ProcessEdge(gpiod.LineEvent{Timestamp: 520 * ms, Type: gpiod.LineEventRisingEdge})
+	require.Equal(t, []button.EventType{button.Released, button.Click, button.Pressed}, eventTypes(evts))
+	assert.Equal(t, 510*ms, evts[0].Timestamp)
+	assert.Equal(t, 520*ms, evts[2].Timestamp)
+	assert.Equal(t, 620*ms, b.Next())
+
+	// a level mismatch only delays timed events for a limited time
+	evts, retry = b.ProcessExpiry(620*ms, 0)
+	assert.True(t, retry)
+	assert.Empty(t, evts)
+	evts, retry = b.ProcessExpiry(640*ms, 0)
+	assert.False(t, retry)
+	assert.Equal(t, []button.EventType{button.LongPress}, eventTypes(evts))
+	assert.Zero(t, b.Next())
+}
+
+func eventTypes(evts []button.Event) []button.EventType {
+	var tt []button.EventType
+	for _, evt := range evts {
+		tt = append(tt, evt.Type)
+	}
+	return tt
+}
+
+// click generates n clicks on the line.
+func click(t *testing.T, c *mockup.Chip, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		require.Nil(t, c.SetValue(offset, 1))
+		time.Sleep(5 * time.Millisecond)
+		require.Nil(t, c.SetValue(offset, 0))
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func expectEvents(t *testing.T, ch <-chan button.Event, types ...button.EventType) []button.Event {
+	t.Helper()
+	evts := make([]button.Event, len(types))
+	for i, et := range types {
+		evts[i] = waitEvent(t, ch)
+		assert.Equal(t, et, evts[i].Type, i)
+	}
+	return evts
+}
+
+func waitEvent(t *testing.T, ch <-chan button.Event) button.Event {
+	t.Helper()
+	select {
+	case evt := <-ch:
+		return evt
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for event")
+	}
+	return button.Event{}
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package button
+
+import (
+	"time"
+
+	"github.com/warthog618/gpiod"
+)
+
+// NewUnattached creates a Button that is not attached to a line, and that
+// debounces in software, so that it may be driven directly by tests.
+func NewUnattached(options ...Option) *Button {
+	b := newButton(options)
+	b.softDebounce = b.debounce
+	return b
+}
+
+// ProcessEdge updates the state with the edge, as the event handler would.
+func (b *Button) ProcessEdge(evt gpiod.LineEvent) []Event {
+	return b.processEdge(evt)
+}
+
+// ProcessExpiry updates the state at the time, with the line at level v, as
+// the timer would.
+func (b *Button) ProcessExpiry(now time.Duration, v int) ([]Event, bool) {
+	return b.processExpiry(now, v)
+}
+
+// Next returns the time the timer is next required.
+func (b *Button) Next() time.Duration {
+	return b.next()
+}
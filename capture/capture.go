@@ -0,0 +1,388 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package capture measures the period, frequency and duty cycle of signals on
+// edge-watched lines.
+//
+// This is intended for PWM and tachometer inputs, and for any other signal
+// characterised by the timing of its edges.
+package capture
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// Capture measures the timing of the edges on a line.
+//
+// The Capture is fed the edge events from the line via HandleEvent, which can
+// be provided to the line request as its event handler, or the Capture can
+// request the line itself using RequestLine.
+//
+// A Capture measures a single line, so the events from other lines must not
+// be passed to it.
+type Capture struct {
+	// mu covers the state below
+	mu sync.Mutex
+
+	// the number of samples averaged
+	window int
+
+	// the time without an edge after which the signal is considered stalled
+	stallTimeout time.Duration
+
+	// the periods, from each edge to the previous edge of the same type
+	periods ring
+
+	// the pulse widths, from each rising edge to the following falling edge
+	widths ring
+
+	// the histogram of all pulse widths since the last reset
+	hist Histogram
+
+	// the previous event
+	last     gpiod.LineEvent
+	haveLast bool
+
+	// the timestamps of the previous edge of each type
+	lastRising      time.Duration
+	haveLastRising  bool
+	lastFalling     time.Duration
+	haveLastFalling bool
+
+	// the time the last event was received, on the timing clock
+	lastSeen time.Duration
+
+	edges  uint64
+	missed uint64
+
+	// an error from the construction options
+	err error
+}
+
+// Measurement contains the measured characteristics of the signal.
+type Measurement struct {
+	// The mean period of the signal over the window.
+	//
+	// Zero if the signal is stalled or not enough edges have been seen.
+	Period time.Duration
+
+	// The frequency of the signal in Hz.
+	//
+	// Zero if the signal is stalled or not enough edges have been seen.
+	Frequency float64
+
+	// The mean time the signal is active in each period over the window.
+	PulseWidth time.Duration
+
+	// The fraction of each period the signal is active, from 0 to 1.
+	//
+	// If the signal is stalled this is 1 if the line was left active, else 0.
+	Duty float64
+
+	// The number of periods in the window.
+	Samples int
+
+	// The total number of edges seen since the last reset.
+	Edges uint64
+
+	// The number of edges known to have been missed since the last reset.
+	Missed uint64
+
+	// True if no edges have been seen within the stall timeout.
+	Stalled bool
+}
+
+// Histogram contains the distribution of pulse widths.
+type Histogram struct {
+	// The upper bounds of the bins, in increasing order.
+	Bounds []time.Duration
+
+	// The number of pulses in each bin.
+	//
+	// Counts[i] is the number of pulses no wider than Bounds[i] and wider than
+	// Bounds[i-1].  The final count, Counts[len(Bounds)], is the number of
+	// pulses wider than the last bound.
+	Counts []uint64
+}
+
+// New creates a Capture.
+//
+// The default window is 16 samples and the default stall timeout is one
+// second.
+func New(options ...Option) (*Capture, error) {
+	c := Capture{
+		window:       16,
+		stallTimeout: time.Second,
+	}
+	for _, option := range options {
+		option(&c)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.periods = newRing(c.window)
+	c.widths = newRing(c.window)
+	c.hist.Counts = make([]uint64, len(c.hist.Bounds)+1)
+	c.lastSeen = timing.Now()
+	return &c, nil
+}
+
+// RequestLine requests the line with edge detection on both edges, and with
+// the Capture as its event handler.
+//
+// The line remains the responsibility of the caller to close.
+func (c *Capture) RequestLine(chip string, offset int, options ...gpiod.LineReqOption) (*gpiod.Line, error) {
+	options = append(options, gpiod.WithBothEdges, gpiod.WithEventHandler(c.HandleEvent))
+	return gpiod.RequestLine(chip, offset, options...)
+}
+
+// HandleEvent adds an edge event to the measurement.
+//
+// Events missed due to the kernel event buffer overflowing are detected from
+// gaps in the LineSeqno.  Measurement restarts from the edge following a gap,
+// so the missed edges do not distort the results.
+func (c *Capture) HandleEvent(evt gpiod.LineEvent) {
+	now := timing.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.edges++
+	if c.haveLast {
+		if now-c.lastSeen > c.stallTimeout {
+			// resuming after a stall, so the old samples are stale.
+			c.restart()
+			c.periods.reset()
+			c.widths.reset()
+		}
+		if evt.LineSeqno != 0 && c.last.LineSeqno != 0 {
+			if evt.LineSeqno != c.last.LineSeqno+1 {
+				c.missed += uint64(evt.LineSeqno - c.last.LineSeqno - 1)
+				c.restart()
+			}
+		} else if evt.Type == c.last.Type {
+			// without seqnos a missed edge is only apparent from
+			// consecutive edges of the same type.
+			c.missed++
+			c.restart()
+		}
+	}
+	c.lastSeen = now
+	switch evt.Type {
+	case gpiod.LineEventRisingEdge:
+		if c.haveLastRising {
+			c.periods.add(evt.Timestamp - c.lastRising)
+		}
+		c.lastRising = evt.Timestamp
+		c.haveLastRising = true
+	case gpiod.LineEventFallingEdge:
+		if c.haveLastFalling {
+			c.periods.add(evt.Timestamp - c.lastFalling)
+		}
+		c.lastFalling = evt.Timestamp
+		c.haveLastFalling = true
+		if c.haveLastRising {
+			w := evt.Timestamp - c.lastRising
+			c.widths.add(w)
+			c.hist.add(w)
+		}
+	}
+	c.last = evt
+	c.haveLast = true
+}
+
+// Measurement returns the current measurement of the signal.
+func (c *Capture) Measurement() Measurement {
+	now := timing.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := Measurement{
+		Edges:  c.edges,
+		Missed: c.missed,
+	}
+	if now-c.lastSeen > c.stallTimeout {
+		m.Stalled = true
+		if c.haveLast && c.last.Type == gpiod.LineEventRisingEdge {
+			m.Duty = 1
+		}
+		return m
+	}
+	m.Samples = c.periods.n
+	if m.Samples == 0 {
+		return m
+	}
+	m.Period = c.periods.mean()
+	if m.Period > 0 {
+		m.Frequency = float64(time.Second) / float64(m.Period)
+	}
+	if c.widths.n != 0 {
+		m.PulseWidth = c.widths.mean()
+		if m.Period > 0 {
+			m.Duty = float64(m.PulseWidth) / float64(m.Period)
+			if m.Duty > 1 {
+				m.Duty = 1
+			}
+		}
+	}
+	return m
+}
+
+// Period returns the mean period of the signal over the window.
+func (c *Capture) Period() time.Duration {
+	return c.Measurement().Period
+}
+
+// Frequency returns the frequency of the signal, in Hz.
+func (c *Capture) Frequency() float64 {
+	return c.Measurement().Frequency
+}
+
+// Duty returns the fraction of each period the signal is active.
+func (c *Capture) Duty() float64 {
+	return c.Measurement().Duty
+}
+
+// PulseWidth returns the mean time the signal is active in each period.
+func (c *Capture) PulseWidth() time.Duration {
+	return c.Measurement().PulseWidth
+}
+
+// Histogram returns the distribution of the pulse widths seen since the last
+// reset.
+func (c *Capture) Histogram() Histogram {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := Histogram{
+		Bounds: append([]time.Duration(nil), c.hist.Bounds...),
+		Counts: append([]uint64(nil), c.hist.Counts...),
+	}
+	return h
+}
+
+// Reset discards all measurements.
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.restart()
+	c.haveLast = false
+	c.periods.reset()
+	c.widths.reset()
+	for i := range c.hist.Counts {
+		c.hist.Counts[i] = 0
+	}
+	c.edges = 0
+	c.missed = 0
+	c.lastSeen = timing.Now()
+}
+
+// restart forgets the previous edges so the next intervals are measured from
+// the next edges.
+func (c *Capture) restart() {
+	c.haveLastRising = false
+	c.haveLastFalling = false
+}
+
+func (h *Histogram) add(w time.Duration) {
+	i := sort.Search(len(h.Bounds), func(i int) bool { return w <= h.Bounds[i] })
+	h.Counts[i]++
+}
+
+// ring is a fixed size buffer of the most recent samples.
+type ring struct {
+	samples []time.Duration
+	// the index of the next sample
+	idx int
+	// the number of samples in the buffer
+	n int
+	// the sum of the samples in the buffer
+	sum time.Duration
+}
+
+func newRing(size int) ring {
+	return ring{samples: make([]time.Duration, size)}
+}
+
+func (r *ring) add(s time.Duration) {
+	if r.n == len(r.samples) {
+		r.sum -= r.samples[r.idx]
+	} else {
+		r.n++
+	}
+	r.samples[r.idx] = s
+	r.sum += s
+	r.idx = (r.idx + 1) % len(r.samples)
+}
+
+func (r *ring) mean() time.Duration {
+	return r.sum / time.Duration(r.n)
+}
+
+func (r *ring) reset() {
+	r.idx = 0
+	r.n = 0
+	r.sum = 0
+}
+
+// Option specifies a construction option for the Capture.
+type Option func(*Capture)
+
+// WithWindow sets the number of samples averaged to determine the period and
+// pulse width.
+func WithWindow(samples int) Option {
+	return func(c *Capture) {
+		if samples <= 0 {
+			c.err = ErrInvalidWindow
+			return
+		}
+		c.window = samples
+	}
+}
+
+// WithStallTimeout sets the time without an edge after which the signal is
+// considered stalled and its frequency reported as zero.
+func WithStallTimeout(timeout time.Duration) Option {
+	return func(c *Capture) {
+		if timeout <= 0 {
+			c.err = ErrInvalidTimeout
+			return
+		}
+		c.stallTimeout = timeout
+	}
+}
+
+// WithHistogram sets the upper bounds of the bins of the pulse width
+// histogram.
+//
+// The bounds must be in increasing order.  Pulses wider than the last bound
+// are counted in an additional bin.
+func WithHistogram(bounds ...time.Duration) Option {
+	return func(c *Capture) {
+		for i := 1; i < len(bounds); i++ {
+			if bounds[i] <= bounds[i-1] {
+				c.err = ErrInvalidBounds
+				return
+			}
+		}
+		c.hist.Bounds = append([]time.Duration(nil), bounds...)
+	}
+}
+
+var (
+	// ErrInvalidBounds indicates the histogram bounds are not in increasing
+	// order.
+	ErrInvalidBounds = errors.New("invalid histogram bounds")
+
+	// ErrInvalidTimeout indicates the stall timeout is not positive.
+	ErrInvalidTimeout = errors.New("invalid stall timeout")
+
+	// ErrInvalidWindow indicates the window is not positive.
+	ErrInvalidWindow = errors.New("invalid window")
+)
@@ -0,0 +1,211 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package capture_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/capture"
+	"github.com/warthog618/gpiod/mockup"
+)
+
+func TestNew(t *testing.T) {
+	patterns := []struct {
+		name    string
+		options []capture.Option
+		err     error
+	}{
+		{"window", []capture.Option{capture.WithWindow(0)}, capture.ErrInvalidWindow},
+		{"timeout", []capture.Option{capture.WithStallTimeout(0)}, capture.ErrInvalidTimeout},
+		{"bounds",
+			[]capture.Option{capture.WithHistogram(time.Millisecond, time.Millisecond)},
+			capture.ErrInvalidBounds},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			c, err := capture.New(p.options...)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, c)
+		}
+		t.Run(p.name, tf)
+	}
+
+	c, err := capture.New()
+	assert.Nil(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, capture.Measurement{}, c.Measurement())
+	assert.Equal(t, capture.Histogram{Counts: []uint64{0}}, c.Histogram())
+}
+
+func TestMeasurement(t *testing.T) {
+	c, err := capture.New(capture.WithWindow(4))
+	require.Nil(t, err)
+
+	// 1kHz with 25% duty
+	g := generator{seqno: 1}
+	g.pulses(c, 3, 250*time.Microsecond, time.Millisecond)
+	m := c.Measurement()
+	assert.Equal(t, time.Millisecond, m.Period)
+	assert.Equal(t, 1000.0, m.Frequency)
+	assert.Equal(t, 250*time.Microsecond, m.PulseWidth)
+	assert.Equal(t, 0.25, m.Duty)
+	assert.Equal(t, 4, m.Samples)
+	assert.Equal(t, uint64(6), m.Edges)
+	assert.Zero(t, m.Missed)
+	assert.False(t, m.Stalled)
+
+	// window rolls over to 2kHz with 50% duty
+	g.pulses(c, 2, 250*time.Microsecond, 500*time.Microsecond)
+	assert.Equal(t, 750*time.Microsecond, c.Period())
+	g.pulses(c, 2, 250*time.Microsecond, 500*time.Microsecond)
+	assert.Equal(t, 500*time.Microsecond, c.Period())
+	assert.Equal(t, 2000.0, c.Frequency())
+	assert.Equal(t, 250*time.Microsecond, c.PulseWidth())
+	assert.Equal(t, 0.5, c.Duty())
+
+	c.Reset()
+	assert.Equal(t, capture.Measurement{}, c.Measurement())
+}
+
+func TestGaps(t *testing.T) {
+	c, err := capture.New(capture.WithWindow(4))
+	require.Nil(t, err)
+
+	g := generator{seqno: 1}
+	g.pulses(c, 3, 250*time.Microsecond, time.Millisecond)
+
+	// lose a falling and rising edge, which would otherwise appear as a
+	// 2ms period and 1.25ms pulse.
+	g.ts += 2 * time.Millisecond
+	g.seqno += 2
+	g.pulses(c, 1, 250*time.Microsecond, time.Millisecond)
+	m := c.Measurement()
+	assert.Equal(t, uint64(2), m.Missed)
+	assert.Equal(t, time.Millisecond, m.Period)
+	assert.Equal(t, 250*time.Microsecond, m.PulseWidth)
+
+	// without seqnos, missing a falling edge produces consecutive rising edges.
+	c.Reset()
+	g = generator{}
+	g.pulses(c, 3, 500*time.Microsecond, time.Millisecond)
+	c.HandleEvent(gpiod.LineEvent{Timestamp: g.ts, Type: gpiod.LineEventRisingEdge})
+	g.ts += 2 * time.Millisecond
+	g.pulses(c, 1, 500*time.Microsecond, time.Millisecond)
+	m = c.Measurement()
+	assert.Equal(t, uint64(1), m.Missed)
+	assert.Equal(t, time.Millisecond, m.Period)
+	assert.Equal(t, 500*time.Microsecond, m.PulseWidth)
+}
+
+func TestStall(t *testing.T) {
+	c, err := capture.New(capture.WithStallTimeout(20 * time.Millisecond))
+	require.Nil(t, err)
+
+	g := generator{seqno: 1}
+	g.pulses(c, 3, 250*time.Microsecond, time.Millisecond)
+	assert.Equal(t, 1000.0, c.Frequency())
+	time.Sleep(30 * time.Millisecond)
+	m := c.Measurement()
+	assert.True(t, m.Stalled)
+	assert.Zero(t, m.Frequency)
+	assert.Zero(t, m.Period)
+	assert.Zero(t, m.Duty)
+
+	// stalled high
+	g.ts += time.Second
+	c.HandleEvent(gpiod.LineEvent{
+		Timestamp: g.ts,
+		Type:      gpiod.LineEventRisingEdge,
+		LineSeqno: g.seqno,
+	})
+	g.seqno++
+	time.Sleep(30 * time.Millisecond)
+	m = c.Measurement()
+	assert.True(t, m.Stalled)
+	assert.Equal(t, 1.0, m.Duty)
+
+	// samples from before the stall are discarded
+	g.ts += time.Second
+	g.pulses(c, 2, 100*time.Microsecond, 200*time.Microsecond)
+	m = c.Measurement()
+	assert.False(t, m.Stalled)
+	assert.Equal(t, 200*time.Microsecond, m.Period)
+	assert.Equal(t, 100*time.Microsecond, m.PulseWidth)
+}
+
+func TestHistogram(t *testing.T) {
+	c, err := capture.New(capture.WithHistogram(100*time.Microsecond, time.Millisecond))
+	require.Nil(t, err)
+
+	g := generator{seqno: 1}
+	g.pulses(c, 1, 50*time.Microsecond, 2*time.Millisecond)
+	g.pulses(c, 2, 100*time.Microsecond, 2*time.Millisecond)
+	g.pulses(c, 3, 500*time.Microsecond, 2*time.Millisecond)
+	g.pulses(c, 1, 1500*time.Microsecond, 2*time.Millisecond)
+	h := c.Histogram()
+	assert.Equal(t, []time.Duration{100 * time.Microsecond, time.Millisecond}, h.Bounds)
+	assert.Equal(t, []uint64{3, 3, 1}, h.Counts)
+
+	c.Reset()
+	assert.Equal(t, []uint64{0, 0, 0}, c.Histogram().Counts)
+}
+
+func TestRequestLine(t *testing.T) {
+	m, err := mockup.New([]int{4}, false)
+	require.Nil(t, err)
+	defer m.Close()
+	mc, err := m.Chip(0)
+	require.Nil(t, err)
+
+	c, err := capture.New()
+	require.Nil(t, err)
+	l, err := c.RequestLine(mc.Name, 2)
+	require.Nil(t, err)
+	defer l.Close()
+
+	for i := 0; i < 3; i++ {
+		mc.SetValue(2, 1)
+		time.Sleep(5 * time.Millisecond)
+		mc.SetValue(2, 0)
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	cm := c.Measurement()
+	assert.Equal(t, uint64(6), cm.Edges)
+	assert.NotZero(t, cm.Frequency)
+	assert.Greater(t, cm.PulseWidth, time.Duration(0))
+}
+
+// generator produces the events for a train of pulses.
+type generator struct {
+	ts    time.Duration
+	seqno uint32
+}
+
+// pulses feeds n pulses of the width and period to the Capture.
+//
+// If the seqno is zero the events have no seqnos, as for uAPI v1.
+func (g *generator) pulses(c *capture.Capture, n int, width, period time.Duration) {
+	for i := 0; i < n; i++ {
+		c.HandleEvent(g.event(g.ts, gpiod.LineEventRisingEdge))
+		c.HandleEvent(g.event(g.ts+width, gpiod.LineEventFallingEdge))
+		g.ts += period
+	}
+}
+
+func (g *generator) event(ts time.Duration, typ gpiod.LineEventType) gpiod.LineEvent {
+	evt := gpiod.LineEvent{Offset: 2, Timestamp: ts, Type: typ, LineSeqno: g.seqno}
+	if g.seqno != 0 {
+		g.seqno++
+	}
+	return evt
+}
@@ -13,15 +13,21 @@ import (
 )
 
 func init() {
+	detectCmd.Flags().BoolVarP(&detectOpts.Verbose, "verbose", "v", false, "display the features supported by each chip")
 	rootCmd.AddCommand(detectCmd)
 }
 
-var detectCmd = &cobra.Command{
-	Use:   "detect",
-	Short: "Detect available GPIO chips",
-	Long:  `List all GPIO chips, print their labels and number of GPIO lines.`,
-	Run:   detect,
-}
+var (
+	detectCmd = &cobra.Command{
+		Use:   "detect",
+		Short: "Detect available GPIO chips",
+		Long:  `List all GPIO chips, print their labels and number of GPIO lines.`,
+		Run:   detect,
+	}
+	detectOpts = struct {
+		Verbose bool
+	}{}
+)
 
 func detect(cmd *cobra.Command, args []string) {
 	rc := 0
@@ -35,7 +41,33 @@ func detect(cmd *cobra.Command, args []string) {
 		}
 		fmt.Printf("%s [%s] (%d lines) using kernel uAPI v%d\n",
 			c.Name, c.Label, c.Lines(), c.UapiAbiVersion())
+		if detectOpts.Verbose {
+			f, err := c.Features()
+			if err != nil {
+				logErr(cmd, err)
+				rc = 1
+			} else {
+				printFeatures(f)
+			}
+		}
 		c.Close()
 	}
 	os.Exit(rc)
 }
+
+func printFeatures(f gpiod.ChipFeatures) {
+	fmt.Printf("\tkernel uAPI:          v%d\n", f.AbiVersion)
+	fmt.Printf("\tbias:                 %s\n", supported(f.Bias))
+	fmt.Printf("\treconfigure:          %s\n", supported(f.Reconfigure))
+	fmt.Printf("\tinfo watch:           %s\n", supported(f.InfoWatch))
+	fmt.Printf("\tedge detection:       %s\n", supported(f.EdgeDetection))
+	fmt.Printf("\tdebounce:             %s\n", supported(f.Debounce))
+	fmt.Printf("\trealtime event clock: %s\n", supported(f.RealtimeEventClock))
+}
+
+func supported(s bool) string {
+	if s {
+		return "supported"
+	}
+	return "unsupported"
+}
@@ -7,7 +7,6 @@ package main
 import (
 	"fmt"
 	"strconv"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/warthog618/gpiod"
@@ -54,12 +53,15 @@ func get(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	opts, err := makeGetOpts()
+	if err != nil {
+		return err
+	}
 	c, err := gpiod.NewChip(name, gpiod.WithConsumer("gpiodctl-get"))
 	if err != nil {
 		return err
 	}
 	defer c.Close()
-	opts := makeGetOpts()
 	l, err := c.RequestLines(oo, opts...)
 	if err != nil {
 		return fmt.Errorf("error requesting GPIO line: %s", err)
@@ -78,7 +80,7 @@ func get(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func makeGetOpts() []gpiod.LineReqOption {
+func makeGetOpts() ([]gpiod.LineReqOption, error) {
 	opts := []gpiod.LineReqOption{}
 	if getOpts.ActiveLow {
 		opts = append(opts, gpiod.AsActiveLow)
@@ -86,22 +88,15 @@ func makeGetOpts() []gpiod.LineReqOption {
 	if !getOpts.AsIs {
 		opts = append(opts, gpiod.AsInput)
 	}
-	bias := strings.ToLower(getOpts.Bias)
-	switch bias {
-	case "pull-up":
-		opts = append(opts, gpiod.WithPullUp)
-	case "pull-down":
-		opts = append(opts, gpiod.WithPullDown)
-	case "disable":
-		opts = append(opts, gpiod.WithBiasDisabled)
-	case "as-is":
-		fallthrough
-	default:
+	var bias gpiod.LineBias
+	if err := bias.UnmarshalText([]byte(getOpts.Bias)); err != nil {
+		return nil, err
 	}
+	opts = append(opts, bias)
 	if getOpts.AbiV != 0 {
 		opts = append(opts, gpiod.WithABIVersion(getOpts.AbiV))
 	}
-	return opts
+	return opts, nil
 }
 
 func parseOffsets(args []string) ([]int, error) {
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
@@ -82,7 +81,10 @@ func mon(cmd *cobra.Command, args []string) error {
 	eh := func(evt gpiod.LineEvent) {
 		evtchan <- evt
 	}
-	opts := makeMonOpts(eh)
+	opts, err := makeMonOpts(eh)
+	if err != nil {
+		return err
+	}
 	l, err := c.RequestLines(oo, opts...)
 	if err != nil {
 		return fmt.Errorf("error requesting GPIO lines: %s", err)
@@ -132,36 +134,25 @@ func monWait(evtchan <-chan gpiod.LineEvent) {
 	}
 }
 
-func makeMonOpts(eh gpiod.EventHandler) []gpiod.LineReqOption {
+func makeMonOpts(eh gpiod.EventHandler) ([]gpiod.LineReqOption, error) {
 	opts := []gpiod.LineReqOption{gpiod.WithEventHandler(eh)}
 	if monOpts.ActiveLow {
 		opts = append(opts, gpiod.AsActiveLow)
 	}
-	edge := strings.ToLower(monOpts.Edge)
-	switch edge {
-	case "falling":
-		opts = append(opts, gpiod.WithFallingEdge)
-	case "rising":
-		opts = append(opts, gpiod.WithRisingEdge)
-	case "both":
-		fallthrough
-	default:
-		opts = append(opts, gpiod.WithBothEdges)
+	var edge gpiod.LineEdge
+	if err := edge.UnmarshalText([]byte(monOpts.Edge)); err != nil {
+		return nil, err
+	}
+	if edge == gpiod.LineEdgeNone {
+		return nil, fmt.Errorf("edge detection required")
 	}
-	bias := strings.ToLower(monOpts.Bias)
-	switch bias {
-	case "pull-up":
-		opts = append(opts, gpiod.WithPullUp)
-	case "pull-down":
-		opts = append(opts, gpiod.WithPullDown)
-	case "disable":
-		opts = append(opts, gpiod.WithBiasDisabled)
-	case "as-is":
-		fallthrough
-	default:
+	var bias gpiod.LineBias
+	if err := bias.UnmarshalText([]byte(monOpts.Bias)); err != nil {
+		return nil, err
 	}
+	opts = append(opts, edge, bias)
 	if monOpts.DebouncePeriod != 0 {
 		opts = append(opts, gpiod.WithDebounce(monOpts.DebouncePeriod))
 	}
-	return opts
+	return opts, nil
 }
@@ -99,12 +99,15 @@ func set(cmd *cobra.Command, args []string) error {
 		ll = append(ll, o)
 		vv = append(vv, v)
 	}
+	opts, err := makeSetOpts(vv)
+	if err != nil {
+		return err
+	}
 	c, err := gpiod.NewChip(name, gpiod.WithConsumer("gpiodctl-set"))
 	if err != nil {
 		return err
 	}
 	defer c.Close()
-	opts := makeSetOpts(vv)
 	l, err := c.RequestLines(ll, opts...)
 	if err != nil {
 		return fmt.Errorf("error requesting GPIO line: %s", err)
@@ -147,37 +150,24 @@ func setWait() {
 	}
 }
 
-func makeSetOpts(vv []int) []gpiod.LineReqOption {
+func makeSetOpts(vv []int) ([]gpiod.LineReqOption, error) {
 	opts := []gpiod.LineReqOption{gpiod.AsOutput(vv...)}
 	if setOpts.ActiveLow {
 		opts = append(opts, gpiod.AsActiveLow)
 	}
-	bias := strings.ToLower(setOpts.Bias)
-	switch bias {
-	case "pull-up":
-		opts = append(opts, gpiod.WithPullUp)
-	case "pull-down":
-		opts = append(opts, gpiod.WithPullDown)
-	case "disable":
-		opts = append(opts, gpiod.WithBiasDisabled)
-	case "as-is":
-		fallthrough
-	default:
-	}
-	drive := strings.ToLower(setOpts.Drive)
-	switch drive {
-	case "open-drain":
-		opts = append(opts, gpiod.AsOpenDrain)
-	case "open-source":
-		opts = append(opts, gpiod.AsOpenSource)
-	case "push-pull":
-		fallthrough
-	default:
+	var bias gpiod.LineBias
+	if err := bias.UnmarshalText([]byte(setOpts.Bias)); err != nil {
+		return nil, err
+	}
+	var drive gpiod.LineDrive
+	if err := drive.UnmarshalText([]byte(setOpts.Drive)); err != nil {
+		return nil, err
 	}
+	opts = append(opts, bias, drive)
 	if setOpts.AbiV != 0 {
 		opts = append(opts, gpiod.WithABIVersion(setOpts.AbiV))
 	}
-	return opts
+	return opts, nil
 }
 
 func parseLineValue(arg string) (int, int, error) {
@@ -0,0 +1,429 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package encoder decodes quadrature rotary encoders connected to a pair of
+// lines.
+//
+// The A and B lines, and the optional index line, are requested together with
+// edge detection on both edges, so the edges are decoded in the order they
+// were detected by the kernel.
+package encoder
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// Mode determines how many counts the encoder produces per quadrature cycle.
+type Mode int
+
+const (
+	// X4 counts every edge on both lines, so four counts per cycle.
+	X4 Mode = iota
+
+	// X2 counts every edge on the A line, so two counts per cycle.
+	X2
+
+	// X1 counts one edge on the A line per cycle.
+	X1
+)
+
+// Direction indicates the direction of rotation.
+type Direction int
+
+const (
+	// Stopped indicates the encoder has not moved.
+	Stopped Direction = 0
+
+	// Forward indicates the A line leads the B line.
+	Forward Direction = 1
+
+	// Reverse indicates the B line leads the A line.
+	Reverse Direction = -1
+)
+
+// Event describes a change in the encoder position.
+type Event struct {
+	// The position of the encoder after the change.
+	Position int64
+
+	// The change in position, +1 or -1, or 0 for an index event.
+	Delta int
+
+	// The velocity of the encoder, in counts per second.
+	//
+	// The velocity is negative when moving in reverse.
+	Velocity float64
+
+	// The time the edge was detected, as per gpiod.LineEvent.
+	Timestamp time.Duration
+
+	// True if the event is the index line going active, which resets the
+	// position to zero.
+	Index bool
+}
+
+// Handler receives encoder events.
+type Handler func(Event)
+
+// Encoder decodes a quadrature encoder on a pair of lines.
+type Encoder struct {
+	lines *gpiod.Lines
+
+	// the offsets of the A, B and index lines
+	a     int
+	b     int
+	index int
+
+	mode Mode
+
+	handler Handler
+	ch      chan<- Event
+
+	lineOptions []gpiod.LineReqOption
+
+	// mu covers the state below
+	mu sync.Mutex
+
+	// the current levels of the lines, with A as bit 1 and B as bit 0
+	state uint8
+
+	position int64
+
+	// the direction of the last count
+	dir Direction
+
+	// the time between the last two counts, from the event timestamps
+	interval time.Duration
+
+	// the timestamp of the last count
+	lastTs time.Duration
+
+	// the time the last count was received, on the timing clock
+	lastSeen time.Duration
+
+	// true once a count has been made
+	counted bool
+
+	// the seqno of the last event, to detect missed events
+	seqno uint32
+
+	invalid uint64
+	missed  uint64
+	dropped uint64
+
+	// an error from the construction options
+	err error
+}
+
+// New requests the A and B lines of an encoder on the chip, and starts
+// decoding their edges.
+//
+// The encoder position starts at zero.
+func New(chip string, a, b int, options ...Option) (*Encoder, error) {
+	e := Encoder{a: a, b: b, index: -1}
+	for _, option := range options {
+		option(&e)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	if a == b || e.index == a || e.index == b {
+		return nil, ErrInvalidOffsets
+	}
+	offsets := []int{a, b}
+	if e.index >= 0 {
+		offsets = append(offsets, e.index)
+	}
+	// hold the lock until the initial state is known, so early events are
+	// decoded relative to it.
+	e.mu.Lock()
+	lro := append([]gpiod.LineReqOption{gpiod.AsInput}, e.lineOptions...)
+	lro = append(lro, gpiod.WithBothEdges, gpiod.WithEventHandler(e.handleEvent))
+	ll, err := gpiod.RequestLines(chip, offsets, lro...)
+	if err != nil {
+		e.mu.Unlock()
+		return nil, err
+	}
+	vv := make([]int, len(offsets))
+	if err := ll.Values(vv); err != nil {
+		// the handler may be waiting on the lock, and Close waits for it.
+		e.mu.Unlock()
+		ll.Close()
+		return nil, err
+	}
+	e.state = uint8(vv[0]<<1 | vv[1])
+	e.lines = ll
+	e.mu.Unlock()
+	return &e, nil
+}
+
+// Close releases the lines.
+//
+// A channel provided by WithChannel is not closed.
+func (e *Encoder) Close() error {
+	return e.lines.Close()
+}
+
+// Position returns the current position of the encoder.
+func (e *Encoder) Position() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.position
+}
+
+// SetPosition sets the current position of the encoder.
+func (e *Encoder) SetPosition(position int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.position = position
+}
+
+// Direction returns the direction of the most recent count, or Stopped if
+// there has been none.
+func (e *Encoder) Direction() Direction {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dir
+}
+
+// Velocity returns the velocity of the encoder, in counts per second.
+//
+// The velocity is determined from the interval between the last two counts.
+// If the time since the last count exceeds that interval then the velocity
+// decays as if a count is imminent, so it falls towards zero once the encoder
+// stops.
+func (e *Encoder) Velocity() float64 {
+	now := timing.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	interval := e.interval
+	if interval == 0 {
+		return 0
+	}
+	if since := now - e.lastSeen; since > interval {
+		interval = since
+	}
+	return float64(e.dir) * float64(time.Second) / float64(interval)
+}
+
+// Invalid returns the number of edges that were not valid quadrature
+// transitions.
+//
+// An invalid edge does not change the position.  Invalid edges usually
+// indicate missed edges, due to bounce or the encoder turning faster than the
+// edges can be detected.
+func (e *Encoder) Invalid() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.invalid
+}
+
+// Missed returns the number of edges the kernel reported as lost due to its
+// event buffer overflowing.
+//
+// Requires uAPI v2.
+func (e *Encoder) Missed() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.missed
+}
+
+// Dropped returns the number of events that could not be sent to the channel
+// provided by WithChannel as it was full.
+func (e *Encoder) Dropped() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dropped
+}
+
+// transitions maps the previous and new states to a count, with A as bit 1
+// and B as bit 0 of each state.
+//
+// The forward sequence is 00, 10, 11, 01, with A leading B.  The zero entries
+// are for states that are unchanged or where both lines changed, neither of
+// which are valid for a single edge.
+var transitions = [16]int8{
+	// from 00
+	0, -1, 1, 0,
+	// from 01
+	1, 0, 0, -1,
+	// from 10
+	-1, 0, 0, 1,
+	// from 11
+	0, 1, -1, 0,
+}
+
+func (e *Encoder) handleEvent(evt gpiod.LineEvent) {
+	now := timing.Now()
+	e.mu.Lock()
+	ev, ok := e.decode(evt, now)
+	if ok && e.ch != nil {
+		select {
+		case e.ch <- ev:
+		default:
+			e.dropped++
+		}
+	}
+	e.mu.Unlock()
+	// called unlocked so the handler may call the Encoder methods.
+	if ok && e.handler != nil {
+		e.handler(ev)
+	}
+}
+
+// decode updates the encoder state with the edge event.
+//
+// Returns the resulting Event, and false if the edge did not produce one.
+func (e *Encoder) decode(evt gpiod.LineEvent, now time.Duration) (Event, bool) {
+	if evt.Seqno != 0 {
+		if e.seqno != 0 && evt.Seqno != e.seqno+1 {
+			e.missed += uint64(evt.Seqno - e.seqno - 1)
+		}
+		e.seqno = evt.Seqno
+	}
+	var bit uint8
+	switch evt.Offset {
+	case e.a:
+		bit = 2
+	case e.b:
+		bit = 1
+	case e.index:
+		if evt.Type != gpiod.LineEventRisingEdge {
+			return Event{}, false
+		}
+		e.position = 0
+		return Event{Timestamp: evt.Timestamp, Index: true, Velocity: e.velocity()}, true
+	default:
+		return Event{}, false
+	}
+	prev := e.state
+	e.state = prev &^ bit
+	if evt.Type == gpiod.LineEventRisingEdge {
+		e.state |= bit
+	}
+	delta := transitions[prev<<2|e.state]
+	if delta == 0 {
+		e.invalid++
+		return Event{}, false
+	}
+	switch e.mode {
+	case X2:
+		if bit != 2 {
+			return Event{}, false
+		}
+	case X1:
+		// count A rising with B low going forward, and its reverse.
+		if bit != 2 || prev|e.state != 2 {
+			return Event{}, false
+		}
+	}
+	e.position += int64(delta)
+	dir := Direction(delta)
+	if e.counted && dir == e.dir {
+		e.interval = evt.Timestamp - e.lastTs
+	} else {
+		// no interval until two counts in the same direction
+		e.interval = 0
+	}
+	e.counted = true
+	e.dir = dir
+	e.lastTs = evt.Timestamp
+	e.lastSeen = now
+	return Event{
+		Position:  e.position,
+		Delta:     int(delta),
+		Velocity:  e.velocity(),
+		Timestamp: evt.Timestamp,
+	}, true
+}
+
+// velocity returns the velocity as of the last count.
+func (e *Encoder) velocity() float64 {
+	if e.interval <= 0 {
+		return 0
+	}
+	return float64(e.dir) * float64(time.Second) / float64(e.interval)
+}
+
+// Option specifies a construction option for the Encoder.
+type Option func(*Encoder)
+
+// WithMode sets the number of counts per quadrature cycle.
+//
+// The default is X4.
+func WithMode(mode Mode) Option {
+	return func(e *Encoder) {
+		if mode < X4 || mode > X1 {
+			e.err = ErrInvalidMode
+			return
+		}
+		e.mode = mode
+	}
+}
+
+// WithIndex requests an index line, which resets the position to zero when it
+// goes active.
+func WithIndex(offset int) Option {
+	return func(e *Encoder) {
+		if offset < 0 {
+			e.err = ErrInvalidOffsets
+			return
+		}
+		e.index = offset
+	}
+}
+
+// WithDebounce sets the debounce period applied to the lines by the kernel.
+//
+// This is equivalent to WithLineOptions(gpiod.WithDebounce(period)).
+func WithDebounce(period time.Duration) Option {
+	return WithLineOptions(gpiod.WithDebounce(period))
+}
+
+// WithLineOptions adds options applied when requesting the lines, such as
+// bias or active level.
+//
+// Edge detection and event handling are set by the Encoder and must not be
+// included.
+func WithLineOptions(options ...gpiod.LineReqOption) Option {
+	return func(e *Encoder) {
+		e.lineOptions = append(e.lineOptions, options...)
+	}
+}
+
+// WithHandler sets a handler that is called for each change in position.
+//
+// The handler is called from the goroutine that reads the edge events, and so
+// should be short lived.
+func WithHandler(h Handler) Option {
+	return func(e *Encoder) {
+		e.handler = h
+	}
+}
+
+// WithChannel sets a channel that is sent each change in position.
+//
+// Events are dropped, rather than blocking decoding, if the channel is full.
+func WithChannel(ch chan<- Event) Option {
+	return func(e *Encoder) {
+		e.ch = ch
+	}
+}
+
+var (
+	// ErrInvalidMode indicates the Mode is not one of X1, X2 or X4.
+	ErrInvalidMode = errors.New("invalid mode")
+
+	// ErrInvalidOffsets indicates the line offsets are negative or not
+	// distinct.
+	ErrInvalidOffsets = errors.New("invalid offsets")
+)
@@ -0,0 +1,192 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package encoder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod/encoder"
+	"github.com/warthog618/gpiod/mockup"
+)
+
+const (
+	lineA = 1
+	lineB = 2
+	lineI = 3
+)
+
+// the states of A and B through a forward cycle
+var forward = [][2]int{{1, 0}, {1, 1}, {0, 1}, {0, 0}}
+
+func TestNew(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+
+	patterns := []struct {
+		name    string
+		a, b    int
+		options []encoder.Option
+		err     error
+	}{
+		{"same lines", lineA, lineA, nil, encoder.ErrInvalidOffsets},
+		{"index clash", lineA, lineB, []encoder.Option{encoder.WithIndex(lineB)},
+			encoder.ErrInvalidOffsets},
+		{"negative index", lineA, lineB, []encoder.Option{encoder.WithIndex(-1)},
+			encoder.ErrInvalidOffsets},
+		{"mode", lineA, lineB, []encoder.Option{encoder.WithMode(encoder.Mode(3))},
+			encoder.ErrInvalidMode},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			e, err := encoder.New(c.Name, p.a, p.b, p.options...)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, e)
+		}
+		t.Run(p.name, tf)
+	}
+
+	e, err := encoder.New(c.Name, lineA, lineB)
+	assert.Nil(t, err)
+	require.NotNil(t, e)
+	assert.Zero(t, e.Position())
+	assert.Equal(t, encoder.Stopped, e.Direction())
+	assert.Zero(t, e.Velocity())
+	assert.Nil(t, e.Close())
+}
+
+func TestDecode(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+
+	patterns := []struct {
+		name string
+		mode encoder.Mode
+		// counts per cycle
+		counts int64
+	}{
+		{"x4", encoder.X4, 4},
+		{"x2", encoder.X2, 2},
+		{"x1", encoder.X1, 1},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			e, err := encoder.New(c.Name, lineA, lineB, encoder.WithMode(p.mode))
+			require.Nil(t, err)
+			defer e.Close()
+
+			cycle(t, c, 3, false)
+			waitPosition(t, e, 3*p.counts)
+			assert.Equal(t, encoder.Forward, e.Direction())
+			assert.Greater(t, e.Velocity(), 0.0)
+
+			cycle(t, c, 2, true)
+			waitPosition(t, e, p.counts)
+			assert.Equal(t, encoder.Reverse, e.Direction())
+			assert.Less(t, e.Velocity(), 0.0)
+			assert.Zero(t, e.Invalid())
+			assert.Zero(t, e.Missed())
+
+			e.SetPosition(-5)
+			assert.Equal(t, int64(-5), e.Position())
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+
+	hch := make(chan encoder.Event, 8)
+	ch := make(chan encoder.Event, 2)
+	e, err := encoder.New(c.Name, lineA, lineB,
+		encoder.WithIndex(lineI),
+		encoder.WithChannel(ch),
+		encoder.WithHandler(func(evt encoder.Event) {
+			hch <- evt
+		}))
+	require.Nil(t, err)
+	defer e.Close()
+
+	cycle(t, c, 1, false)
+	for i := 1; i <= 4; i++ {
+		evt := waitEvent(t, hch)
+		assert.Equal(t, int64(i), evt.Position)
+		assert.Equal(t, 1, evt.Delta)
+		assert.False(t, evt.Index)
+	}
+	// the channel is full so later events are dropped
+	assert.Equal(t, int64(1), waitEvent(t, ch).Position)
+	assert.Equal(t, int64(2), waitEvent(t, ch).Position)
+	assert.Equal(t, uint64(2), e.Dropped())
+
+	c.SetValue(lineI, 1)
+	evt := waitEvent(t, hch)
+	assert.True(t, evt.Index)
+	assert.Zero(t, evt.Position)
+	assert.Zero(t, e.Position())
+
+	// index falling edge is ignored
+	c.SetValue(lineI, 0)
+	select {
+	case evt := <-hch:
+		assert.Fail(t, "unexpected event", evt)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// cycle drives the A and B lines through n quadrature cycles.
+func cycle(t *testing.T, c *mockup.Chip, n int, reverse bool) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		for j := range forward {
+			s := forward[j]
+			if reverse {
+				// the reverse cycle is the forward cycle backwards, from 00.
+				s = forward[(len(forward)-2-j+len(forward))%len(forward)]
+			}
+			require.Nil(t, c.SetValue(lineA, s[0]))
+			require.Nil(t, c.SetValue(lineB, s[1]))
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func waitPosition(t *testing.T, e *encoder.Encoder, position int64) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if e.Position() == position {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, position, e.Position())
+}
+
+func waitEvent(t *testing.T, ch <-chan encoder.Event) encoder.Event {
+	t.Helper()
+	select {
+	case evt := <-ch:
+		return evt
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for event")
+	}
+	return encoder.Event{}
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
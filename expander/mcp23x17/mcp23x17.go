@@ -0,0 +1,690 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package mcp23x17 provides a driver for the MCP23017 and MCP23S17 16-bit
+// I/O expanders.
+//
+// The MCP23S17 is accessed via an spi.Conn, and the MCP23017 via an I2C
+// device such as an i2c.Device.
+//
+// The pins of the expander are requested as Lines, and configured using
+// options that mirror those of gpiod, such as AsInput, AsOutput, WithPullUp and
+// AsActiveLow.  Pins 0-7 are GPA0-7 and pins 8-15 are GPB0-7.
+//
+// Edge events on expander pins are supported when the INT output of the
+// expander is connected to a line on a GPIO chip, as specified by
+// WithInterrupt.
+package mcp23x17
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/spi"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// NumPins is the number of pins on the expander.
+const NumPins = 16
+
+// Register addresses, with IOCON.BANK clear, for port A.
+//
+// The port B register immediately follows the port A register, so the pair
+// may be accessed as a 16-bit register, port A first.
+const (
+	regIODIR   = 0x00
+	regGPINTEN = 0x04
+	regIOCON   = 0x0a
+	regGPPU    = 0x0c
+	regINTF    = 0x0e
+	regINTCAP  = 0x10
+	regGPIO    = 0x12
+	regOLAT    = 0x14
+)
+
+// IOCON bits
+const (
+	ioconMIRROR = 0x40
+	ioconHAEN   = 0x08
+)
+
+// the SPI opcode, to which the hardware address and read flag are added.
+const spiOpcode = 0x40
+
+// I2C is a connection to a device on an I2C bus.
+//
+// It is implemented by i2c.Device.
+type I2C interface {
+	// Tx writes w to the device then reads r from it.
+	Tx(w, r []byte) error
+}
+
+// transport performs register accesses on the expander.
+type transport interface {
+	read(reg byte, data []byte) error
+	write(reg byte, data []byte) error
+}
+
+type spiTransport struct {
+	c spi.Conn
+
+	// opcode including the hardware address
+	opcode byte
+}
+
+func (t spiTransport) read(reg byte, data []byte) error {
+	r := make([]byte, 2+len(data))
+	if err := t.c.Tx([]byte{t.opcode | 1, reg}, r); err != nil {
+		return err
+	}
+	copy(data, r[2:])
+	return nil
+}
+
+func (t spiTransport) write(reg byte, data []byte) error {
+	return t.c.Tx(append([]byte{t.opcode, reg}, data...), nil)
+}
+
+type i2cTransport struct {
+	c I2C
+}
+
+func (t i2cTransport) read(reg byte, data []byte) error {
+	return t.c.Tx([]byte{reg}, data)
+}
+
+func (t i2cTransport) write(reg byte, data []byte) error {
+	return t.c.Tx(append([]byte{reg}, data...), nil)
+}
+
+// MCP23x17 is an MCP23017 or MCP23S17 I/O expander.
+type MCP23x17 struct {
+	// mu serialises access to the expander and the driver state.
+	mu sync.Mutex
+
+	t transport
+
+	// cached registers, bit n corresponding to pin n
+	iodir   uint16
+	gppu    uint16
+	gpinten uint16
+	olat    uint16
+
+	lines [NumPins]*Line
+
+	// the line connected to INT, if any
+	intr *gpiod.Line
+
+	// the chip and offset of the INT line, from options
+	intChip   *gpiod.Chip
+	intOffset int
+
+	// the sequence number of the most recent event
+	seqno uint32
+
+	closed bool
+}
+
+// NewSPI creates a driver for an MCP23S17 on the SPI connection with the
+// hardware address addr, as set by its A0-A2 pins.
+//
+// The hardware address is enabled on all expanders sharing the chip select.
+func NewSPI(c spi.Conn, addr int, options ...Option) (*MCP23x17, error) {
+	if addr < 0 || addr > 7 {
+		return nil, ErrInvalidAddress
+	}
+	// until the hardware address is enabled the expanders respond to address 0
+	t := spiTransport{c: c, opcode: spiOpcode}
+	if err := t.write(regIOCON, []byte{ioconMIRROR | ioconHAEN}); err != nil {
+		return nil, err
+	}
+	t.opcode |= byte(addr << 1)
+	return newMCP23x17(t, options...)
+}
+
+// NewI2C creates a driver for an MCP23017 on the I2C connection.
+func NewI2C(c I2C, options ...Option) (*MCP23x17, error) {
+	return newMCP23x17(i2cTransport{c: c}, options...)
+}
+
+func newMCP23x17(t transport, options ...Option) (*MCP23x17, error) {
+	x := MCP23x17{
+		t:     t,
+		iodir: 0xffff,
+	}
+	for _, option := range options {
+		option(&x)
+	}
+	// restore power-on defaults, other than the interrupt configuration,
+	// so the cached registers match the expander
+	err := t.write(regIOCON, []byte{ioconMIRROR | ioconHAEN})
+	if err != nil {
+		return nil, err
+	}
+	err = t.write(regIODIR, []byte{
+		0xff, 0xff, // IODIR
+		0, 0, // IPOL
+		0, 0, // GPINTEN
+		0, 0, // DEFVAL
+		0, 0, // INTCON
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = t.write(regGPPU, []byte{0, 0})
+	if err != nil {
+		return nil, err
+	}
+	err = t.write(regOLAT, []byte{0, 0})
+	if err != nil {
+		return nil, err
+	}
+	if x.intChip != nil {
+		// clear any pending interrupt before the handler can access the
+		// expander.  No interrupts are enabled, so none can follow.
+		if err = t.read(regINTCAP, make([]byte, 2)); err != nil {
+			return nil, err
+		}
+		x.intr, err = x.intChip.RequestLine(x.intOffset,
+			gpiod.WithFallingEdge,
+			gpiod.WithEventHandler(x.interruptHandler))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &x, nil
+}
+
+// Close releases the INT line and the requested lines.
+//
+// The expander pins are left in their current state.
+func (x *MCP23x17) Close() error {
+	x.mu.Lock()
+	if x.closed {
+		x.mu.Unlock()
+		return ErrClosed
+	}
+	x.closed = true
+	for i, l := range x.lines {
+		if l != nil {
+			l.closed = true
+			x.lines[i] = nil
+		}
+	}
+	intr := x.intr
+	x.mu.Unlock()
+	if intr != nil {
+		// outside the lock as Close waits for the interrupt handler to return
+		return intr.Close()
+	}
+	return nil
+}
+
+// RequestLine requests control of a single pin on the expander.
+//
+// The pin is initially configured as an input with the pull-up disabled.
+func (x *MCP23x17) RequestLine(offset int, options ...LineOption) (*Line, error) {
+	if offset < 0 || offset >= NumPins {
+		return nil, ErrInvalidOffset
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return nil, ErrClosed
+	}
+	if x.lines[offset] != nil {
+		return nil, ErrBusy
+	}
+	l := &Line{x: x, offset: offset}
+	if err := x.configure(l, lineConfig{}, options); err != nil {
+		return nil, err
+	}
+	x.lines[offset] = l
+	return l, nil
+}
+
+// configure applies the options to the line, starting from the base config,
+// and updates the expander to match.
+//
+// Must be called with the mu held.
+func (x *MCP23x17) configure(l *Line, base lineConfig, options []LineOption) error {
+	cfg := base
+	for _, option := range options {
+		option.applyLineOption(&cfg)
+	}
+	if cfg.edge != gpiod.LineEdgeNone && x.intr == nil {
+		return ErrNoInterrupt
+	}
+	mask := uint16(1) << uint(l.offset)
+	iodir := x.iodir | mask
+	olat := x.olat
+	if cfg.output {
+		iodir &^= mask
+		olat = setBit(olat, mask, cfg.physical(cfg.value))
+	}
+	gppu := setBit(x.gppu, mask, boolToInt(cfg.pullUp))
+	gpinten := setBit(x.gpinten, mask, boolToInt(cfg.edge != gpiod.LineEdgeNone))
+
+	// set the output latch before switching direction to avoid glitches
+	err := x.write16(regOLAT, &x.olat, olat)
+	if err == nil {
+		err = x.write16(regGPPU, &x.gppu, gppu)
+	}
+	if err == nil {
+		err = x.write16(regGPINTEN, &x.gpinten, gpinten)
+	}
+	if err == nil {
+		err = x.write16(regIODIR, &x.iodir, iodir)
+	}
+	if err != nil {
+		return err
+	}
+	l.cfg = cfg
+	return nil
+}
+
+// write16 writes a 16-bit register, if it differs from its cached value.
+//
+// Must be called with the mu held.
+func (x *MCP23x17) write16(reg byte, cache *uint16, v uint16) error {
+	if *cache == v {
+		return nil
+	}
+	if err := x.t.write(reg, []byte{byte(v), byte(v >> 8)}); err != nil {
+		return err
+	}
+	*cache = v
+	return nil
+}
+
+// readPins reads the pin levels and collects the events for any pending
+// interrupt.
+//
+// The read clears the pending interrupt, so pending events are collected on
+// every read to prevent them being lost.
+//
+// Must be called with the mu held.
+func (x *MCP23x17) readPins(timestamp func() time.Duration) (uint16, []pendingEvent, error) {
+	var data [6]byte
+	var err error
+	if x.intr == nil {
+		err = x.t.read(regGPIO, data[4:])
+	} else {
+		// INTF, INTCAP and GPIO are contiguous
+		err = x.t.read(regINTF, data[:])
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	intf := uint16(data[0]) | uint16(data[1])<<8
+	intcap := uint16(data[2]) | uint16(data[3])<<8
+	gpio := uint16(data[4]) | uint16(data[5])<<8
+	intf &= x.gpinten
+	if intf == 0 {
+		return gpio, nil, nil
+	}
+	ts := timestamp()
+	var events []pendingEvent
+	for offset, l := range x.lines {
+		mask := uint16(1) << uint(offset)
+		if l == nil || intf&mask == 0 {
+			continue
+		}
+		typ := gpiod.LineEventFallingEdge
+		if l.cfg.physical(int(intcap>>uint(offset))&1) != 0 {
+			typ = gpiod.LineEventRisingEdge
+		}
+		if !l.cfg.reports(typ) {
+			continue
+		}
+		x.seqno++
+		l.seqno++
+		events = append(events, pendingEvent{
+			eh: l.cfg.eh,
+			evt: gpiod.LineEvent{
+				Offset:    offset,
+				Timestamp: ts,
+				Type:      typ,
+				Seqno:     x.seqno,
+				LineSeqno: l.seqno,
+			},
+		})
+	}
+	return gpio, events, nil
+}
+
+// interruptHandler handles falling edges on the INT line.
+func (x *MCP23x17) interruptHandler(evt gpiod.LineEvent) {
+	x.mu.Lock()
+	if x.closed {
+		x.mu.Unlock()
+		return
+	}
+	_, events, _ := x.readPins(func() time.Duration { return evt.Timestamp })
+	x.mu.Unlock()
+	dispatch(events)
+}
+
+// pendingEvent is an event waiting to be passed to its handler, which is
+// called after the mu is released so the handler may access the expander.
+type pendingEvent struct {
+	eh  gpiod.EventHandler
+	evt gpiod.LineEvent
+}
+
+func dispatch(events []pendingEvent) {
+	for _, pe := range events {
+		if pe.eh != nil {
+			pe.eh(pe.evt)
+		}
+	}
+}
+
+// Line is a pin on the expander.
+type Line struct {
+	x      *MCP23x17
+	offset int
+	cfg    lineConfig
+
+	// the sequence number of the most recent event on the line
+	seqno uint32
+
+	closed bool
+}
+
+// Offset returns the offset of the pin on the expander.
+func (l *Line) Offset() int {
+	return l.offset
+}
+
+// Value returns the current value (active state) of the line.
+func (l *Line) Value() (int, error) {
+	x := l.x
+	x.mu.Lock()
+	if l.closed {
+		x.mu.Unlock()
+		return 0, ErrClosed
+	}
+	gpio, events, err := x.readPins(timing.Now)
+	x.mu.Unlock()
+	dispatch(events)
+	if err != nil {
+		return 0, err
+	}
+	return l.cfg.physical(int(gpio>>uint(l.offset)) & 1), nil
+}
+
+// SetValue sets the current active state of the line.
+//
+// Only valid for output lines.
+func (l *Line) SetValue(value int) error {
+	x := l.x
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	if !l.cfg.output {
+		return ErrPermissionDenied
+	}
+	mask := uint16(1) << uint(l.offset)
+	err := x.write16(regOLAT, &x.olat, setBit(x.olat, mask, l.cfg.physical(value)))
+	if err != nil {
+		return err
+	}
+	// retain the active state if the line is reconfigured
+	l.cfg.value = value
+	return nil
+}
+
+// Reconfigure updates the configuration of the line.
+//
+// Options not specified are left unchanged.
+func (l *Line) Reconfigure(options ...LineOption) error {
+	x := l.x
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	return x.configure(l, l.cfg, options)
+}
+
+// Close releases the line.
+//
+// The pin is left in its current state, other than its events being disabled.
+func (l *Line) Close() error {
+	x := l.x
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	l.closed = true
+	x.lines[l.offset] = nil
+	mask := uint16(1) << uint(l.offset)
+	return x.write16(regGPINTEN, &x.gpinten, x.gpinten&^mask)
+}
+
+// lineConfig is the configuration of a line.
+type lineConfig struct {
+	output    bool
+	value     int
+	activeLow bool
+	pullUp    bool
+	edge      gpiod.LineEdge
+	eh        gpiod.EventHandler
+}
+
+// physical converts between the active state and the physical level.
+func (lc lineConfig) physical(v int) int {
+	if v != 0 {
+		v = 1
+	}
+	if lc.activeLow {
+		v ^= 1
+	}
+	return v
+}
+
+// reports returns true if events of the type are reported for the line.
+func (lc lineConfig) reports(typ gpiod.LineEventType) bool {
+	switch lc.edge {
+	case gpiod.LineEdgeBoth:
+		return true
+	case gpiod.LineEdgeRising:
+		return typ == gpiod.LineEventRisingEdge
+	case gpiod.LineEdgeFalling:
+		return typ == gpiod.LineEventFallingEdge
+	}
+	return false
+}
+
+func setBit(v, mask uint16, bit int) uint16 {
+	if bit != 0 {
+		return v | mask
+	}
+	return v &^ mask
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Option specifies a construction option for the MCP23x17.
+type Option func(*MCP23x17)
+
+// WithInterrupt specifies the line on a GPIO chip connected to the INT output
+// of the expander, which is required for edge events on the expander pins.
+//
+// The INTA and INTB outputs are mirrored, so either may be connected.
+func WithInterrupt(c *gpiod.Chip, offset int) Option {
+	return func(x *MCP23x17) {
+		x.intChip = c
+		x.intOffset = offset
+	}
+}
+
+// LineOption specifies a configuration option for a Line.
+type LineOption interface {
+	applyLineOption(*lineConfig)
+}
+
+// InputOption indicates the line direction should be set to an input.
+type InputOption int
+
+// AsInput indicates that a line be configured as an input.
+//
+// This option overrides and clears any previous Output option.
+const AsInput = InputOption(0)
+
+func (o InputOption) applyLineOption(lc *lineConfig) {
+	lc.output = false
+}
+
+// OutputOption indicates the line direction should be set to an output.
+type OutputOption []int
+
+// AsOutput indicates that a line be configured as an output.
+//
+// The initial active state of the line can optionally be provided, else it
+// defaults to inactive.
+//
+// This option overrides and clears any previous Input or edge options.
+func AsOutput(values ...int) OutputOption {
+	vv := append([]int(nil), values...)
+	return OutputOption(vv)
+}
+
+func (o OutputOption) applyLineOption(lc *lineConfig) {
+	lc.output = true
+	lc.edge = gpiod.LineEdgeNone
+	lc.value = 0
+	if len(o) > 0 {
+		lc.value = o[0]
+	}
+}
+
+// LevelOption determines the line level that is considered active.
+type LevelOption bool
+
+// AsActiveLow indicates that a line be considered active when the line level
+// is low.
+const AsActiveLow = LevelOption(true)
+
+// AsActiveHigh indicates that a line be considered active when the line level
+// is high.
+//
+// This is the default active level.
+const AsActiveHigh = LevelOption(false)
+
+func (o LevelOption) applyLineOption(lc *lineConfig) {
+	lc.activeLow = bool(o)
+}
+
+// BiasOption determines the internal bias of the line.
+//
+// The expander only supports pull-ups.
+type BiasOption bool
+
+// WithPullUp indicates that a line have its internal pull-up enabled.
+const WithPullUp = BiasOption(true)
+
+// WithBiasDisabled indicates that a line have its internal pull-up disabled.
+//
+// This is the default bias.
+const WithBiasDisabled = BiasOption(false)
+
+func (o BiasOption) applyLineOption(lc *lineConfig) {
+	lc.pullUp = bool(o)
+}
+
+// EdgeOption determines the edges on which a line generates events.
+//
+// Requires the INT line, as specified by WithInterrupt.
+type EdgeOption gpiod.LineEdge
+
+// WithFallingEdge indicates that a line will generate events when its active
+// state transitions from high to low.
+//
+// This option sets the Input option and overrides and clears any previous
+// Output option.
+const WithFallingEdge = EdgeOption(gpiod.LineEdgeFalling)
+
+// WithRisingEdge indicates that a line will generate events when its active
+// state transitions from low to high.
+//
+// This option sets the Input option and overrides and clears any previous
+// Output option.
+const WithRisingEdge = EdgeOption(gpiod.LineEdgeRising)
+
+// WithBothEdges indicates that a line will generate events when its active
+// state transitions from low to high and from high to low.
+//
+// This option sets the Input option and overrides and clears any previous
+// Output option.
+const WithBothEdges = EdgeOption(gpiod.LineEdgeBoth)
+
+// WithoutEdges indicates that a line will not generate events.
+//
+// This is the default edge detection.
+const WithoutEdges = EdgeOption(gpiod.LineEdgeNone)
+
+func (o EdgeOption) applyLineOption(lc *lineConfig) {
+	lc.edge = gpiod.LineEdge(o)
+	if o != WithoutEdges {
+		lc.output = false
+	}
+}
+
+// EventHandlerOption specifies the handler for events on a line.
+type EventHandlerOption gpiod.EventHandler
+
+// WithEventHandler indicates that events on a line are forwarded to the
+// provided handler function.
+//
+// The events are detected by reading the expander in response to an edge on
+// the INT line, or when reading a line value, and so carry the timestamp of
+// the INT edge or the read rather than the pin transition.  Only the state
+// captured by the expander at the time of the interrupt is reported, so
+// transitions that occur while an interrupt is pending are lost.
+//
+// The handler is called from the goroutine that detected the event, without
+// the expander locked, so it may access the expander.
+func WithEventHandler(eh gpiod.EventHandler) EventHandlerOption {
+	return EventHandlerOption(eh)
+}
+
+func (o EventHandlerOption) applyLineOption(lc *lineConfig) {
+	lc.eh = gpiod.EventHandler(o)
+}
+
+var (
+	// ErrBusy indicates the line has already been requested.
+	ErrBusy = errors.New("line busy")
+
+	// ErrClosed indicates the expander or line has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrInvalidAddress indicates the hardware address is out of range.
+	ErrInvalidAddress = errors.New("invalid address")
+
+	// ErrInvalidOffset indicates the pin offset is out of range.
+	ErrInvalidOffset = errors.New("invalid offset")
+
+	// ErrNoInterrupt indicates edge detection was requested without an INT
+	// line.
+	ErrNoInterrupt = errors.New("no interrupt line")
+
+	// ErrPermissionDenied indicates the value of an input line was set.
+	ErrPermissionDenied = errors.New("permission denied")
+)
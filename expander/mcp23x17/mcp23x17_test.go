@@ -0,0 +1,528 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package mcp23x17_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/expander/mcp23x17"
+	"github.com/warthog618/gpiod/mockup"
+	"github.com/warthog618/gpiod/spi"
+)
+
+// register addresses, with IOCON.BANK clear
+const (
+	iodir   = 0x00
+	gpinten = 0x04
+	iocon   = 0x0a
+	gppu    = 0x0c
+	intf    = 0x0e
+	intcap  = 0x10
+	gpio    = 0x12
+	olat    = 0x14
+)
+
+// the mockup line connected to INT
+const intOffset = 2
+
+func TestNewSPI(t *testing.T) {
+	d := newDevice(5)
+	x, err := mcp23x17.NewSPI(d.spi(), 8)
+	assert.Equal(t, mcp23x17.ErrInvalidAddress, err)
+	assert.Nil(t, x)
+
+	// dirty the registers to check they are reset
+	d.regs[iodir] = 0
+	d.regs[gppu+1] = 0xff
+	d.regs[olat] = 0x55
+	x, err = mcp23x17.NewSPI(d.spi(), 5)
+	require.Nil(t, err)
+	require.NotNil(t, x)
+	assert.Equal(t, byte(0x48), d.regs[iocon])
+	assert.Equal(t, uint16(0xffff), d.reg16(iodir))
+	assert.Zero(t, d.reg16(gppu))
+	assert.Zero(t, d.reg16(olat))
+	assert.Nil(t, x.Close())
+	assert.Equal(t, mcp23x17.ErrClosed, x.Close())
+
+	// wrong address
+	d = newDevice(5)
+	x, err = mcp23x17.NewSPI(d.spi(), 4)
+	assert.Nil(t, err)
+	l, err := x.RequestLine(0, mcp23x17.AsOutput(1))
+	assert.Nil(t, err)
+	assert.Zero(t, d.reg16(olat))
+	assert.Nil(t, l.Close())
+	assert.Nil(t, x.Close())
+
+	// transport error
+	d = newDevice(0)
+	d.err = errors.New("tx failed")
+	x, err = mcp23x17.NewSPI(d.spi(), 0)
+	assert.Equal(t, d.err, err)
+	assert.Nil(t, x)
+}
+
+func TestNewI2C(t *testing.T) {
+	d := newDevice(0)
+	d.regs[iodir+1] = 0
+	x, err := mcp23x17.NewI2C(d.i2c())
+	require.Nil(t, err)
+	require.NotNil(t, x)
+	assert.Equal(t, uint16(0xffff), d.reg16(iodir))
+	assert.Nil(t, x.Close())
+
+	d.err = errors.New("tx failed")
+	x, err = mcp23x17.NewI2C(d.i2c())
+	assert.Equal(t, d.err, err)
+	assert.Nil(t, x)
+}
+
+func TestRequestLine(t *testing.T) {
+	d := newDevice(0)
+	x, err := mcp23x17.NewI2C(d.i2c())
+	require.Nil(t, err)
+
+	patterns := []struct {
+		name    string
+		offset  int
+		options []mcp23x17.LineOption
+		err     error
+	}{
+		{"negative offset", -1, nil, mcp23x17.ErrInvalidOffset},
+		{"high offset", 16, nil, mcp23x17.ErrInvalidOffset},
+		{"edges without interrupt", 3, []mcp23x17.LineOption{mcp23x17.WithBothEdges}, mcp23x17.ErrNoInterrupt},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			l, err := x.RequestLine(p.offset, p.options...)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, l)
+		}
+		t.Run(p.name, tf)
+	}
+
+	l, err := x.RequestLine(3)
+	require.Nil(t, err)
+	assert.Equal(t, 3, l.Offset())
+
+	// busy
+	l2, err := x.RequestLine(3)
+	assert.Equal(t, mcp23x17.ErrBusy, err)
+	assert.Nil(t, l2)
+
+	// released
+	assert.Nil(t, l.Close())
+	assert.Equal(t, mcp23x17.ErrClosed, l.Close())
+	l, err = x.RequestLine(3)
+	assert.Nil(t, err)
+
+	// closed with the expander
+	assert.Nil(t, x.Close())
+	_, err = l.Value()
+	assert.Equal(t, mcp23x17.ErrClosed, err)
+	assert.Equal(t, mcp23x17.ErrClosed, l.SetValue(1))
+	assert.Equal(t, mcp23x17.ErrClosed, l.Reconfigure(mcp23x17.AsInput))
+	assert.Equal(t, mcp23x17.ErrClosed, l.Close())
+	l, err = x.RequestLine(4)
+	assert.Equal(t, mcp23x17.ErrClosed, err)
+	assert.Nil(t, l)
+}
+
+func TestLineConfig(t *testing.T) {
+	patterns := []struct {
+		name    string
+		offset  int
+		options []mcp23x17.LineOption
+		iodir   uint16
+		gppu    uint16
+		olat    uint16
+	}{
+		{"default", 1, nil, 0xffff, 0, 0},
+		{"input", 9, []mcp23x17.LineOption{mcp23x17.AsInput}, 0xffff, 0, 0},
+		{"pull-up", 9, []mcp23x17.LineOption{mcp23x17.WithPullUp}, 0xffff, 0x0200, 0},
+		{"bias disabled", 9, []mcp23x17.LineOption{mcp23x17.WithPullUp, mcp23x17.WithBiasDisabled}, 0xffff, 0, 0},
+		{"output", 2, []mcp23x17.LineOption{mcp23x17.AsOutput()}, 0xfffb, 0, 0},
+		{"output high", 10, []mcp23x17.LineOption{mcp23x17.AsOutput(1)}, 0xfbff, 0, 0x0400},
+		{"output active low", 7, []mcp23x17.LineOption{mcp23x17.AsOutput(), mcp23x17.AsActiveLow}, 0xff7f, 0, 0x0080},
+		{"output active high", 7, []mcp23x17.LineOption{mcp23x17.AsActiveLow, mcp23x17.AsActiveHigh, mcp23x17.AsOutput(1)}, 0xff7f, 0, 0x0080},
+		{"output then input", 7, []mcp23x17.LineOption{mcp23x17.AsOutput(1), mcp23x17.AsInput}, 0xffff, 0, 0},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			d := newDevice(0)
+			x, err := mcp23x17.NewI2C(d.i2c())
+			require.Nil(t, err)
+			defer x.Close()
+			l, err := x.RequestLine(p.offset, p.options...)
+			require.Nil(t, err)
+			assert.Equal(t, p.iodir, d.reg16(iodir))
+			assert.Equal(t, p.gppu, d.reg16(gppu))
+			assert.Equal(t, p.olat, d.reg16(olat))
+			assert.Nil(t, l.Close())
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestValue(t *testing.T) {
+	d := newDevice(3)
+	x, err := mcp23x17.NewSPI(d.spi(), 3)
+	require.Nil(t, err)
+	defer x.Close()
+
+	in, err := x.RequestLine(12, mcp23x17.WithPullUp)
+	require.Nil(t, err)
+	inLow, err := x.RequestLine(0, mcp23x17.AsActiveLow)
+	require.Nil(t, err)
+	out, err := x.RequestLine(5, mcp23x17.AsOutput(1))
+	require.Nil(t, err)
+
+	for _, level := range []int{0, 1, 0} {
+		d.setInput(12, level)
+		d.setInput(0, level)
+		v, err := in.Value()
+		assert.Nil(t, err)
+		assert.Equal(t, level, v)
+		v, err = inLow.Value()
+		assert.Nil(t, err)
+		assert.Equal(t, level^1, v)
+	}
+
+	v, err := out.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	assert.Nil(t, out.SetValue(0))
+	assert.Zero(t, d.reg16(olat))
+	v, err = out.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+
+	// inputs can't be set
+	assert.Equal(t, mcp23x17.ErrPermissionDenied, in.SetValue(1))
+
+	// reconfigure retains the active state
+	assert.Nil(t, out.SetValue(1))
+	assert.Nil(t, out.Reconfigure(mcp23x17.AsActiveLow))
+	assert.Equal(t, uint16(0), d.reg16(olat))
+	v, err = out.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	assert.Nil(t, out.Reconfigure(mcp23x17.AsInput))
+	assert.Equal(t, uint16(0xffff), d.reg16(iodir))
+	assert.Equal(t, mcp23x17.ErrPermissionDenied, out.SetValue(1))
+
+	// transport error
+	d.err = errors.New("tx failed")
+	_, err = in.Value()
+	assert.Equal(t, d.err, err)
+	assert.Equal(t, d.err, in.Reconfigure(mcp23x17.AsOutput()))
+}
+
+func TestEvents(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	d := newDevice(0)
+	d.intr = func(v int) { c.SetValue(intOffset, v) }
+	require.Nil(t, c.SetValue(intOffset, 1))
+	x, err := mcp23x17.NewSPI(d.spi(), 0, mcp23x17.WithInterrupt(chip, intOffset))
+	require.Nil(t, err)
+	defer x.Close()
+
+	// INT line is busy
+	x2, err := mcp23x17.NewI2C(d.i2c(), mcp23x17.WithInterrupt(chip, intOffset))
+	assert.NotNil(t, err)
+	assert.Nil(t, x2)
+
+	ech := make(chan gpiod.LineEvent, 10)
+	eh := func(evt gpiod.LineEvent) {
+		ech <- evt
+	}
+	// inactive for the active low line
+	d.setInput(9, 1)
+	both, err := x.RequestLine(1, mcp23x17.WithBothEdges, mcp23x17.WithEventHandler(eh))
+	require.Nil(t, err)
+	rising, err := x.RequestLine(9, mcp23x17.WithRisingEdge, mcp23x17.AsActiveLow, mcp23x17.WithEventHandler(eh))
+	require.Nil(t, err)
+	assert.Equal(t, uint16(0x0202), d.reg16(gpinten))
+
+	patterns := []struct {
+		name   string
+		offset int
+		level  int
+		typ    gpiod.LineEventType
+	}{
+		{"both rising", 1, 1, gpiod.LineEventRisingEdge},
+		{"both falling", 1, 0, gpiod.LineEventFallingEdge},
+		{"active low rising", 9, 0, gpiod.LineEventRisingEdge},
+		{"active low falling", 9, 1, 0},
+	}
+	var seqno uint32
+	lineSeqno := map[int]uint32{}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			start := time.Now()
+			d.setInput(p.offset, p.level)
+			if p.typ == 0 {
+				waitNoEvent(t, ech)
+				return
+			}
+			evt := waitEvent(t, ech)
+			seqno++
+			lineSeqno[p.offset]++
+			assert.Equal(t, p.offset, evt.Offset)
+			assert.Equal(t, p.typ, evt.Type)
+			assert.Equal(t, seqno, evt.Seqno)
+			assert.Equal(t, lineSeqno[p.offset], evt.LineSeqno)
+			assert.Less(t, time.Since(start), 50*time.Millisecond)
+		}
+		t.Run(p.name, tf)
+	}
+
+	// events are not lost if the value is read before the interrupt is handled
+	d.mu.Lock()
+	d.intr = nil
+	d.mu.Unlock()
+	d.setInput(1, 1)
+	v, err := both.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	evt := waitEvent(t, ech)
+	assert.Equal(t, 1, evt.Offset)
+	assert.Equal(t, gpiod.LineEventRisingEdge, evt.Type)
+
+	// no events after the line is closed
+	assert.Nil(t, rising.Close())
+	assert.Zero(t, d.reg16(gpinten)&0x0200)
+	d.setInput(9, 0)
+	_, err = both.Value()
+	assert.Nil(t, err)
+	waitNoEvent(t, ech)
+}
+
+func waitEvent(t *testing.T, ch <-chan gpiod.LineEvent) gpiod.LineEvent {
+	t.Helper()
+	select {
+	case evt := <-ch:
+		return evt
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for event")
+	}
+	return gpiod.LineEvent{}
+}
+
+func waitNoEvent(t *testing.T, ch <-chan gpiod.LineEvent) {
+	t.Helper()
+	select {
+	case evt := <-ch:
+		assert.Fail(t, "received unexpected event", evt)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// device simulates the registers of an MCP23x17, with IOCON.BANK and
+// IOCON.SEQOP clear, so the address pointer increments through the registers.
+type device struct {
+	mu   sync.Mutex
+	addr int
+	regs [0x16]byte
+
+	// the levels applied to the pins
+	inputs uint16
+
+	// true when an interrupt is pending
+	pending bool
+
+	// sets the level of the INT output
+	intr func(v int)
+
+	// the error returned by transfers
+	err error
+}
+
+func newDevice(addr int) *device {
+	d := device{addr: addr}
+	d.regs[iodir] = 0xff
+	d.regs[iodir+1] = 0xff
+	return &d
+}
+
+func (d *device) reg16(reg int) uint16 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return uint16(d.regs[reg]) | uint16(d.regs[reg+1])<<8
+}
+
+// levels returns the level of the pins, with inputs driven externally and
+// outputs driven by the output latch.
+func (d *device) levels() uint16 {
+	dir := uint16(d.regs[iodir]) | uint16(d.regs[iodir+1])<<8
+	lat := uint16(d.regs[olat]) | uint16(d.regs[olat+1])<<8
+	return d.inputs&dir | lat&^dir
+}
+
+// setInput sets the level applied to a pin, triggering an interrupt if
+// enabled for the pin and one is not already pending.
+func (d *device) setInput(pin, v int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	mask := uint16(1) << uint(pin)
+	before := d.levels()
+	if v != 0 {
+		d.inputs |= mask
+	} else {
+		d.inputs &^= mask
+	}
+	after := d.levels()
+	en := uint16(d.regs[gpinten]) | uint16(d.regs[gpinten+1])<<8
+	changed := (before ^ after) & en
+	if changed == 0 || d.pending {
+		return
+	}
+	d.pending = true
+	d.regs[intf] = byte(changed)
+	d.regs[intf+1] = byte(changed >> 8)
+	d.regs[intcap] = byte(after)
+	d.regs[intcap+1] = byte(after >> 8)
+	if d.intr != nil {
+		d.intr(0)
+	}
+}
+
+func (d *device) read(reg int, data []byte) {
+	for i := range data {
+		switch reg {
+		case gpio, gpio + 1:
+			l := d.levels()
+			d.regs[gpio] = byte(l)
+			d.regs[gpio+1] = byte(l >> 8)
+		}
+		data[i] = d.regs[reg]
+		switch reg {
+		case intcap, intcap + 1, gpio, gpio + 1:
+			d.clearInterrupt()
+		}
+		reg = (reg + 1) % len(d.regs)
+	}
+}
+
+func (d *device) write(reg int, data []byte) {
+	for _, v := range data {
+		switch reg {
+		case intf, intf + 1, intcap, intcap + 1:
+			// read only
+		default:
+			d.regs[reg] = v
+		}
+		reg = (reg + 1) % len(d.regs)
+	}
+}
+
+func (d *device) clearInterrupt() {
+	if !d.pending {
+		return
+	}
+	d.pending = false
+	d.regs[intf] = 0
+	d.regs[intf+1] = 0
+	if d.intr != nil {
+		d.intr(1)
+	}
+}
+
+func (d *device) spi() spi.Conn {
+	return spiConn{d}
+}
+
+func (d *device) i2c() mcp23x17.I2C {
+	return i2cConn{d}
+}
+
+// spiConn is an MCP23S17 on an SPI bus.
+type spiConn struct {
+	d *device
+}
+
+func (c spiConn) Tx(w, r []byte) error {
+	d := c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	addr := 0
+	if d.regs[iocon]&0x08 != 0 {
+		addr = d.addr
+	}
+	if len(w) < 2 || w[0]&0xfe != byte(0x40|addr<<1) {
+		// not addressed
+		for i := range r {
+			r[i] = 0xff
+		}
+		return nil
+	}
+	if w[0]&1 != 0 {
+		if len(r) > 2 {
+			d.read(int(w[1]), r[2:])
+		}
+		return nil
+	}
+	d.write(int(w[1]), w[2:])
+	return nil
+}
+
+func (c spiConn) TxPackets(packets ...spi.Packet) error {
+	for _, p := range packets {
+		if err := c.Tx(p.W, p.R); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// i2cConn is an MCP23017 on an I2C bus.
+type i2cConn struct {
+	d *device
+}
+
+func (c i2cConn) Tx(w, r []byte) error {
+	d := c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	if len(w) == 0 {
+		return nil
+	}
+	if len(r) > 0 {
+		d.read(int(w[0]), r)
+		return nil
+	}
+	d.write(int(w[0]), w[1:])
+	return nil
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiod
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// ChipFeatures describes the GPIO features supported by the running kernel and
+// a particular chip.
+type ChipFeatures struct {
+	// The highest version of the GPIO uAPI supported by the kernel.
+	//
+	// This may be higher than the version the Chip is using if the Chip was
+	// created using WithABIVersion.
+	AbiVersion int
+
+	// The kernel supports setting line bias.
+	//
+	// Requires Linux v5.5 or later.
+	Bias bool
+
+	// The kernel supports reconfiguring requested lines.
+	//
+	// Requires Linux v5.5 or later.
+	Reconfigure bool
+
+	// The kernel supports watching changes to line info.
+	//
+	// Requires Linux v5.7 or later.
+	InfoWatch bool
+
+	// The chip is able to generate edge events.
+	//
+	// This is only reported true if verified by requesting an unused input
+	// line with edge detection, so it is false if the chip has no unused input
+	// lines.
+	EdgeDetection bool
+
+	// The kernel supports debouncing lines on the chip.
+	//
+	// Requires uAPI v2 and, as the kernel falls back to debouncing in software
+	// where the hardware does not support it, edge detection.
+	Debounce bool
+
+	// The kernel supports using CLOCK_REALTIME to timestamp edge events.
+	//
+	// Requires Linux v5.11 or later.
+	RealtimeEventClock bool
+}
+
+// Features returns the features supported by the running kernel and the chip.
+//
+// The features are determined by probing the kernel on the first call and
+// are cached for subsequent calls.
+//
+// Probing does not alter the configuration of any line.  The only line
+// requested is an unused line that is already an input, and it is requested
+// as an input without altering its bias, then immediately released.
+func (c *Chip) Features() (ChipFeatures, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ChipFeatures{}, ErrClosed
+	}
+	if c.features != nil {
+		return *c.features, nil
+	}
+	cf, err := probeFeatures(nameToPath(c.Name), c.lines)
+	if err != nil {
+		return ChipFeatures{}, err
+	}
+	c.features = &cf
+	return cf, nil
+}
+
+// probeFeatures determines the features supported by the kernel and chip.
+//
+// Probing uses a separate file descriptor so as not to interfere with any info
+// watches on the Chip.
+func probeFeatures(path string, lines int) (cf ChipFeatures, err error) {
+	f, err := os.OpenFile(path, unix.O_CLOEXEC, unix.O_RDONLY)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fd := f.Fd()
+	kv := kernelVersion()
+
+	cf.AbiVersion = 1
+	if _, err := uapi.GetLineInfoV2(fd, 0); err == nil {
+		cf.AbiVersion = 2
+	}
+	v2 := cf.AbiVersion == 2
+	cf.Bias = v2 || kv.atLeast(5, 5)
+	cf.Reconfigure = v2 || kv.atLeast(5, 5)
+
+	if v2 {
+		li := uapi.LineInfoV2{}
+		if uapi.WatchLineInfoV2(fd, &li) == nil {
+			cf.InfoWatch = true
+		}
+	} else {
+		li := uapi.LineInfo{}
+		if uapi.WatchLineInfo(fd, &li) == nil {
+			cf.InfoWatch = true
+		}
+	}
+	if cf.InfoWatch {
+		uapi.UnwatchLineInfo(fd, 0)
+	}
+
+	offset := probeLine(fd, lines, v2)
+	if offset < 0 {
+		cf.RealtimeEventClock = kv.atLeast(5, 11)
+		return
+	}
+	if v2 {
+		switch probeEdgesV2(fd, offset, true) {
+		case nil:
+			cf.EdgeDetection = true
+			cf.RealtimeEventClock = true
+		case unix.EINVAL:
+			cf.EdgeDetection = probeEdgesV2(fd, offset, false) == nil
+		}
+	} else {
+		cf.EdgeDetection = probeEdges(fd, offset) == nil
+		cf.RealtimeEventClock = kv.atLeast(5, 11)
+	}
+	cf.Debounce = v2 && cf.EdgeDetection
+	return
+}
+
+// probeLine returns the offset of an unused input line, or -1 if there is
+// none.
+func probeLine(fd uintptr, lines int, v2 bool) int {
+	for o := 0; o < lines; o++ {
+		if v2 {
+			li, err := uapi.GetLineInfoV2(fd, o)
+			if err == nil && !li.Flags.IsUsed() && li.Flags.IsInput() {
+				return o
+			}
+			continue
+		}
+		li, err := uapi.GetLineInfo(fd, o)
+		if err == nil && !li.Flags.IsUsed() && !li.Flags.IsOut() {
+			return o
+		}
+	}
+	return -1
+}
+
+func probeEdges(fd uintptr, offset int) error {
+	er := uapi.EventRequest{
+		Offset:      uint32(offset),
+		HandleFlags: uapi.HandleRequestInput,
+		EventFlags:  uapi.EventRequestBothEdges,
+	}
+	copy(er.Consumer[:], "gpiod-probe")
+	err := uapi.GetLineEvent(fd, &er)
+	if err == nil {
+		unix.Close(int(er.Fd))
+	}
+	return err
+}
+
+func probeEdgesV2(fd uintptr, offset int, realtime bool) error {
+	lr := uapi.LineRequest{Lines: 1}
+	lr.Offsets[0] = uint32(offset)
+	lr.Config.Flags = uapi.LineFlagV2Input |
+		uapi.LineFlagV2EdgeRising |
+		uapi.LineFlagV2EdgeFalling
+	if realtime {
+		lr.Config.Flags |= uapi.LineFlagV2EventClockRealtime
+	}
+	copy(lr.Consumer[:], "gpiod-probe")
+	err := uapi.GetLine(fd, &lr)
+	if err == nil {
+		unix.Close(int(lr.Fd))
+	}
+	return err
+}
+
+// semver is the major and minor version of the kernel.
+type semver [2]int
+
+func (v semver) atLeast(major, minor int) bool {
+	if v[0] != major {
+		return v[0] > major
+	}
+	return v[1] >= minor
+}
+
+// kernelVersion returns the version of the running kernel, or zero if it
+// cannot be determined.
+func kernelVersion() (v semver) {
+	uname := unix.Utsname{}
+	if unix.Uname(&uname) != nil {
+		return
+	}
+	release := uapi.BytesToString(uname.Release[:])
+	vers := regexp.MustCompile(`^(\d+)\.(\d+)`).FindStringSubmatch(release)
+	if len(vers) != 3 {
+		return
+	}
+	for i, vf := range vers[1:] {
+		n, err := strconv.Atoi(vf)
+		if err != nil {
+			return semver{}
+		}
+		v[i] = n
+	}
+	return
+}
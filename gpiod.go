@@ -43,6 +43,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/warthog618/gpiod/timing"
 	"github.com/warthog618/gpiod/uapi"
 	"golang.org/x/sys/unix"
 )
@@ -71,11 +72,18 @@ type Chip struct {
 	// handlers for info changes in watched lines, keyed by offset.
 	ich map[int]InfoChangeHandler
 
+	// features supported by the kernel and chip, once probed.
+	features *ChipFeatures
+
 	// indicates the chip has been closed.
 	closed bool
 }
 
 // LineConfig contains the configuration parameters for the line.
+//
+// A LineConfig may be used as an option, such as after being parsed with
+// ParseLineConfig, in which case it replaces the entire configuration of the
+// lines, other than their output values.
 type LineConfig struct {
 	// A flag indicating if the line is active low.
 	ActiveLow bool
@@ -103,6 +111,10 @@ type LineConfig struct {
 }
 
 // LineDirection indicates the direction of a line.
+//
+// A LineDirection may be used as an option, in which case
+// LineDirectionInput is equivalent to AsInput, LineDirectionOutput to
+// AsOutput(), and LineDirectionUnknown to AsIs.
 type LineDirection int
 
 const (
@@ -180,19 +192,19 @@ const (
 // line.
 type LineInfo struct {
 	// The line offset within the chip.
-	Offset int
+	Offset int `json:"offset"`
 
 	// The system name for the line.
-	Name string
+	Name string `json:"name"`
 
 	// A string identifying the requester of the line, if requested.
-	Consumer string
+	Consumer string `json:"consumer"`
 
 	// The line is in use.
-	Used bool
+	Used bool `json:"used"`
 
 	// The configuration parameters for the line.
-	Config LineConfig
+	Config LineConfig `json:"config"`
 }
 
 // Chips returns the names of the available GPIO devices.
@@ -421,18 +433,7 @@ func (c *Chip) RequestLine(offset int, options ...LineReqOption) (*Line, error)
 	if err != nil {
 		return nil, err
 	}
-	l := Line{
-		baseLine: baseLine{
-			offsets: ll.offsets,
-			values:  ll.values,
-			vfd:     ll.vfd,
-			isEvent: ll.isEvent,
-			chip:    ll.chip,
-			abi:     ll.abi,
-			defCfg:  ll.defCfg,
-			watcher: ll.watcher,
-		},
-	}
+	l := Line{ll.baseLine}
 	return &l, nil
 }
 
@@ -459,28 +460,27 @@ func (c *Chip) RequestLines(offsets []int, options ...LineReqOption) (*Lines, er
 	for _, option := range options {
 		option.applyLineReqOption(&lro)
 	}
-	ll := Lines{
-		baseLine: baseLine{
-			offsets: offsets,
-			values:  lro.values,
-			chip:    c.Name,
-			abi:     lro.abi,
-			defCfg:  lro.defCfg,
-		},
+	lr := lineRequest{
+		offsets: offsets,
+		chip:    c.Name,
+		abi:     lro.abi,
+		done:    make(chan struct{}),
 	}
+	lr.setConfig(lro.lineConfigOptions)
+	ll := Lines{baseLine{lineRequest: &lr, offsets: offsets}}
 	var err error
-	if ll.abi == 2 {
-		ll.vfd, ll.watcher, err = c.getLine(ll.offsets, lro)
+	if lr.abi == 2 {
+		lr.vfd, lr.watcher, err = c.getLine(offsets, lro)
 	} else {
 		err = lro.defCfg.v1Validate()
 		if err != nil {
 			return nil, err
 		}
 		if lro.eh == nil {
-			ll.vfd, err = c.getHandleRequest(ll.offsets, lro)
+			lr.vfd, err = c.getHandleRequest(offsets, lro)
 		} else {
-			ll.isEvent = true
-			ll.vfd, ll.watcher, err = c.getEventRequest(ll.offsets, lro)
+			lr.isEvent = true
+			lr.vfd, lr.watcher, err = c.getEventRequest(offsets, lro)
 		}
 	}
 	if err != nil {
@@ -755,7 +755,9 @@ func (c *Chip) UapiAbiVersion() int {
 	return c.options.abi
 }
 
-type baseLine struct {
+// lineRequest is the state of a request to the kernel, shared by the handles
+// on the request.
+type lineRequest struct {
 	offsets []int
 	vfd     uintptr
 	isEvent bool
@@ -763,12 +765,242 @@ type baseLine struct {
 	abi     int
 	// mu covers all that follow - those above are immutable
 	mu      sync.Mutex
-	values  map[int]int
 	defCfg  LineConfig
 	lineCfg map[int]*LineConfig
-	info    []*LineInfo
 	closed  bool
 	watcher io.Closer
+
+	// the cached output values, indexed by position in the request.
+	values uapi.LineBitmap
+
+	// the lines that are outputs, indexed by position in the request.
+	outputs uapi.LineBitmap
+
+	// closed when the request is closed, to abort scheduled changes.
+	done chan struct{}
+
+	// delayMu serialises scheduled output changes, and covers the delay.
+	//
+	// If both are required then delayMu must be locked before mu.
+	delayMu sync.Mutex
+
+	// the delay used by scheduled output changes, created on first use.
+	delay *timing.Delay
+}
+
+// setConfig updates the cached configuration of the request.
+//
+// Assumes r is locked.
+func (r *lineRequest) setConfig(lco lineConfigOptions) {
+	r.defCfg = lco.defCfg
+	r.lineCfg = lco.lineCfg
+	r.values = 0
+	r.outputs = 0
+	for idx, offset := range r.offsets {
+		r.values = r.values.Set(idx, lco.values[offset])
+		cfg := lco.defCfg
+		if lc := lco.lineCfg[offset]; lc != nil && r.abi != 1 {
+			cfg = *lc
+		}
+		if cfg.Direction == LineDirectionOutput {
+			r.outputs = r.outputs.Set(idx, 1)
+		}
+	}
+}
+
+// lineConfigOptions returns a copy of the configuration of the request that
+// can be modified by options without altering the request.
+//
+// Assumes r is locked.
+func (r *lineRequest) lineConfigOptions() lineConfigOptions {
+	lco := lineConfigOptions{
+		offsets: r.offsets,
+		values:  make(map[int]int, len(r.offsets)),
+		defCfg:  r.defCfg,
+	}
+	for idx, offset := range r.offsets {
+		lco.values[offset] = r.values.Get(idx)
+	}
+	if r.lineCfg != nil {
+		lco.lineCfg = make(map[int]*LineConfig, len(r.lineCfg))
+		for offset, lc := range r.lineCfg {
+			tlc := *lc
+			lco.lineCfg[offset] = &tlc
+		}
+	}
+	return lco
+}
+
+// baseLine is a handle on some or all of the lines of a request.
+type baseLine struct {
+	*lineRequest
+
+	// the offsets of the lines covered by the handle.
+	offsets []int
+
+	// the index of each of the offsets within the request.
+	//
+	// nil if the handle covers the whole request.
+	idx []int
+
+	// those that follow are covered by the request mu.
+
+	// the handle is a view that has been closed
+	viewClosed bool
+	info       []*LineInfo
+}
+
+// isView returns true if the handle is a view on a request owned by another
+// handle.
+func (l *baseLine) isView() bool {
+	return l.idx != nil
+}
+
+// isClosed returns true if the handle, or the request it is a view of, has
+// been closed.
+//
+// Assumes l is locked.
+func (l *baseLine) isClosed() bool {
+	return l.closed || l.viewClosed
+}
+
+// bit returns the position of the line at index i in the handle within the
+// request.
+func (l *baseLine) bit(i int) int {
+	if l.idx == nil {
+		return i
+	}
+	return l.idx[i]
+}
+
+// view creates a handle on a subset of the lines covered by this handle.
+//
+// Assumes l is locked.
+func (l *baseLine) view(offsets []int) (baseLine, error) {
+	if len(offsets) == 0 {
+		return baseLine{}, unix.EINVAL
+	}
+	v := baseLine{
+		lineRequest: l.lineRequest,
+		offsets:     append([]int(nil), offsets...),
+		idx:         make([]int, len(offsets)),
+	}
+	for i, o := range offsets {
+		found := false
+		for j, lo := range l.offsets {
+			if o == lo {
+				v.idx[i] = l.bit(j)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return baseLine{}, ErrInvalidOffset
+		}
+		for _, vo := range offsets[:i] {
+			if o == vo {
+				return baseLine{}, ErrInvalidOffset
+			}
+		}
+	}
+	return v, nil
+}
+
+// index returns the index of the offset within the handle.
+func (l *baseLine) index(offset int) (int, bool) {
+	for i, o := range l.offsets {
+		if o == offset {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// requestBits maps a bitmap of lines in the handle to the equivalent bitmap
+// of lines in the request.
+//
+// Bits beyond the lines in the handle are ignored.
+func (l *baseLine) requestBits(hb uint64) uapi.LineBitmap {
+	if !l.isView() {
+		return uapi.LineBitmap(hb) & uapi.NewLineBitMask(len(l.offsets))
+	}
+	var rb uapi.LineBitmap
+	for i, idx := range l.idx {
+		if hb&(1<<uint(i)) != 0 {
+			rb |= uapi.LineBitmap(1) << uint(idx)
+		}
+	}
+	return rb
+}
+
+// handleBits maps a bitmap of lines in the request to the equivalent bitmap
+// of lines in the handle.
+func (l *baseLine) handleBits(rb uapi.LineBitmap) uint64 {
+	if !l.isView() {
+		return uint64(rb & uapi.NewLineBitMask(len(l.offsets)))
+	}
+	var hb uint64
+	for i, idx := range l.idx {
+		if rb&(uapi.LineBitmap(1)<<uint(idx)) != 0 {
+			hb |= 1 << uint(i)
+		}
+	}
+	return hb
+}
+
+// getValues returns the current values of the lines in the handle identified
+// by mask.
+//
+// Assumes l is locked and open.
+func (l *baseLine) getValues(mask uint64) (uint64, error) {
+	if l.abi == 1 {
+		hd := uapi.HandleData{}
+		err := uapi.GetLineValues(l.vfd, &hd)
+		if err != nil {
+			return 0, err
+		}
+		var rb uapi.LineBitmap
+		for idx := range l.lineRequest.offsets {
+			rb = rb.Set(idx, int(hd[idx]))
+		}
+		return l.handleBits(rb) & mask, nil
+	}
+	lv := uapi.LineValues{Mask: l.requestBits(mask)}
+	err := uapi.GetLineValuesV2(l.vfd, &lv)
+	if err != nil {
+		return 0, err
+	}
+	return l.handleBits(lv.Bits&lv.Mask) & mask, nil
+}
+
+// setValues sets the values of the lines in the handle identified by mask.
+//
+// Only the lines identified by the mask need be outputs.
+//
+// Assumes l is locked and open.
+func (l *baseLine) setValues(mask, bits uint64) error {
+	rm := l.requestBits(mask)
+	if rm&^l.outputs != 0 {
+		return ErrPermissionDenied
+	}
+	rb := l.requestBits(bits & mask)
+	var err error
+	if l.abi == 1 {
+		// uAPI v1 has no mask, so the other lines are set to their cached
+		// values.
+		values := l.values&^rm | rb
+		hd := uapi.HandleData{}
+		for idx := range l.lineRequest.offsets {
+			hd[idx] = uint8(values.Get(idx))
+		}
+		err = uapi.SetLineValues(l.vfd, hd)
+	} else {
+		err = uapi.SetLineValuesV2(l.vfd, uapi.LineValues{Mask: rm, Bits: rb})
+	}
+	if err == nil {
+		l.values = l.values&^rm | rb
+	}
+	return err
 }
 
 // UapiAbiVersion returns the version of the GPIO uAPI the line is using.
@@ -783,22 +1015,40 @@ func (l *baseLine) Chip() string {
 
 // Close releases all resources held by the requested line.
 //
+// Closing a view only invalidates the view - the lines remain requested until
+// the handle that requested them is closed.  Closing that handle invalidates
+// all views of the request.
+//
 // Note that this includes waiting for any running event handler to return.
 // As a consequence the Close must not be called from the context of the event
 // handler - the Close should be called from a different goroutine.
 func (l *baseLine) Close() error {
 	l.mu.Lock()
-	defer l.mu.Unlock()
-	if l.closed {
+	if l.isClosed() {
+		l.mu.Unlock()
 		return ErrClosed
 	}
+	if l.isView() {
+		l.viewClosed = true
+		l.mu.Unlock()
+		return nil
+	}
 	l.closed = true
+	close(l.done)
 	if l.watcher != nil {
 		l.watcher.Close()
 	}
 	if !l.isEvent { // isEvent => v1 => closed by watcher
 		unix.Close(int(l.vfd))
 	}
+	l.mu.Unlock()
+	// waits for any scheduled change to notice the close
+	l.delayMu.Lock()
+	if l.delay != nil {
+		l.delay.Close()
+		l.delay = nil
+	}
+	l.delayMu.Unlock()
 	return nil
 }
 
@@ -806,6 +1056,10 @@ func (l *baseLine) Close() error {
 //
 // Configuration for options other than those passed in remain unchanged.
 //
+// For a view the options are only applied to the lines in the view, so they
+// must also be SubsetLineConfigOptions.  Views cannot be reconfigured using
+// uAPI v1.
+//
 // Not valid for lines with edge detection enabled.
 //
 // Requires Linux v5.5 or later.
@@ -818,19 +1072,25 @@ func (l *baseLine) Reconfigure(options ...LineConfigOption) error {
 	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.closed {
+	if l.isClosed() {
 		return ErrClosed
 	}
-	lro := lineReqOptions{
-		lineConfigOptions: lineConfigOptions{
-			offsets: l.offsets,
-			values:  l.values,
-			defCfg:  l.defCfg,
-			lineCfg: l.lineCfg,
-		},
-	}
-	for _, option := range options {
-		option.applyLineConfigOption(&lro.lineConfigOptions)
+	lro := lineReqOptions{lineConfigOptions: l.lineConfigOptions()}
+	if l.isView() {
+		if l.abi == 1 {
+			return ErrUapiIncompatibility{"view reconfiguration", 1}
+		}
+		for _, option := range options {
+			so, ok := option.(SubsetLineConfigOption)
+			if !ok {
+				return unix.EINVAL
+			}
+			so.applySubsetLineConfigOption(l.offsets, &lro.lineConfigOptions)
+		}
+	} else {
+		for _, option := range options {
+			option.applyLineConfigOption(&lro.lineConfigOptions)
+		}
 	}
 	if l.abi == 1 {
 		err := lro.defCfg.v1Validate()
@@ -843,7 +1103,7 @@ func (l *baseLine) Reconfigure(options ...LineConfigOption) error {
 		}
 		err = uapi.SetLineConfig(l.vfd, &hc)
 		if err == nil {
-			l.defCfg = lro.defCfg
+			l.setConfig(lro.lineConfigOptions)
 		}
 		return err
 	}
@@ -853,8 +1113,7 @@ func (l *baseLine) Reconfigure(options ...LineConfigOption) error {
 	}
 	err = uapi.SetLineConfigV2(l.vfd, &config)
 	if err == nil {
-		l.defCfg = lro.defCfg
-		l.lineCfg = lro.lineCfg
+		l.setConfig(lro.lineConfigOptions)
 	}
 	return err
 }
@@ -873,7 +1132,7 @@ func (l *Line) Offset() int {
 func (l *Line) Info() (info LineInfo, err error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.closed {
+	if l.isClosed() {
 		err = ErrClosed
 		return
 	}
@@ -899,17 +1158,11 @@ func (l *Line) Info() (info LineInfo, err error) {
 func (l *Line) Value() (int, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.closed {
+	if l.isClosed() {
 		return 0, ErrClosed
 	}
-	if l.abi == 1 {
-		hd := uapi.HandleData{}
-		err := uapi.GetLineValues(l.vfd, &hd)
-		return int(hd[0]), err
-	}
-	lv := uapi.LineValues{Mask: 1}
-	err := uapi.GetLineValuesV2(l.vfd, &lv)
-	return lv.Get(0), err
+	bits, err := l.getValues(1)
+	return int(bits), err
 }
 
 // SetValue sets the current active state of the line.
@@ -918,30 +1171,31 @@ func (l *Line) Value() (int, error) {
 func (l *Line) SetValue(value int) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.defCfg.Direction != LineDirectionOutput {
-		return ErrPermissionDenied
-	}
-	if l.closed {
+	if l.isClosed() {
 		return ErrClosed
 	}
-	if l.abi == 1 {
-		hd := uapi.HandleData{}
-		hd[0] = uint8(value)
-		err := uapi.SetLineValues(l.vfd, hd)
-		if err == nil {
-			l.values[l.offsets[0]] = value
-		}
-		return err
-	}
-	lsv := uapi.LineValues{
-		Mask: 1,
-		Bits: uapi.NewLineBitmap(value),
-	}
-	err := uapi.SetLineValuesV2(l.vfd, lsv)
-	if err == nil {
-		l.values[l.offsets[0]] = value
-	}
-	return err
+	return l.setValues(1, uint64(uapi.LineBitmap(0).Set(0, value)))
+}
+
+// SetValueAt sets the active state of the line at the deadline.
+//
+// The deadline is on CLOCK_MONOTONIC, as returned by timing.Now, which is also
+// the clock of LineEvent timestamps unless the line is configured otherwise,
+// so the change may be scheduled relative to an edge.
+//
+// Blocks until the value has been set, and returns how late the value was set
+// relative to the deadline.  If the deadline has passed then the value is set
+// immediately.
+//
+// The first scheduled change in the process calibrates the timing, which
+// takes several milliseconds, so timing.DefaultCalibration should be called
+// beforehand if the first change is time critical.
+//
+// If the request is closed while waiting then ErrClosed is returned.
+//
+// Only valid for output lines.
+func (l *Line) SetValueAt(deadline time.Duration, value int) (time.Duration, error) {
+	return l.setValuesAt(deadline, 1, uint64(uapi.LineBitmap(0).Set(0, value)))
 }
 
 // Lines represents a collection of requested lines.
@@ -954,11 +1208,48 @@ func (l *Lines) Offsets() []int {
 	return l.offsets
 }
 
+// Line returns a view of one of the lines.
+//
+// The view shares the request with the Lines, and so is only valid while the
+// Lines are open.
+func (l *Lines) Line(offset int) (*Line, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return nil, ErrClosed
+	}
+	v, err := l.view([]int{offset})
+	if err != nil {
+		return nil, err
+	}
+	return &Line{v}, nil
+}
+
+// Subset returns a view of a subset of the lines.
+//
+// The offsets must be contained in the Lines, and the order of the offsets
+// determines the order of values in the view.
+//
+// The view shares the request with the Lines, and so is only valid while the
+// Lines are open.
+func (l *Lines) Subset(offsets []int) (*Lines, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return nil, ErrClosed
+	}
+	v, err := l.view(offsets)
+	if err != nil {
+		return nil, err
+	}
+	return &Lines{v}, nil
+}
+
 // Info returns the information about the lines.
 func (l *Lines) Info() ([]*LineInfo, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.closed {
+	if l.isClosed() {
 		return nil, ErrClosed
 	}
 	if l.info != nil {
@@ -988,35 +1279,66 @@ func (l *Lines) Info() ([]*LineInfo, error) {
 func (l *Lines) Values(values []int) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.closed {
+	if l.isClosed() {
 		return ErrClosed
 	}
 	lines := len(values)
 	if lines > len(l.offsets) {
 		lines = len(l.offsets)
 	}
-	if l.abi == 1 {
-		hd := uapi.HandleData{}
-		err := uapi.GetLineValues(l.vfd, &hd)
-		if err != nil {
-			return err
-		}
-		for i := 0; i < lines; i++ {
-			values[i] = int(hd[i])
-		}
-		return nil
-	}
-	lv := uapi.LineValues{Mask: uapi.NewLineBitMask(lines)}
-	err := uapi.GetLineValuesV2(l.vfd, &lv)
+	bits, err := l.getValues(uint64(uapi.NewLineBitMask(lines)))
 	if err != nil {
 		return err
 	}
 	for i := 0; i < lines; i++ {
-		values[i] = lv.Get(i)
+		values[i] = int((bits >> uint(i)) & 1)
 	}
 	return nil
 }
 
+// ValuesMask returns the current values (active state) of the lines identified
+// by mask.
+//
+// The mask and the returned values are bitmaps of the lines, where bit n
+// corresponds to the nth line in Offsets.  Bits not in the mask are zero.
+func (l *Lines) ValuesMask(mask uint64) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return 0, ErrClosed
+	}
+	return l.getValues(mask & uint64(uapi.NewLineBitMask(len(l.offsets))))
+}
+
+// ValuesOf returns the current values (active state) of a subset of the lines.
+//
+// The values are keyed by offset.  The offsets must be contained in the Lines.
+func (l *Lines) ValuesOf(offsets []int) (map[int]int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return nil, ErrClosed
+	}
+	var mask uint64
+	for _, o := range offsets {
+		i, ok := l.index(o)
+		if !ok {
+			return nil, ErrInvalidOffset
+		}
+		mask |= 1 << uint(i)
+	}
+	bits, err := l.getValues(mask)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[int]int, len(offsets))
+	for _, o := range offsets {
+		i, _ := l.index(o)
+		values[o] = int((bits >> uint(i)) & 1)
+	}
+	return values, nil
+}
+
 // SetValues sets the current active state of the collection of lines.
 //
 // Only valid for output lines.
@@ -1024,43 +1346,125 @@ func (l *Lines) Values(values []int) error {
 // All lines in the set are set at once.  If insufficient values are provided
 // then the remaining lines are set to inactive. If too many values are provided
 // then the surplus values are ignored.
+//
+// For a view, only the lines in the view are set.
 func (l *Lines) SetValues(values []int) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.defCfg.Direction != LineDirectionOutput {
-		return ErrPermissionDenied
-	}
-	if l.closed {
+	if l.isClosed() {
 		return ErrClosed
 	}
 	if len(values) > len(l.offsets) {
 		values = values[:len(l.offsets)]
 	}
-	if l.abi == 1 {
-		hd := uapi.HandleData{}
-		for i, v := range values {
-			hd[i] = uint8(v)
+	bits := uint64(uapi.NewLineBitmap(values...))
+	return l.setValues(uint64(uapi.NewLineBitMask(len(l.offsets))), bits)
+}
+
+// SetValuesOf sets the current active state of a subset of the lines.
+//
+// The values are keyed by offset, and the offsets must be contained in the
+// Lines.  Only the lines in values are set, and only those lines need be
+// outputs.  The remaining lines are unaltered.
+func (l *Lines) SetValuesOf(values map[int]int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return ErrClosed
+	}
+	var mask, bits uint64
+	for o, v := range values {
+		i, ok := l.index(o)
+		if !ok {
+			return ErrInvalidOffset
 		}
-		err := uapi.SetLineValues(l.vfd, hd)
-		if err == nil {
-			for i, v := range values {
-				l.values[l.offsets[i]] = v
-			}
+		mask |= 1 << uint(i)
+		if v != 0 {
+			bits |= 1 << uint(i)
 		}
-		return err
 	}
-	lv := uapi.LineValues{
-		Mask: uapi.NewLineBitMask(len(l.offsets)),
-		Bits: uapi.NewLineBitmap(values...),
+	if mask == 0 {
+		return nil
 	}
-	err := uapi.SetLineValuesV2(l.vfd, lv)
-	if err == nil {
-		for i, v := range values {
-			l.values[l.offsets[i]] = v
-		}
+	return l.setValues(mask, bits)
+}
+
+// SetValuesMask sets the current active state of the lines identified by mask.
+//
+// The mask and bits are bitmaps of the lines, where bit n corresponds to the
+// nth line in Offsets.  Only the lines in the mask are set, to the value of the
+// corresponding bit in bits, and only those lines need be outputs.  The
+// remaining lines are unaltered.
+func (l *Lines) SetValuesMask(mask, bits uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return ErrClosed
 	}
+	return l.setValues(mask, bits)
+}
 
-	return err
+// SetValuesAt sets the active state of the collection of lines at the
+// deadline.
+//
+// The values are applied as per SetValues.
+//
+// The deadline is on CLOCK_MONOTONIC, as returned by timing.Now, which is also
+// the clock of LineEvent timestamps unless the lines are configured otherwise,
+// so the change may be scheduled relative to an edge.
+//
+// Blocks until the values have been set, and returns how late the values were
+// set relative to the deadline.  If the deadline has passed then the values are
+// set immediately.
+//
+// The first scheduled change in the process calibrates the timing, which
+// takes several milliseconds, so timing.DefaultCalibration should be called
+// beforehand if the first change is time critical.
+//
+// If the request is closed while waiting then ErrClosed is returned.
+func (l *Lines) SetValuesAt(deadline time.Duration, values []int) (time.Duration, error) {
+	if len(values) > len(l.offsets) {
+		values = values[:len(l.offsets)]
+	}
+	bits := uint64(uapi.NewLineBitmap(values...))
+	return l.setValuesAt(deadline, uint64(uapi.NewLineBitMask(len(l.offsets))), bits)
+}
+
+// setValuesAt waits until the deadline then sets the values of the lines
+// identified by mask.
+//
+// The delay is shared by all handles on the request, so concurrent scheduled
+// changes are serialised.
+func (l *baseLine) setValuesAt(deadline time.Duration, mask, bits uint64) (time.Duration, error) {
+	l.delayMu.Lock()
+	defer l.delayMu.Unlock()
+	if l.delay == nil {
+		l.mu.Lock()
+		closed := l.isClosed()
+		l.mu.Unlock()
+		if closed {
+			return 0, ErrClosed
+		}
+		d, err := timing.NewDelay()
+		if err != nil {
+			return 0, err
+		}
+		l.delay = d
+	}
+	if !l.delay.UntilOrDone(deadline, l.done) {
+		return 0, ErrClosed
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return 0, ErrClosed
+	}
+	err := l.setValues(mask, bits)
+	late := timing.Now() - deadline
+	if late < 0 {
+		late = 0
+	}
+	return late, err
 }
 
 // LineEventType indicates the type of change to the line active state.
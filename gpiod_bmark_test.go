@@ -13,6 +13,7 @@ import (
 )
 
 func BenchmarkChipNewClose(b *testing.B) {
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		c, _ := gpiod.NewChip(platform.Devpath())
 		c.Close()
@@ -24,6 +25,7 @@ func BenchmarkLineInfo(b *testing.B) {
 	require.Nil(b, err)
 	require.NotNil(b, c)
 	defer c.Close()
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		c.LineInfo(platform.IntrLine())
 	}
@@ -38,6 +40,7 @@ func BenchmarkLineReconfigure(b *testing.B) {
 	require.Nil(b, err)
 	require.NotNil(b, l)
 	defer l.Close()
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		l.Reconfigure(gpiod.AsActiveLow)
 	}
@@ -52,6 +55,7 @@ func BenchmarkLineValue(b *testing.B) {
 	require.Nil(b, err)
 	require.NotNil(b, l)
 	defer l.Close()
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		l.Value()
 	}
@@ -67,10 +71,27 @@ func BenchmarkLinesValues(b *testing.B) {
 	require.NotNil(b, l)
 	defer l.Close()
 	vv := make([]int, c.Lines())
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		l.Values(vv)
 	}
 }
+
+func BenchmarkLinesValuesMask(b *testing.B) {
+	c, err := gpiod.NewChip(platform.Devpath())
+	require.Nil(b, err)
+	require.NotNil(b, c)
+	defer c.Close()
+	l, err := c.RequestLines(platform.FloatingLines())
+	require.Nil(b, err)
+	require.NotNil(b, l)
+	defer l.Close()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.ValuesMask(0x03)
+	}
+}
+
 func BenchmarkLineSetValue(b *testing.B) {
 	c, err := gpiod.NewChip(platform.Devpath())
 	require.Nil(b, err)
@@ -80,6 +101,7 @@ func BenchmarkLineSetValue(b *testing.B) {
 	require.Nil(b, err)
 	require.NotNil(b, l)
 	defer l.Close()
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		l.SetValue(1)
 	}
@@ -95,12 +117,45 @@ func BenchmarkLinesSetValues(b *testing.B) {
 	require.NotNil(b, ll)
 	defer ll.Close()
 	vv := []int{0, 0}
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		vv[0] = i & 1
 		ll.SetValues(vv)
 	}
 }
 
+func BenchmarkLinesSetValuesMask(b *testing.B) {
+	c, err := gpiod.NewChip(platform.Devpath())
+	require.Nil(b, err)
+	require.NotNil(b, c)
+	defer c.Close()
+	ll, err := c.RequestLines(platform.FloatingLines(), gpiod.AsOutput(0))
+	require.Nil(b, err)
+	require.NotNil(b, ll)
+	defer ll.Close()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ll.SetValuesMask(0x01, uint64(i&1))
+	}
+}
+
+func BenchmarkLineViewSetValue(b *testing.B) {
+	c, err := gpiod.NewChip(platform.Devpath())
+	require.Nil(b, err)
+	require.NotNil(b, c)
+	defer c.Close()
+	ll, err := c.RequestLines(platform.FloatingLines(), gpiod.AsOutput(0))
+	require.Nil(b, err)
+	require.NotNil(b, ll)
+	defer ll.Close()
+	l, err := ll.Line(platform.FloatingLines()[1])
+	require.Nil(b, err)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.SetValue(i & 1)
+	}
+}
+
 func BenchmarkInterruptLatency(b *testing.B) {
 	c, err := gpiod.NewChip(platform.Devpath())
 	require.Nil(b, err)
@@ -121,6 +176,7 @@ func BenchmarkInterruptLatency(b *testing.B) {
 	case <-ich:
 	case <-time.After(time.Millisecond):
 	}
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		platform.TriggerIntr(i & 1)
 		<-ich
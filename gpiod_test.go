@@ -17,6 +17,7 @@ import (
 	"github.com/warthog618/gpiod"
 	"github.com/warthog618/gpiod/device/rpi"
 	"github.com/warthog618/gpiod/mockup"
+	"github.com/warthog618/gpiod/timing"
 	"github.com/warthog618/gpiod/uapi"
 	"golang.org/x/sys/unix"
 )
@@ -244,6 +245,42 @@ func TestChipLines(t *testing.T) {
 	assert.Equal(t, platform.Lines(), lines)
 }
 
+func TestChipFeatures(t *testing.T) {
+	c := getChip(t)
+	f, err := c.Features()
+	assert.Nil(t, err)
+	xabi := 1
+	if mockup.CheckKernelVersion(uapiV2Kernel) == nil {
+		xabi = 2
+	}
+	assert.Equal(t, xabi, f.AbiVersion)
+	assert.GreaterOrEqual(t, f.AbiVersion, c.UapiAbiVersion())
+	assert.Equal(t, mockup.CheckKernelVersion(biasKernel) == nil, f.Bias)
+	assert.Equal(t, mockup.CheckKernelVersion(setConfigKernel) == nil, f.Reconfigure)
+	assert.Equal(t, mockup.CheckKernelVersion(infoWatchKernel) == nil, f.InfoWatch)
+	assert.True(t, f.EdgeDetection)
+	assert.Equal(t, xabi == 2, f.Debounce)
+	assert.Equal(t, mockup.CheckKernelVersion(eventClockRealtimeKernel) == nil, f.RealtimeEventClock)
+
+	// probing leaves lines unrequested
+	for o := 0; o < c.Lines(); o++ {
+		li, err := c.LineInfo(o)
+		assert.Nil(t, err)
+		assert.False(t, li.Used)
+	}
+
+	// cached
+	f2, err := c.Features()
+	assert.Nil(t, err)
+	assert.Equal(t, f, f2)
+
+	// closed
+	c.Close()
+	f, err = c.Features()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Equal(t, gpiod.ChipFeatures{}, f)
+}
+
 func TestChipRequestLine(t *testing.T) {
 	c := getChip(t)
 	defer c.Close()
@@ -727,6 +764,56 @@ func TestLineSetValue(t *testing.T) {
 	assert.Equal(t, gpiod.ErrClosed, err)
 }
 
+func TestLineSetValueAt(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	_, err := timing.DefaultCalibration()
+	require.Nil(t, err)
+	l, err := c.RequestLine(platform.FloatingLines()[0], gpiod.AsOutput(0))
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	// once calibrated, the first change is not delayed by setting up the delay
+	deadline := timing.Now() + 2*time.Millisecond
+	late, err := l.SetValueAt(deadline, 1)
+	assert.Nil(t, err)
+	now := timing.Now()
+	assert.GreaterOrEqual(t, now, deadline)
+	assert.GreaterOrEqual(t, late, time.Duration(0))
+	assert.LessOrEqual(t, late, now-deadline)
+	assert.Less(t, late, time.Millisecond)
+	v, err := l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	// in the past
+	late, err = l.SetValueAt(timing.Now()-time.Millisecond, 0)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, late, time.Millisecond)
+	v, err = l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+
+	// closed while waiting
+	errCh := make(chan error)
+	go func() {
+		_, err := l.SetValueAt(timing.Now()+time.Hour, 1)
+		errCh <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	start := timing.Now()
+	l.Close()
+	select {
+	case err = <-errCh:
+		assert.Equal(t, gpiod.ErrClosed, err)
+	case <-time.After(time.Second):
+		require.Fail(t, "scheduled change not aborted by close")
+	}
+	assert.Less(t, timing.Now()-start, 100*time.Millisecond)
+	_, err = l.SetValueAt(timing.Now(), 1)
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
 func TestLinesChip(t *testing.T) {
 	c := getChip(t)
 	defer c.Close()
@@ -873,6 +960,426 @@ func TestLinesSetValues(t *testing.T) {
 	assert.Equal(t, gpiod.ErrClosed, err)
 }
 
+func TestLinesValuesOf(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	platform.TriggerIntr(1)
+	lines := append([]int{platform.IntrLine()}, platform.FloatingLines()...)
+	l, err := c.RequestLines(lines)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+
+	vv, err := l.ValuesOf([]int{platform.IntrLine()})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]int{platform.IntrLine(): 1}, vv)
+	platform.TriggerIntr(0)
+	vv, err = l.ValuesOf([]int{lines[1], platform.IntrLine()})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, vv[platform.IntrLine()])
+	assert.Equal(t, 2, len(vv))
+
+	// not in set
+	vv, err = l.ValuesOf([]int{platform.IntrLine(), platform.Lines()})
+	assert.Equal(t, gpiod.ErrInvalidOffset, err)
+	assert.Nil(t, vv)
+
+	l.Close()
+
+	// after close
+	vv, err = l.ValuesOf([]int{platform.IntrLine()})
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Nil(t, vv)
+}
+
+func TestLinesValuesMask(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	platform.TriggerIntr(1)
+	lines := append([]int{platform.IntrLine()}, platform.FloatingLines()...)
+	l, err := c.RequestLines(lines)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+
+	bits, err := l.ValuesMask(0x01)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0x01), bits)
+	platform.TriggerIntr(0)
+	bits, err = l.ValuesMask(0x01)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), bits)
+
+	// bits beyond the lines are ignored
+	platform.TriggerIntr(1)
+	bits, err = l.ValuesMask(^uint64(0x3e))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0x01), bits)
+
+	l.Close()
+
+	// after close
+	bits, err = l.ValuesMask(0x01)
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Zero(t, bits)
+}
+
+func TestLinesSetValuesOf(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	lines := platform.FloatingLines()
+
+	// input
+	l, err := c.RequestLines(lines)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	err = l.SetValuesOf(map[int]int{lines[0]: 1})
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+	l.Close()
+
+	// output
+	l, err = c.RequestLines(lines, gpiod.AsOutput(1, 1, 1, 1, 1))
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	err = l.SetValuesOf(map[int]int{lines[1]: 0, lines[3]: 0})
+	assert.Nil(t, err)
+	vv := make([]int, len(lines))
+	err = l.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0, 1, 0, 1}, vv)
+	err = l.SetValuesOf(map[int]int{lines[3]: 1})
+	assert.Nil(t, err)
+	err = l.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0, 1, 1, 1}, vv)
+
+	// empty
+	err = l.SetValuesOf(nil)
+	assert.Nil(t, err)
+
+	// not in set
+	err = l.SetValuesOf(map[int]int{lines[0]: 0, platform.IntrLine(): 1})
+	assert.Equal(t, gpiod.ErrInvalidOffset, err)
+
+	// closed
+	l.Close()
+	err = l.SetValuesOf(map[int]int{lines[0]: 0})
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestLinesSetValuesMask(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	lines := platform.FloatingLines()
+	l, err := c.RequestLines(lines, gpiod.AsOutput(0, 0, 0, 0, 0))
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	err = l.SetValuesMask(0x0a, 0xff)
+	assert.Nil(t, err)
+	vv := make([]int, len(lines))
+	err = l.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1, 0, 1, 0}, vv)
+	err = l.SetValuesMask(0x03, 0x01)
+	assert.Nil(t, err)
+	err = l.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0, 0, 1, 0}, vv)
+
+	// bits beyond the lines are ignored
+	err = l.SetValuesMask(0xf0, 0xf0)
+	assert.Nil(t, err)
+	err = l.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0, 0, 1, 1}, vv)
+
+	// closed
+	l.Close()
+	err = l.SetValuesMask(1, 1)
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestLinesSetValuesMaskMixed(t *testing.T) {
+	requireKernel(t, uapiV2Kernel)
+	c := getChip(t)
+	defer c.Close()
+	requireABI(t, c, 2)
+
+	// outputs only need be outputs if they are masked
+	lines := platform.FloatingLines()
+	l, err := c.RequestLines(lines,
+		gpiod.AsInput,
+		gpiod.WithLines(lines[:2], gpiod.AsOutput(0, 0)))
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	err = l.SetValuesMask(0x03, 0x02)
+	assert.Nil(t, err)
+	vv, err := l.ValuesOf(lines[:2])
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]int{lines[0]: 0, lines[1]: 1}, vv)
+	err = l.SetValuesMask(0x04, 0x04)
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+	err = l.SetValuesOf(map[int]int{lines[0]: 1})
+	assert.Nil(t, err)
+	err = l.SetValues([]int{1, 1})
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+}
+
+func TestLinesSetValuesAt(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	_, err := timing.DefaultCalibration()
+	require.Nil(t, err)
+	lines := platform.FloatingLines()
+	l, err := c.RequestLines(lines, gpiod.AsOutput(0, 0, 0, 0, 0))
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	deadline := timing.Now() + 2*time.Millisecond
+	late, err := l.SetValuesAt(deadline, []int{1, 0, 1})
+	assert.Nil(t, err)
+	now := timing.Now()
+	assert.GreaterOrEqual(t, now, deadline)
+	assert.LessOrEqual(t, late, now-deadline)
+	assert.Less(t, late, time.Millisecond)
+	vv := make([]int, len(lines))
+	err = l.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0, 1, 0, 0}, vv)
+
+	l.Close()
+	_, err = l.SetValuesAt(timing.Now(), vv)
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestValueAllocs(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	lines := platform.FloatingLines()
+	ll, err := c.RequestLines(lines, gpiod.AsOutput(0, 0, 0, 0, 0))
+	assert.Nil(t, err)
+	require.NotNil(t, ll)
+	defer ll.Close()
+	l, err := ll.Line(lines[1])
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	vv := make([]int, len(lines))
+	patterns := []struct {
+		name string
+		f    func()
+	}{
+		{"line value", func() { l.Value() }},
+		{"line set value", func() { l.SetValue(1) }},
+		{"values", func() { ll.Values(vv) }},
+		{"set values", func() { ll.SetValues(vv) }},
+		{"values mask", func() { ll.ValuesMask(0x03) }},
+		{"set values mask", func() { ll.SetValuesMask(0x03, 0x01) }},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			assert.Zero(t, testing.AllocsPerRun(100, p.f))
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestLinesLine(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	// input
+	platform.TriggerIntr(0)
+	lines := append([]int{platform.IntrLine()}, platform.FloatingLines()...)
+	ll, err := c.RequestLines(lines)
+	assert.Nil(t, err)
+	require.NotNil(t, ll)
+
+	// not in set
+	l, err := ll.Line(platform.OutLine())
+	assert.Equal(t, gpiod.ErrInvalidOffset, err)
+	assert.Nil(t, l)
+
+	l, err = ll.Line(platform.IntrLine())
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	assert.Equal(t, platform.IntrLine(), l.Offset())
+	assert.Equal(t, ll.Chip(), l.Chip())
+	v, err := l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+	platform.TriggerIntr(1)
+	v, err = l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	err = l.SetValue(0)
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+
+	// closing the view leaves the parent
+	err = l.Close()
+	assert.Nil(t, err)
+	_, err = l.Value()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	err = l.Close()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	vv := make([]int, len(lines))
+	err = ll.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, vv[0])
+
+	// closing the parent invalidates the view
+	l, err = ll.Line(platform.IntrLine())
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	ll.Close()
+	_, err = l.Value()
+	assert.Equal(t, gpiod.ErrClosed, err)
+	l, err = ll.Line(platform.IntrLine())
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Nil(t, l)
+
+	// output
+	lines = platform.FloatingLines()
+	ll, err = c.RequestLines(lines, gpiod.AsOutput(1, 1, 1, 1, 1))
+	assert.Nil(t, err)
+	require.NotNil(t, ll)
+	l, err = ll.Line(lines[1])
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	err = l.SetValue(0)
+	assert.Nil(t, err)
+	v, err = l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+	vv = make([]int, len(lines))
+	err = ll.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0, 1, 1, 1}, vv)
+	ll.Close()
+}
+
+func TestLinesSubset(t *testing.T) {
+	c := getChip(t)
+	defer c.Close()
+
+	lines := platform.FloatingLines()
+	ll, err := c.RequestLines(lines, gpiod.AsOutput(1, 1, 1, 1, 1))
+	assert.Nil(t, err)
+	require.NotNil(t, ll)
+
+	// empty
+	s, err := ll.Subset(nil)
+	assert.Equal(t, unix.EINVAL, err)
+	assert.Nil(t, s)
+
+	// not in set
+	s, err = ll.Subset([]int{lines[0], platform.IntrLine()})
+	assert.Equal(t, gpiod.ErrInvalidOffset, err)
+	assert.Nil(t, s)
+
+	// duplicate
+	s, err = ll.Subset([]int{lines[0], lines[0]})
+	assert.Equal(t, gpiod.ErrInvalidOffset, err)
+	assert.Nil(t, s)
+
+	// reordered
+	offsets := []int{lines[3], lines[1]}
+	s, err = ll.Subset(offsets)
+	assert.Nil(t, err)
+	require.NotNil(t, s)
+	assert.Equal(t, offsets, s.Offsets())
+
+	// only the subset is set
+	err = s.SetValues([]int{0})
+	assert.Nil(t, err)
+	vv := make([]int, len(lines))
+	err = ll.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0, 1, 0, 1}, vv)
+	err = s.SetValues([]int{1, 0})
+	assert.Nil(t, err)
+	err = ll.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0, 1, 1, 1}, vv)
+	vv = vv[:2]
+	err = s.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 0}, vv)
+
+	// subset of subset
+	ss, err := s.Subset([]int{lines[1]})
+	assert.Nil(t, err)
+	require.NotNil(t, ss)
+	err = ss.SetValues([]int{1})
+	assert.Nil(t, err)
+	vv = make([]int, len(lines))
+	err = ll.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 1, 1, 1, 1}, vv)
+	ss, err = s.Subset([]int{lines[0]})
+	assert.Equal(t, gpiod.ErrInvalidOffset, err)
+	assert.Nil(t, ss)
+
+	// closing the parent invalidates the view
+	ll.Close()
+	err = s.SetValues([]int{1})
+	assert.Equal(t, gpiod.ErrClosed, err)
+	err = s.Close()
+	assert.Equal(t, gpiod.ErrClosed, err)
+}
+
+func TestLinesSubsetReconfigure(t *testing.T) {
+	requireKernel(t, uapiV2Kernel)
+	c := getChip(t, gpiod.WithConsumer("TestLinesSubsetReconfigure"))
+	defer c.Close()
+	requireABI(t, c, 2)
+
+	lines := platform.FloatingLines()
+	ll, err := c.RequestLines(lines,
+		gpiod.AsInput,
+		gpiod.WithLines(lines[1:2], gpiod.AsOutput(1)))
+	assert.Nil(t, err)
+	require.NotNil(t, ll)
+	defer ll.Close()
+
+	in, err := ll.Line(lines[0])
+	assert.Nil(t, err)
+	require.NotNil(t, in)
+	out, err := ll.Line(lines[1])
+	assert.Nil(t, err)
+	require.NotNil(t, out)
+
+	// direction is per line
+	err = in.SetValue(1)
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+	err = out.SetValue(0)
+	assert.Nil(t, err)
+
+	// reconfigure only affects the view
+	err = in.Reconfigure(gpiod.AsOutput(1))
+	assert.Nil(t, err)
+	inf, err := c.LineInfo(lines[0])
+	assert.Nil(t, err)
+	assert.Equal(t, gpiod.LineDirectionOutput, inf.Config.Direction)
+	err = out.Reconfigure(gpiod.AsInput, gpiod.WithPullUp)
+	assert.Nil(t, err)
+	inf, err = c.LineInfo(lines[1])
+	assert.Nil(t, err)
+	assert.Equal(t, gpiod.LineDirectionInput, inf.Config.Direction)
+	assert.Equal(t, gpiod.LineBiasPullUp, inf.Config.Bias)
+	inf, err = c.LineInfo(lines[0])
+	assert.Nil(t, err)
+	assert.Equal(t, gpiod.LineDirectionOutput, inf.Config.Direction)
+	assert.Equal(t, gpiod.LineBiasUnknown, inf.Config.Bias)
+	err = in.SetValue(0)
+	assert.Nil(t, err)
+	err = out.SetValue(0)
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+}
+
 func TestIsChip(t *testing.T) {
 	// nonexistent
 	err := gpiod.IsChip("/dev/nonexistent")
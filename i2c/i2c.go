@@ -0,0 +1,541 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package i2c provides a bit-bashed I2C master.
+//
+// The bus is driven by two open-drain lines, which require pull-ups, either
+// external or the internal pull-ups of the GPIO controller, which are enabled
+// using WithPullUp.
+//
+// The package is not related to the I2C device drivers provided by the Linux
+// kernel, including its i2c-gpio driver, which are the preferred solution for
+// production applications.
+package i2c
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// TenBit flags an address as a 10-bit address.
+//
+// Addresses without the flag are 7-bit addresses.
+const TenBit uint16 = 0x8000
+
+const (
+	sclMask  = 0x1
+	sdaMask  = 0x2
+	busMask  = sclMask | sdaMask
+	readFlag = 0x01
+
+	// the prefix of the first byte of a 10-bit address
+	tenBitPrefix = 0xf0
+)
+
+// Msg is a single read or write within a transaction.
+type Msg struct {
+	// The address of the device.
+	Addr uint16
+
+	// True to read from the device, else false to write to it.
+	Read bool
+
+	// The data written, or the buffer to read into.
+	Data []byte
+}
+
+// Bus is a bit-bashed I2C bus master.
+type Bus struct {
+	// time between clock edges (i.e. half the cycle time)
+	tclk time.Duration
+
+	// the maximum time a device may hold the clock low
+	stretchTimeout time.Duration
+
+	// true if the internal pull-ups should be enabled
+	pullUp bool
+
+	// mu serialises transactions
+	mu sync.Mutex
+
+	// the SCL and SDA lines, in that order
+	lines *gpiod.Lines
+
+	delay *timing.Delay
+
+	// options for the delay
+	timingOptions []timing.Option
+}
+
+// New creates a Bus on the SCL and SDA lines of the chip.
+//
+// If SDA is held low by a device the bus is recovered using Recover.
+func New(c *gpiod.Chip, scl, sda int, options ...Option) (*Bus, error) {
+	b := Bus{
+		// default to 100kHz full cycle.
+		tclk:           5 * time.Microsecond,
+		stretchTimeout: 25 * time.Millisecond,
+	}
+	for _, option := range options {
+		option(&b)
+	}
+	d, err := timing.NewDelay(b.timingOptions...)
+	if err != nil {
+		return nil, err
+	}
+	b.delay = d
+	lro := []gpiod.LineReqOption{gpiod.AsOpenDrain, gpiod.AsOutput(1, 1)}
+	if b.pullUp {
+		lro = append(lro, gpiod.WithPullUp)
+	}
+	b.lines, err = c.RequestLines([]int{scl, sda}, lro...)
+	if err != nil {
+		b.delay.Close()
+		return nil, err
+	}
+	v, err := b.lines.ValuesMask(sdaMask)
+	if err == nil && v == 0 {
+		err = b.recover()
+	}
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Close releases the lines.
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.delay.Close()
+	return b.lines.Close()
+}
+
+// String returns a description of the bus.
+func (b *Bus) String() string {
+	oo := b.lines.Offsets()
+	return fmt.Sprintf("i2c(%s scl=%d sda=%d)", b.lines.Chip(), oo[0], oo[1])
+}
+
+// Tx writes w to the device then reads r from it, with a repeated start
+// between the two.
+//
+// Either w or r may be empty, in which case that part of the transaction is
+// skipped.  If both are empty the device is addressed for writing, which
+// probes for its presence.
+//
+// This is compatible with the Tx method of common Go I2C bus interfaces.
+func (b *Bus) Tx(addr uint16, w, r []byte) error {
+	msgs := make([]Msg, 0, 2)
+	if len(w) != 0 || len(r) == 0 {
+		msgs = append(msgs, Msg{Addr: addr, Data: w})
+	}
+	if len(r) != 0 {
+		msgs = append(msgs, Msg{Addr: addr, Read: true, Data: r})
+	}
+	return b.Transfer(msgs...)
+}
+
+// Transfer performs the messages as a single transaction, with a repeated
+// start between each message.
+//
+// The final byte of each read is NACKed, as required before the following
+// start or stop.  The transaction is stopped on the first error.
+func (b *Bus) Transfer(msgs ...Msg) error {
+	for _, m := range msgs {
+		if err := checkAddr(m.Addr); err != nil {
+			return err
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.start(); err != nil {
+		return err
+	}
+	for i, m := range msgs {
+		if i != 0 {
+			if err := b.repeatedStart(); err != nil {
+				return err
+			}
+		}
+		err := b.address(m)
+		if err == nil {
+			if m.Read {
+				err = b.readBytes(m.Data)
+			} else {
+				err = b.writeBytes(m.Data)
+			}
+		}
+		if err != nil {
+			// a stuck clock prevents the stop, so return the original error.
+			b.stop()
+			return err
+		}
+	}
+	return b.stop()
+}
+
+// Recover frees a bus that has a device holding SDA low, such as after a
+// transaction was interrupted.
+//
+// SCL is pulsed up to 9 times, until the device releases SDA, and then the bus
+// is stopped.
+func (b *Bus) Recover() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.recover()
+}
+
+func (b *Bus) recover() error {
+	if err := b.lines.SetValuesMask(busMask, busMask); err != nil {
+		return err
+	}
+	for i := 0; i < 9; i++ {
+		v, err := b.lines.ValuesMask(sdaMask)
+		if err != nil {
+			return err
+		}
+		if v != 0 {
+			break
+		}
+		if err := b.sclLow(); err != nil {
+			return err
+		}
+		if err := b.sclHigh(); err != nil {
+			return err
+		}
+	}
+	// a start and stop to reset the device state machines
+	if err := b.start(); err != nil {
+		return err
+	}
+	if err := b.stop(); err != nil {
+		return err
+	}
+	v, err := b.lines.ValuesMask(sdaMask)
+	if err != nil {
+		return err
+	}
+	if v == 0 {
+		return ErrBusStuck
+	}
+	return nil
+}
+
+// address sends the address for the message.
+func (b *Bus) address(m Msg) error {
+	var rw byte
+	if m.Read {
+		rw = readFlag
+	}
+	if m.Addr&TenBit == 0 {
+		return b.writeAddrByte(byte(m.Addr<<1) | rw)
+	}
+	hdr := tenBitPrefix | byte(m.Addr>>7)&0x06
+	if err := b.writeAddrByte(hdr); err != nil {
+		return err
+	}
+	if err := b.writeAddrByte(byte(m.Addr)); err != nil {
+		return err
+	}
+	if !m.Read {
+		return nil
+	}
+	// reads are addressed for writing, then turned around with a repeated
+	// start and the header alone.
+	if err := b.repeatedStart(); err != nil {
+		return err
+	}
+	return b.writeAddrByte(hdr | readFlag)
+}
+
+func (b *Bus) writeAddrByte(v byte) error {
+	ack, err := b.writeByte(v)
+	if err != nil {
+		return err
+	}
+	if !ack {
+		return ErrAddressNack
+	}
+	return nil
+}
+
+func (b *Bus) writeBytes(data []byte) error {
+	for _, v := range data {
+		ack, err := b.writeByte(v)
+		if err != nil {
+			return err
+		}
+		if !ack {
+			return ErrDataNack
+		}
+	}
+	return nil
+}
+
+func (b *Bus) readBytes(data []byte) error {
+	for i := range data {
+		v, err := b.readByte(i != len(data)-1)
+		if err != nil {
+			return err
+		}
+		data[i] = v
+	}
+	return nil
+}
+
+// writeByte writes a byte, MSB first, and returns true if the device ACKed it.
+func (b *Bus) writeByte(v byte) (bool, error) {
+	for i := 7; i >= 0; i-- {
+		if err := b.writeBit(int(v>>uint(i)) & 1); err != nil {
+			return false, err
+		}
+	}
+	bit, err := b.readBit()
+	return bit == 0, err
+}
+
+// readByte reads a byte, MSB first, and ACKs it if ack is true.
+func (b *Bus) readByte(ack bool) (byte, error) {
+	var v byte
+	for i := 0; i < 8; i++ {
+		bit, err := b.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | byte(bit)
+	}
+	nack := 1
+	if ack {
+		nack = 0
+	}
+	return v, b.writeBit(nack)
+}
+
+// Bus conditions and bit transfers all start and end with SCL low, other than
+// start and stop, which start and end respectively with the bus idle.
+
+// start generates a start condition on an idle bus.
+func (b *Bus) start() error {
+	if err := b.sda(0); err != nil {
+		return err
+	}
+	b.delay.Wait(b.tclk)
+	return b.sclLow()
+}
+
+// repeatedStart generates a start condition within a transaction.
+func (b *Bus) repeatedStart() error {
+	if err := b.sda(1); err != nil {
+		return err
+	}
+	b.delay.Wait(b.tclk)
+	if err := b.sclHigh(); err != nil {
+		return err
+	}
+	b.delay.Wait(b.tclk)
+	return b.start()
+}
+
+// stop generates a stop condition, leaving the bus idle.
+func (b *Bus) stop() error {
+	if err := b.sda(0); err != nil {
+		return err
+	}
+	b.delay.Wait(b.tclk)
+	if err := b.sclHigh(); err != nil {
+		return err
+	}
+	b.delay.Wait(b.tclk)
+	if err := b.sda(1); err != nil {
+		return err
+	}
+	b.delay.Wait(b.tclk)
+	return nil
+}
+
+func (b *Bus) writeBit(v int) error {
+	if err := b.sda(v); err != nil {
+		return err
+	}
+	b.delay.Wait(b.tclk)
+	if err := b.sclHigh(); err != nil {
+		return err
+	}
+	b.delay.Wait(b.tclk)
+	return b.sclLow()
+}
+
+func (b *Bus) readBit() (int, error) {
+	if err := b.sda(1); err != nil {
+		return 0, err
+	}
+	b.delay.Wait(b.tclk)
+	if err := b.sclHigh(); err != nil {
+		return 0, err
+	}
+	b.delay.Wait(b.tclk)
+	v, err := b.lines.ValuesMask(sdaMask)
+	if err != nil {
+		return 0, err
+	}
+	if err := b.sclLow(); err != nil {
+		return 0, err
+	}
+	if v != 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (b *Bus) sda(v int) error {
+	var bits uint64
+	if v != 0 {
+		bits = sdaMask
+	}
+	return b.lines.SetValuesMask(sdaMask, bits)
+}
+
+func (b *Bus) sclLow() error {
+	return b.lines.SetValuesMask(sclMask, 0)
+}
+
+// sclHigh releases SCL and waits for it to go high, as devices may stretch the
+// clock by holding it low.
+func (b *Bus) sclHigh() error {
+	if err := b.lines.SetValuesMask(sclMask, sclMask); err != nil {
+		return err
+	}
+	deadline := timing.Now() + b.stretchTimeout
+	for {
+		v, err := b.lines.ValuesMask(sclMask)
+		if err != nil {
+			return err
+		}
+		if v != 0 {
+			return nil
+		}
+		if timing.Now() > deadline {
+			return ErrClockStretch
+		}
+		b.delay.Wait(b.tclk)
+	}
+}
+
+func checkAddr(addr uint16) error {
+	if addr&TenBit != 0 {
+		if addr&^TenBit > 0x3ff {
+			return ErrInvalidAddress
+		}
+		return nil
+	}
+	if addr > 0x7f {
+		return ErrInvalidAddress
+	}
+	return nil
+}
+
+// Device is a device on a Bus.
+type Device struct {
+	// The bus the device is connected to.
+	Bus *Bus
+
+	// The address of the device, including the TenBit flag for 10-bit
+	// addresses.
+	Addr uint16
+}
+
+// Tx writes w to the device then reads r from it, as per Bus.Tx.
+func (d *Device) Tx(w, r []byte) error {
+	return d.Bus.Tx(d.Addr, w, r)
+}
+
+// Write writes the data to the device.
+//
+// This implements io.Writer.
+func (d *Device) Write(p []byte) (int, error) {
+	if err := d.Bus.Tx(d.Addr, p, nil); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Read reads data from the device.
+//
+// This implements io.Reader.
+func (d *Device) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := d.Bus.Tx(d.Addr, nil, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Option specifies a construction option for the Bus.
+type Option func(*Bus)
+
+// WithTclk sets the clock period for the Bus.
+//
+// Note that this is the half-cycle period.  The default is 5µs, for a 100kHz
+// clock.
+func WithTclk(tclk time.Duration) Option {
+	return func(b *Bus) {
+		b.tclk = tclk
+	}
+}
+
+// WithStretchTimeout sets the maximum time a device may stretch the clock.
+//
+// The default is 25ms.
+func WithStretchTimeout(timeout time.Duration) Option {
+	return func(b *Bus) {
+		b.stretchTimeout = timeout
+	}
+}
+
+// WithPullUp enables the internal pull-ups on the lines.
+//
+// The internal pull-ups are typically weak, so external pull-ups are preferred
+// for all but the slowest buses.
+func WithPullUp() Option {
+	return func(b *Bus) {
+		b.pullUp = true
+	}
+}
+
+// WithTiming sets the options for the Delay used to time the clock edges.
+func WithTiming(options ...timing.Option) Option {
+	return func(b *Bus) {
+		b.timingOptions = append(b.timingOptions, options...)
+	}
+}
+
+var (
+	// ErrAddressNack indicates no device acknowledged the address.
+	ErrAddressNack = errors.New("address not acknowledged")
+
+	// ErrBusStuck indicates SDA remains low after attempting recovery.
+	ErrBusStuck = errors.New("bus stuck")
+
+	// ErrClockStretch indicates a device held SCL low for longer than the
+	// stretch timeout.
+	ErrClockStretch = errors.New("clock stretch timeout")
+
+	// ErrDataNack indicates the device did not acknowledge written data.
+	ErrDataNack = errors.New("data not acknowledged")
+
+	// ErrInvalidAddress indicates an address is out of range for its size.
+	ErrInvalidAddress = errors.New("invalid address")
+)
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package i2c_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/i2c"
+	"github.com/warthog618/gpiod/mockup"
+)
+
+const (
+	scl = 1
+	sda = 2
+)
+
+func TestNew(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	b, err := i2c.New(chip, scl, sda, i2c.WithPullUp(), i2c.WithTclk(time.Microsecond))
+	assert.Nil(t, err)
+	require.NotNil(t, b)
+	assert.Equal(t, fmt.Sprintf("i2c(%s scl=%d sda=%d)", c.Name, scl, sda), b.String())
+
+	// lines are busy
+	b2, err := i2c.New(chip, scl, sda)
+	assert.NotNil(t, err)
+	assert.Nil(t, b2)
+
+	// released idle
+	for _, o := range []int{scl, sda} {
+		v, err := c.Value(o)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, v)
+	}
+	assert.Nil(t, b.Close())
+}
+
+func TestTx(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	b, err := i2c.New(chip, scl, sda)
+	require.Nil(t, err)
+	defer b.Close()
+
+	patterns := []struct {
+		name string
+		addr uint16
+		err  error
+	}{
+		{"7-bit range", 0x80, i2c.ErrInvalidAddress},
+		{"10-bit range", i2c.TenBit | 0x400, i2c.ErrInvalidAddress},
+		// nothing on the bus pulls SDA low to ACK
+		{"7-bit nack", 0x50, i2c.ErrAddressNack},
+		{"10-bit nack", i2c.TenBit | 0x250, i2c.ErrAddressNack},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			err := b.Tx(p.addr, []byte{1, 2}, make([]byte, 2))
+			assert.Equal(t, p.err, err)
+		}
+		t.Run(p.name, tf)
+	}
+
+	d := i2c.Device{Bus: b, Addr: 0x50}
+	n, err := d.Write([]byte{1})
+	assert.Equal(t, i2c.ErrAddressNack, err)
+	assert.Zero(t, n)
+	n, err = d.Read(make([]byte, 1))
+	assert.Equal(t, i2c.ErrAddressNack, err)
+	assert.Zero(t, n)
+	assert.Equal(t, i2c.ErrAddressNack, d.Tx(nil, nil))
+
+	// the bus is left idle
+	for _, o := range []int{scl, sda} {
+		v, err := c.Value(o)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, v)
+	}
+	assert.Nil(t, b.Recover())
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
@@ -0,0 +1,711 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package hd44780 provides a driver for HD44780 compatible character LCDs
+// connected directly to GPIO lines.
+//
+// The LCD may be connected in 4-bit mode, using data lines D4-D7, or 8-bit
+// mode, using data lines D0-D7.  The RW line is optional.  If it is connected
+// then the busy flag is polled to determine when the LCD is ready for the next
+// instruction, else the LCD is assumed ready after the maximum execution time
+// of the instruction.
+//
+// Polling the busy flag requires switching the direction of the data lines
+// independently of the other lines, which requires uAPI v2.  With uAPI v1 the
+// RW line is held low and the LCD is timed as if RW were not connected.
+package hd44780
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// instructions
+const (
+	cmdClear          = 0x01
+	cmdHome           = 0x02
+	cmdEntryMode      = 0x04
+	cmdDisplayControl = 0x08
+	cmdShift          = 0x10
+	cmdFunctionSet    = 0x20
+	cmdSetCGRAMAddr   = 0x40
+	cmdSetDDRAMAddr   = 0x80
+)
+
+// instruction flags
+const (
+	// entry mode
+	entryIncrement = 0x02
+	entryShift     = 0x01
+
+	// display control
+	displayOn = 0x04
+	cursorOn  = 0x02
+	blinkOn   = 0x01
+
+	// cursor or display shift
+	shiftDisplay = 0x08
+	shiftRight   = 0x04
+
+	// function set
+	function8Bit  = 0x10
+	function2Line = 0x08
+
+	// the busy flag read with the address counter
+	busyFlag = 0x80
+)
+
+// execution times, with some margin over the datasheet for a 270kHz clock.
+const (
+	// the time after power on before the LCD accepts instructions
+	tPowerOn = 50 * time.Millisecond
+
+	// the delays between the initial function sets
+	tInit1 = 5 * time.Millisecond
+	tInit2 = 150 * time.Microsecond
+
+	// Clear and Home
+	tHome = 2 * time.Millisecond
+
+	// all other instructions and data writes
+	tExec = 50 * time.Microsecond
+
+	// the enable pulse width, and the time after it before the next
+	tEnable = time.Microsecond
+
+	// the maximum time the busy flag may remain set
+	busyTimeout = 10 * time.Millisecond
+)
+
+// masks for the control lines, which are requested in RS, E, RW order.
+const (
+	rsMask = 0x1
+	eMask  = 0x2
+	rwMask = 0x4
+)
+
+// Lines is the set of lines driving the LCD.
+//
+// It is implemented by gpiod.Lines, and may be substituted by a simulation
+// for testing.
+//
+// The lines are ordered RS, E, RW, then the data lines from the least
+// significant, i.e. D0-D7 in 8-bit mode or D4-D7 in 4-bit mode.  RW is omitted
+// if not connected.
+type Lines interface {
+	// Offsets returns the offsets of the lines.
+	Offsets() []int
+
+	// ValuesMask returns the values of the lines identified by mask.
+	ValuesMask(mask uint64) (uint64, error)
+
+	// SetValuesMask sets the values of the lines identified by mask.
+	SetValuesMask(mask, bits uint64) error
+
+	// Reconfigure updates the configuration of the lines.
+	//
+	// Only used to switch the direction of the data lines when RW is
+	// connected.
+	Reconfigure(options ...gpiod.LineConfigOption) error
+}
+
+// abiVersioner is implemented by Lines that report the uAPI version they use,
+// such as gpiod.Lines.
+type abiVersioner interface {
+	UapiAbiVersion() int
+}
+
+// LCD is an HD44780 compatible character LCD.
+type LCD struct {
+	// mu serialises access to the LCD
+	mu sync.Mutex
+
+	l Lines
+
+	// the lines requested by New, which are released by Close
+	owned *gpiod.Lines
+
+	// true if RW is connected
+	rw bool
+
+	// true if the busy flag is polled, which requires RW and uAPI v2
+	poll bool
+
+	// the offsets of the data lines
+	data []int
+
+	// the position of D0, or D4 in 4-bit mode, in the lines
+	dataShift uint
+
+	// the number of data lines, 4 or 8
+	dataLines int
+
+	cols int
+	rows int
+
+	// the DDRAM address of the start of each row
+	rowAddr []byte
+
+	// the current display control and entry mode flags
+	displayControl byte
+	entryMode      byte
+
+	delay *timing.Delay
+
+	// options for the delay
+	timingOptions []timing.Option
+
+	// the offset of the RW line, from WithRW
+	rwOffset int
+
+	// Error from construction options
+	err error
+
+	closed bool
+}
+
+// New creates an LCD on the RS, E and data lines of the chip, and initialises
+// it.
+//
+// The data lines are D4-D7 for 4-bit mode, or D0-D7 for 8-bit mode.
+//
+// The busy flag is only polled if RW is connected and the chip uses uAPI v2.
+func New(c *gpiod.Chip, rs, e int, data []int, options ...Option) (*LCD, error) {
+	if len(data) != 4 && len(data) != 8 {
+		return nil, ErrInvalidDataLines
+	}
+	d := newLCD(options)
+	if d.err != nil {
+		return nil, d.err
+	}
+	offsets := []int{rs, e}
+	if d.rwOffset >= 0 {
+		offsets = append(offsets, d.rwOffset)
+	}
+	offsets = append(offsets, data...)
+	l, err := c.RequestLines(offsets, gpiod.AsOutput())
+	if err != nil {
+		return nil, err
+	}
+	if err = d.setup(l); err != nil {
+		l.Close()
+		return nil, err
+	}
+	d.owned = l
+	return d, nil
+}
+
+// NewLines creates an LCD driven by the lines, and initialises it.
+//
+// The mode and whether RW is connected are determined by the number of lines,
+// which must be 6 or 7 for 4-bit mode, and 10 or 11 for 8-bit mode.  The lines
+// must be outputs.
+//
+// The busy flag is only polled if RW is connected and the lines do not report
+// using uAPI v1.
+//
+// The LCD does not take ownership of the lines, so they are not released by
+// Close.
+func NewLines(l Lines, options ...Option) (*LCD, error) {
+	d := newLCD(options)
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := d.setup(l); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func newLCD(options []Option) *LCD {
+	d := LCD{
+		cols:     16,
+		rows:     2,
+		rwOffset: -1,
+	}
+	for _, option := range options {
+		option(&d)
+	}
+	return &d
+}
+
+// setup determines the wiring from the lines, then initialises the LCD.
+func (d *LCD) setup(l Lines) error {
+	offsets := l.Offsets()
+	switch len(offsets) {
+	case 6, 10:
+		d.dataShift = 2
+	case 7, 11:
+		d.rw = true
+		d.dataShift = 3
+	default:
+		return ErrInvalidLines
+	}
+	d.poll = d.rw
+	if v, ok := l.(abiVersioner); ok && v.UapiAbiVersion() == 1 {
+		// the data lines cannot be reconfigured alone, so fall back to
+		// timed delays.
+		d.poll = false
+	}
+	d.l = l
+	d.data = offsets[d.dataShift:]
+	d.dataLines = len(d.data)
+	for _, addr := range []int{0, 0x40, d.cols, 0x40 + d.cols}[:d.rows] {
+		d.rowAddr = append(d.rowAddr, byte(addr))
+	}
+	var err error
+	d.delay, err = timing.NewDelay(d.timingOptions...)
+	if err != nil {
+		return err
+	}
+	if err = d.init(); err != nil {
+		d.delay.Close()
+		return err
+	}
+	return nil
+}
+
+// Close releases the lines, if requested by New.
+//
+// The display is left in its current state.
+func (d *LCD) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	d.closed = true
+	d.delay.Close()
+	if d.owned != nil {
+		return d.owned.Close()
+	}
+	return nil
+}
+
+// Cols returns the number of columns on the display.
+func (d *LCD) Cols() int {
+	return d.cols
+}
+
+// Rows returns the number of rows on the display.
+func (d *LCD) Rows() int {
+	return d.rows
+}
+
+// init performs the initialisation by instruction sequence from the
+// datasheet, which places the LCD in a known state regardless of its state
+// at power on.
+func (d *LCD) init() error {
+	d.delay.Wait(tPowerOn)
+	// the busy flag can't be checked until the interface width is set
+	for _, t := range []time.Duration{tInit1, tInit2, tExec} {
+		if err := d.writeInit(cmdFunctionSet | function8Bit); err != nil {
+			return err
+		}
+		d.delay.Wait(t)
+	}
+	if d.dataLines == 4 {
+		if err := d.writeInit(cmdFunctionSet); err != nil {
+			return err
+		}
+		d.delay.Wait(tExec)
+	}
+	function := byte(cmdFunctionSet)
+	if d.dataLines == 8 {
+		function |= function8Bit
+	}
+	if d.rows > 1 {
+		function |= function2Line
+	}
+	d.displayControl = displayOn
+	d.entryMode = entryIncrement
+	for _, cmd := range []byte{
+		function,
+		cmdDisplayControl,
+		cmdClear,
+		cmdEntryMode | d.entryMode,
+		cmdDisplayControl | d.displayControl,
+	} {
+		if err := d.command(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear clears the display and returns the cursor to the home position.
+func (d *LCD) Clear() error {
+	return d.locked(func() error {
+		return d.command(cmdClear)
+	})
+}
+
+// Home returns the cursor to the home position, and the display to its
+// original position if it has been shifted.
+func (d *LCD) Home() error {
+	return d.locked(func() error {
+		return d.command(cmdHome)
+	})
+}
+
+// SetCursor moves the cursor to the column and row, both zero based.
+func (d *LCD) SetCursor(col, row int) error {
+	if col < 0 || col >= d.cols || row < 0 || row >= d.rows {
+		return ErrInvalidPosition
+	}
+	return d.locked(func() error {
+		return d.command(cmdSetDDRAMAddr | (d.rowAddr[row] + byte(col)))
+	})
+}
+
+// Write writes characters to the display at the cursor.
+//
+// The bytes are written as is, so characters 0-7 are the custom characters,
+// and characters above 0x7f depend on the character ROM of the LCD.  No
+// wrapping is performed, so the cursor follows the DDRAM address order, which
+// is not the row order for all displays.
+func (d *LCD) Write(p []byte) (int, error) {
+	n := 0
+	err := d.locked(func() error {
+		for _, c := range p {
+			if err := d.write(rsMask, c, tExec); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// WriteString writes a string to the display at the cursor, as per Write.
+func (d *LCD) WriteString(s string) (int, error) {
+	return d.Write([]byte(s))
+}
+
+// Display turns the display on or off.
+//
+// The contents are retained while the display is off.
+func (d *LCD) Display(on bool) error {
+	return d.setDisplayControl(displayOn, on)
+}
+
+// Cursor shows or hides the underline cursor.
+func (d *LCD) Cursor(on bool) error {
+	return d.setDisplayControl(cursorOn, on)
+}
+
+// Blink enables or disables the blinking block cursor.
+func (d *LCD) Blink(on bool) error {
+	return d.setDisplayControl(blinkOn, on)
+}
+
+func (d *LCD) setDisplayControl(flag byte, on bool) error {
+	return d.locked(func() error {
+		dc := setFlag(d.displayControl, flag, on)
+		if err := d.command(cmdDisplayControl | dc); err != nil {
+			return err
+		}
+		d.displayControl = dc
+		return nil
+	})
+}
+
+// LeftToRight sets the cursor to move right after each character written.
+//
+// This is the default.
+func (d *LCD) LeftToRight() error {
+	return d.setEntryMode(entryIncrement, true)
+}
+
+// RightToLeft sets the cursor to move left after each character written.
+func (d *LCD) RightToLeft() error {
+	return d.setEntryMode(entryIncrement, false)
+}
+
+// Autoscroll enables or disables shifting the display, rather than the
+// cursor, after each character written.
+func (d *LCD) Autoscroll(on bool) error {
+	return d.setEntryMode(entryShift, on)
+}
+
+func (d *LCD) setEntryMode(flag byte, on bool) error {
+	return d.locked(func() error {
+		em := setFlag(d.entryMode, flag, on)
+		if err := d.command(cmdEntryMode | em); err != nil {
+			return err
+		}
+		d.entryMode = em
+		return nil
+	})
+}
+
+// ScrollLeft shifts the display, but not the contents of DDRAM, one position
+// to the left.
+func (d *LCD) ScrollLeft() error {
+	return d.locked(func() error {
+		return d.command(cmdShift | shiftDisplay)
+	})
+}
+
+// ScrollRight shifts the display, but not the contents of DDRAM, one position
+// to the right.
+func (d *LCD) ScrollRight() error {
+	return d.locked(func() error {
+		return d.command(cmdShift | shiftDisplay | shiftRight)
+	})
+}
+
+// CreateChar defines the pattern of one of the 8 custom characters, which
+// may then be displayed by writing characters 0-7.
+//
+// Each byte of the pattern is a row, top first, with the lower 5 bits being
+// the pixels, leftmost as the most significant.
+//
+// The cursor is returned to the home position.
+func (d *LCD) CreateChar(location int, pattern [8]byte) error {
+	if location < 0 || location > 7 {
+		return ErrInvalidChar
+	}
+	return d.locked(func() error {
+		if err := d.command(cmdSetCGRAMAddr | byte(location<<3)); err != nil {
+			return err
+		}
+		for _, row := range pattern {
+			if err := d.write(rsMask, row&0x1f, tExec); err != nil {
+				return err
+			}
+		}
+		return d.command(cmdSetDDRAMAddr)
+	})
+}
+
+// Command sends a raw instruction to the LCD.
+//
+// This is intended for instructions not otherwise supported by the driver,
+// and changes made by the instruction are not tracked by the driver.
+func (d *LCD) Command(cmd byte) error {
+	return d.locked(func() error {
+		return d.command(cmd)
+	})
+}
+
+// locked calls fn with the mu held, if the LCD is not closed.
+func (d *LCD) locked(fn func() error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	return fn()
+}
+
+// command writes an instruction and waits for it to complete.
+func (d *LCD) command(cmd byte) error {
+	t := tExec
+	if cmd == cmdClear || cmd&^1 == cmdHome {
+		t = tHome
+	}
+	return d.write(0, cmd, t)
+}
+
+// write writes an instruction, or data if rs is set, then waits for it to
+// complete, either by polling the busy flag or waiting the execution time t.
+func (d *LCD) write(rs uint64, v byte, t time.Duration) error {
+	if d.dataLines == 4 {
+		if err := d.writeBits(rs, v>>4); err != nil {
+			return err
+		}
+		if err := d.writeBits(rs, v&0x0f); err != nil {
+			return err
+		}
+	} else {
+		if err := d.writeBits(rs, v); err != nil {
+			return err
+		}
+	}
+	if d.poll {
+		return d.waitReady()
+	}
+	d.delay.Wait(t)
+	return nil
+}
+
+// writeInit writes the upper nibble of an instruction as a single transfer,
+// as required while the LCD may be in 8-bit mode.
+func (d *LCD) writeInit(cmd byte) error {
+	if d.dataLines == 4 {
+		return d.writeBits(0, cmd>>4)
+	}
+	return d.writeBits(0, cmd)
+}
+
+// writeBits places the value on the data lines and pulses E to transfer it.
+func (d *LCD) writeBits(rs uint64, v byte) error {
+	ctrl := uint64(rsMask | eMask)
+	if d.rw {
+		ctrl |= rwMask
+	}
+	mask := ctrl | d.dataMask()
+	if err := d.l.SetValuesMask(mask, rs|uint64(v)<<d.dataShift); err != nil {
+		return err
+	}
+	return d.pulse()
+}
+
+// pulse pulses E to transfer data to the LCD.
+func (d *LCD) pulse() error {
+	if err := d.l.SetValuesMask(eMask, eMask); err != nil {
+		return err
+	}
+	d.delay.Wait(tEnable)
+	if err := d.l.SetValuesMask(eMask, 0); err != nil {
+		return err
+	}
+	d.delay.Wait(tEnable)
+	return nil
+}
+
+// waitReady polls the busy flag until it is clear.
+func (d *LCD) waitReady() (err error) {
+	err = d.l.Reconfigure(gpiod.WithLines(d.data, gpiod.AsInput))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		rerr := d.l.Reconfigure(gpiod.WithLines(d.data, gpiod.AsOutput()))
+		if err == nil {
+			err = rerr
+		}
+	}()
+	if err = d.l.SetValuesMask(rsMask|rwMask, rwMask); err != nil {
+		return err
+	}
+	defer func() {
+		rerr := d.l.SetValuesMask(rwMask, 0)
+		if err == nil {
+			err = rerr
+		}
+	}()
+	deadline := timing.Now() + busyTimeout
+	for {
+		var v byte
+		if v, err = d.readBits(); err != nil {
+			return err
+		}
+		if d.dataLines == 4 {
+			// the busy flag is in the upper nibble, read first
+			if _, err = d.readBits(); err != nil {
+				return err
+			}
+			v <<= 4
+		}
+		if v&busyFlag == 0 {
+			return nil
+		}
+		if timing.Now() > deadline {
+			return ErrBusyTimeout
+		}
+	}
+}
+
+// readBits raises E and reads the data lines before lowering it again.
+func (d *LCD) readBits() (byte, error) {
+	if err := d.l.SetValuesMask(eMask, eMask); err != nil {
+		return 0, err
+	}
+	d.delay.Wait(tEnable)
+	v, err := d.l.ValuesMask(d.dataMask())
+	if err != nil {
+		return 0, err
+	}
+	if err = d.l.SetValuesMask(eMask, 0); err != nil {
+		return 0, err
+	}
+	d.delay.Wait(tEnable)
+	return byte(v >> d.dataShift), nil
+}
+
+func (d *LCD) dataMask() uint64 {
+	return (uint64(1)<<uint(d.dataLines) - 1) << d.dataShift
+}
+
+func setFlag(v, flag byte, on bool) byte {
+	if on {
+		return v | flag
+	}
+	return v &^ flag
+}
+
+// Option specifies a construction option for the LCD.
+type Option func(*LCD)
+
+// WithRW specifies the line connected to RW, so the busy flag can be polled.
+//
+// Polling requires uAPI v2.  With uAPI v1 the RW line is held low and timed
+// delays are used instead.
+//
+// Only applies to New.  By default RW is assumed to be tied low.
+func WithRW(offset int) Option {
+	return func(d *LCD) {
+		d.rwOffset = offset
+	}
+}
+
+// WithSize sets the number of columns and rows on the display.
+//
+// The default is 16x2.
+func WithSize(cols, rows int) Option {
+	return func(d *LCD) {
+		if rows < 1 || rows > 4 || cols < 1 || cols > 40 || cols*rows > 80 {
+			d.err = ErrInvalidSize
+			return
+		}
+		d.cols = cols
+		d.rows = rows
+	}
+}
+
+// WithTiming sets the options for the Delay used to time the instructions.
+//
+// Most instructions complete within tens of microseconds, but Clear and Home take
+// milliseconds, so a Sleep Delay is only suitable for displays that are
+// rarely updated.
+func WithTiming(options ...timing.Option) Option {
+	return func(d *LCD) {
+		d.timingOptions = append(d.timingOptions, options...)
+	}
+}
+
+var (
+	// ErrBusyTimeout indicates the busy flag remained set for longer than any
+	// instruction should take.
+	ErrBusyTimeout = errors.New("timeout waiting for busy flag")
+
+	// ErrClosed indicates the LCD has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrInvalidChar indicates the custom character location is not 0-7.
+	ErrInvalidChar = errors.New("invalid character")
+
+	// ErrInvalidDataLines indicates the number of data lines is not 4 or 8.
+	ErrInvalidDataLines = errors.New("invalid data lines")
+
+	// ErrInvalidLines indicates the number of lines does not correspond to a
+	// supported wiring.
+	ErrInvalidLines = errors.New("invalid lines")
+
+	// ErrInvalidPosition indicates the cursor position is outside the display.
+	ErrInvalidPosition = errors.New("invalid position")
+
+	// ErrInvalidSize indicates the display size is not supported.
+	ErrInvalidSize = errors.New("invalid size")
+)
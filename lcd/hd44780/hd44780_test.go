@@ -0,0 +1,489 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package hd44780_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/lcd/hd44780"
+	"github.com/warthog618/gpiod/mockup"
+)
+
+func TestNew(t *testing.T) {
+	// invalid options are rejected before the lines are requested
+	d, err := hd44780.New(nil, 0, 1, []int{2, 3, 4})
+	assert.Equal(t, hd44780.ErrInvalidDataLines, err)
+	assert.Nil(t, d)
+	d, err = hd44780.New(nil, 0, 1, []int{2, 3, 4, 5}, hd44780.WithSize(20, 5))
+	assert.Equal(t, hd44780.ErrInvalidSize, err)
+	assert.Nil(t, d)
+
+	m, c := newMockup(t, 12)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	d, err = hd44780.New(chip, 1, 2, []int{4, 5, 6, 7}, hd44780.WithSize(20, 4))
+	require.Nil(t, err)
+	require.NotNil(t, d)
+	assert.Equal(t, 20, d.Cols())
+	assert.Equal(t, 4, d.Rows())
+
+	// lines are busy
+	d2, err := hd44780.New(chip, 1, 2, []int{4, 5, 6, 7})
+	assert.NotNil(t, err)
+	assert.Nil(t, d2)
+
+	// E is left low
+	v, err := c.Value(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+	assert.Nil(t, d.Close())
+	assert.Equal(t, hd44780.ErrClosed, d.Close())
+}
+
+func TestNewLines(t *testing.T) {
+	s := newSim(4, false)
+	s.offsets = s.offsets[:5]
+	d, err := hd44780.NewLines(s)
+	assert.Equal(t, hd44780.ErrInvalidLines, err)
+	assert.Nil(t, d)
+
+	s = newSim(4, false)
+	s.err = errors.New("set failed")
+	d, err = hd44780.NewLines(s)
+	assert.Equal(t, s.err, err)
+	assert.Nil(t, d)
+
+	// stuck busy
+	s = newSim(8, true)
+	s.busyReads = -1
+	d, err = hd44780.NewLines(s)
+	assert.Equal(t, hd44780.ErrBusyTimeout, err)
+	assert.Nil(t, d)
+
+	// uAPI v1 falls back to timed delays
+	s = newSim(8, true)
+	s.busyReads = -1
+	s.abi = 1
+	d, err = hd44780.NewLines(s)
+	require.Nil(t, err)
+	require.NotNil(t, d)
+	assert.Zero(t, s.busyPolls)
+	assert.Zero(t, s.reconfigs)
+	_, err = d.WriteString("hi")
+	assert.Nil(t, err)
+	assert.Nil(t, d.Close())
+}
+
+func TestInit(t *testing.T) {
+	patterns := []struct {
+		dataLines int
+		rw        bool
+		options   []hd44780.Option
+		trace     []op
+	}{
+		{4, false, nil, []op{
+			{0, 0x30}, {0, 0x30}, {0, 0x30}, {0, 0x20},
+			{0, 0x28}, {0, 0x08}, {0, 0x01}, {0, 0x06}, {0, 0x0c},
+		}},
+		{4, true, nil, []op{
+			{0, 0x30}, {0, 0x30}, {0, 0x30}, {0, 0x20},
+			{0, 0x28}, {0, 0x08}, {0, 0x01}, {0, 0x06}, {0, 0x0c},
+		}},
+		{8, false, nil, []op{
+			{0, 0x30}, {0, 0x30}, {0, 0x30},
+			{0, 0x38}, {0, 0x08}, {0, 0x01}, {0, 0x06}, {0, 0x0c},
+		}},
+		{8, true, []hd44780.Option{hd44780.WithSize(8, 1)}, []op{
+			{0, 0x30}, {0, 0x30}, {0, 0x30},
+			{0, 0x30}, {0, 0x08}, {0, 0x01}, {0, 0x06}, {0, 0x0c},
+		}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			s := newSim(p.dataLines, p.rw)
+			d, err := hd44780.NewLines(s, p.options...)
+			require.Nil(t, err)
+			defer d.Close()
+			assert.Equal(t, p.trace, s.trace)
+			assert.Equal(t, p.dataLines == 8, s.eightBit)
+			assert.False(t, s.pending)
+			if p.rw {
+				assert.NotZero(t, s.busyPolls)
+			} else {
+				assert.Zero(t, s.busyPolls)
+			}
+		}
+		t.Run(fmt.Sprintf("%d-bit rw=%t", p.dataLines, p.rw), tf)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	for _, dataLines := range []int{4, 8} {
+		tf := func(t *testing.T) {
+			s := newSim(dataLines, true)
+			d, err := hd44780.NewLines(s, hd44780.WithSize(20, 4))
+			require.Nil(t, err)
+			defer d.Close()
+
+			s.trace = nil
+			n, err := d.WriteString("hello")
+			assert.Nil(t, err)
+			assert.Equal(t, 5, n)
+			assert.Nil(t, d.SetCursor(3, 1))
+			n, err = d.Write([]byte("world"))
+			assert.Nil(t, err)
+			assert.Equal(t, 5, n)
+			assert.Nil(t, d.SetCursor(19, 3))
+			_, err = d.WriteString("!")
+			assert.Nil(t, err)
+			assert.Equal(t, "hello               ", s.text(0x00, 20))
+			assert.Equal(t, "   world            ", s.text(0x40, 20))
+			assert.Equal(t, "                   !", s.text(0x54, 20))
+			assert.Equal(t, op{0, 0xc3}, s.trace[5])
+			assert.Equal(t, op{0, 0xe7}, s.trace[11])
+
+			patterns := []struct {
+				col int
+				row int
+			}{{-1, 0}, {20, 0}, {0, -1}, {0, 4}}
+			for _, p := range patterns {
+				assert.Equal(t, hd44780.ErrInvalidPosition, d.SetCursor(p.col, p.row))
+			}
+
+			assert.Nil(t, d.Clear())
+			assert.Equal(t, "                    ", s.text(0x00, 20))
+			assert.Zero(t, s.ac)
+		}
+		t.Run(fmt.Sprintf("%d-bit", dataLines), tf)
+	}
+}
+
+func TestControl(t *testing.T) {
+	s := newSim(4, false)
+	d, err := hd44780.NewLines(s)
+	require.Nil(t, err)
+	defer d.Close()
+
+	patterns := []struct {
+		name string
+		fn   func() error
+		cmd  byte
+	}{
+		{"home", d.Home, 0x02},
+		{"clear", d.Clear, 0x01},
+		{"cursor on", func() error { return d.Cursor(true) }, 0x0e},
+		{"blink on", func() error { return d.Blink(true) }, 0x0f},
+		{"display off", func() error { return d.Display(false) }, 0x0b},
+		{"cursor off", func() error { return d.Cursor(false) }, 0x09},
+		{"display on", func() error { return d.Display(true) }, 0x0d},
+		{"blink off", func() error { return d.Blink(false) }, 0x0c},
+		{"right to left", d.RightToLeft, 0x04},
+		{"autoscroll", func() error { return d.Autoscroll(true) }, 0x05},
+		{"left to right", d.LeftToRight, 0x07},
+		{"no autoscroll", func() error { return d.Autoscroll(false) }, 0x06},
+		{"scroll left", d.ScrollLeft, 0x18},
+		{"scroll right", d.ScrollRight, 0x1c},
+		{"command", func() error { return d.Command(0x14) }, 0x14},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			s.trace = nil
+			assert.Nil(t, p.fn())
+			assert.Equal(t, []op{{0, p.cmd}}, s.trace)
+		}
+		t.Run(p.name, tf)
+	}
+
+	// the LCD tracks the effect of the commands
+	assert.Equal(t, byte(0x04), s.display)
+	assert.Equal(t, byte(0x02), s.entry)
+	assert.Equal(t, 0, s.shift)
+
+	// closed
+	assert.Nil(t, d.Close())
+	assert.Equal(t, hd44780.ErrClosed, d.Clear())
+	assert.Equal(t, hd44780.ErrClosed, d.Cursor(true))
+	_, err = d.WriteString("x")
+	assert.Equal(t, hd44780.ErrClosed, err)
+}
+
+func TestCreateChar(t *testing.T) {
+	s := newSim(4, true)
+	d, err := hd44780.NewLines(s)
+	require.Nil(t, err)
+	defer d.Close()
+
+	assert.Equal(t, hd44780.ErrInvalidChar, d.CreateChar(-1, [8]byte{}))
+	assert.Equal(t, hd44780.ErrInvalidChar, d.CreateChar(8, [8]byte{}))
+
+	heart := [8]byte{0x00, 0x0a, 0x1f, 0x1f, 0x0e, 0x04, 0x00, 0xff}
+	s.trace = nil
+	assert.Nil(t, d.CreateChar(3, heart))
+	assert.Equal(t, op{0, 0x58}, s.trace[0])
+	assert.Equal(t, op{0, 0x80}, s.trace[9])
+	assert.Equal(t, []byte{0x00, 0x0a, 0x1f, 0x1f, 0x0e, 0x04, 0x00, 0x1f}, s.cgram[24:32])
+
+	// subsequent writes go to DDRAM
+	_, err = d.Write([]byte{3})
+	assert.Nil(t, err)
+	assert.Equal(t, byte(3), s.ddram[0])
+}
+
+// op is an instruction, or data if RS is set, received by the simulated LCD.
+type op struct {
+	RS int
+	V  byte
+}
+
+// sim simulates an HD44780 connected to the Lines.
+type sim struct {
+	mu sync.Mutex
+
+	offsets   []int
+	dataShift uint
+	dataLines int
+
+	// the line levels
+	bits uint64
+
+	// the byte presented on the data lines for a read
+	out byte
+
+	// true if the interface is 8-bit
+	eightBit bool
+
+	// true if the upper nibble of a 4-bit transfer has been received
+	pending bool
+	upper   byte
+
+	// true if the upper nibble of a 4-bit read has been presented
+	reading bool
+
+	// the operations received
+	trace []op
+
+	ddram   [0x80]byte
+	cgram   [0x40]byte
+	ac      byte
+	cg      bool
+	entry   byte
+	display byte
+	shift   int
+
+	// the number of reads the busy flag is set after each operation, or
+	// forever if negative
+	busyReads int
+	busy      int
+	busyPolls int
+
+	// the error returned by SetValuesMask
+	err error
+
+	// the uAPI version reported by the lines
+	abi int
+
+	// the number of times the lines were reconfigured
+	reconfigs int
+}
+
+func newSim(dataLines int, rw bool) *sim {
+	s := sim{
+		offsets:   []int{0, 1},
+		dataShift: 2,
+		dataLines: dataLines,
+		eightBit:  true,
+		busyReads: 2,
+		abi:       2,
+	}
+	if rw {
+		s.offsets = append(s.offsets, 2)
+		s.dataShift = 3
+	}
+	for i := 0; i < dataLines; i++ {
+		s.offsets = append(s.offsets, 4+i)
+	}
+	for i := range s.ddram {
+		s.ddram[i] = ' '
+	}
+	return &s
+}
+
+func (s *sim) rw() bool {
+	return s.dataShift == 3 && s.bits&4 != 0
+}
+
+func (s *sim) Offsets() []int {
+	return s.offsets
+}
+
+func (s *sim) Reconfigure(options ...gpiod.LineConfigOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.abi == 1 {
+		return gpiod.ErrUapiIncompatibility{Feature: "view reconfiguration", AbiVersion: 1}
+	}
+	s.reconfigs++
+	return nil
+}
+
+func (s *sim) UapiAbiVersion() int {
+	return s.abi
+}
+
+func (s *sim) ValuesMask(mask uint64) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bits := s.bits
+	if s.rw() && bits&2 != 0 {
+		dm := uint64(1)<<uint(s.dataLines) - 1
+		bits = bits&^(dm<<s.dataShift) | uint64(s.out)<<s.dataShift
+	}
+	return bits & mask, nil
+}
+
+func (s *sim) SetValuesMask(mask, bits uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	prev := s.bits
+	s.bits = prev&^mask | bits&mask
+	rising := prev&2 == 0 && s.bits&2 != 0
+	falling := prev&2 != 0 && s.bits&2 == 0
+	if rising && s.rw() {
+		s.present()
+	}
+	if falling && !s.rw() {
+		s.latch()
+	}
+	return nil
+}
+
+// present places the busy flag and address counter on the data lines.
+func (s *sim) present() {
+	status := s.ac & 0x7f
+	if s.busy != 0 {
+		status |= 0x80
+	}
+	if s.dataLines == 8 {
+		s.out = status
+		s.busyPolls++
+		s.endBusyRead()
+		return
+	}
+	if !s.reading {
+		s.out = status >> 4
+		s.reading = true
+		s.busyPolls++
+		return
+	}
+	s.out = status & 0x0f
+	s.reading = false
+	s.endBusyRead()
+}
+
+func (s *sim) endBusyRead() {
+	if s.busy > 0 {
+		s.busy--
+	}
+}
+
+// latch receives the data lines on the falling edge of E.
+func (s *sim) latch() {
+	d := byte(s.bits>>s.dataShift) & byte(uint(1)<<uint(s.dataLines)-1)
+	rs := int(s.bits & 1)
+	switch {
+	case s.eightBit && s.dataLines == 4:
+		// D0-D3 not connected
+		s.execute(rs, d<<4)
+	case s.eightBit:
+		s.execute(rs, d)
+	case !s.pending:
+		s.upper = d
+		s.pending = true
+	default:
+		s.pending = false
+		s.execute(rs, s.upper<<4|d)
+	}
+}
+
+func (s *sim) execute(rs int, v byte) {
+	s.trace = append(s.trace, op{rs, v})
+	s.busy = s.busyReads
+	if rs == 1 {
+		if s.cg {
+			s.cgram[s.ac&0x3f] = v & 0x1f
+		} else {
+			s.ddram[s.ac&0x7f] = v
+		}
+		if s.entry&0x02 != 0 {
+			s.ac++
+		} else {
+			s.ac--
+		}
+		return
+	}
+	switch {
+	case v&0x80 != 0:
+		s.ac = v & 0x7f
+		s.cg = false
+	case v&0x40 != 0:
+		s.ac = v & 0x3f
+		s.cg = true
+	case v&0x20 != 0:
+		s.eightBit = v&0x10 != 0
+	case v&0x10 != 0:
+		if v&0x08 != 0 {
+			if v&0x04 != 0 {
+				s.shift++
+			} else {
+				s.shift--
+			}
+		}
+	case v&0x08 != 0:
+		s.display = v & 0x07
+	case v&0x04 != 0:
+		s.entry = v & 0x03
+	case v&0x02 != 0:
+		s.ac = 0
+		s.cg = false
+		s.shift = 0
+	case v&0x01 != 0:
+		for i := range s.ddram {
+			s.ddram[i] = ' '
+		}
+		s.ac = 0
+		s.cg = false
+		s.shift = 0
+		s.entry |= 0x02
+	}
+}
+
+// text returns the characters in DDRAM starting at the address.
+func (s *sim) text(addr, n int) string {
+	return string(s.ddram[addr : addr+n])
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
@@ -0,0 +1,427 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package manifest provides a loader for declarative descriptions of the GPIO
+// lines used by an application.
+//
+// A manifest describes named groups of lines, each group being requested from
+// a single chip as a single gpiod.Lines.  The configuration of lines uses the
+// text form parsed by gpiod.ParseLineConfig.
+//
+// An example manifest, in YAML:
+//
+//	consumer: pump-controller
+//	groups:
+//	  - name: leds
+//	    chip: gpiochip0
+//	    config: output
+//	    lines:
+//	      - line: 17
+//	        value: 1
+//	      - line: LED_GREEN
+//	        config: output,active-low
+//	  - name: buttons
+//	    chip: gpiochip0
+//	    config: input,pull-up,edge=both,debounce-period=10ms
+//	    lines:
+//	      - line: 5
+//	      - line: 6
+//
+// Lines are identified by either their offset or their name on the chip.
+// If the chip of a group is not specified then all lines of the group must be
+// identified by name, and the chip is the one containing the first line.
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/warthog618/config"
+	"github.com/warthog618/config/blob"
+	"github.com/warthog618/config/blob/decoder/json"
+	"github.com/warthog618/config/blob/decoder/yaml"
+	"github.com/warthog618/config/blob/loader/bytes"
+	"github.com/warthog618/config/blob/loader/file"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/uapi"
+)
+
+// Manifest describes the lines used by an application.
+type Manifest struct {
+	// The consumer label applied to all requested lines.
+	//
+	// If empty then the gpiod default is used.
+	Consumer string
+
+	// The groups of lines.
+	Groups []Group
+}
+
+// Group is a named collection of lines requested together from a chip.
+type Group struct {
+	// The name used to identify the group.
+	Name string
+
+	// The name of the chip containing the lines.
+	Chip string
+
+	// The default configuration for lines in the group, in the form parsed by
+	// gpiod.ParseLineConfig.
+	Config string
+
+	// The lines in the group.
+	Lines []Line
+}
+
+// Line describes a single line within a Group.
+type Line struct {
+	// The offset or name of the line on the chip.
+	Line string
+
+	// The configuration of the line, in the form parsed by
+	// gpiod.ParseLineConfig.
+	//
+	// If empty then the line has the default configuration for the group.
+	// Otherwise it replaces the default configuration for the group.
+	Config string
+
+	// The initial value of the line, if it is an output.
+	Value int
+}
+
+// Load reads a manifest from a file.
+//
+// The format of the file is determined by its extension, which must be one of
+// .json, .yaml or .yml.
+func Load(filename string) (*Manifest, error) {
+	d, err := decoder(filename)
+	if err != nil {
+		return nil, err
+	}
+	return load(file.New(filename), d)
+}
+
+// Decode reads a manifest from a block of data in the format understood by the
+// decoder.
+func Decode(data []byte, d blob.Decoder) (*Manifest, error) {
+	return load(bytes.New(data), d)
+}
+
+func decoder(filename string) (blob.Decoder, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return json.NewDecoder(), nil
+	case ".yaml", ".yml":
+		return yaml.NewDecoder(), nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+func load(l blob.Loader, d blob.Decoder) (*Manifest, error) {
+	var lerr error
+	b := blob.New(l, d, blob.WithErrorHandler(func(err error) {
+		lerr = err
+	}))
+	if lerr != nil {
+		return nil, lerr
+	}
+	cfg := config.New(b)
+	defer cfg.Close()
+	m := Manifest{}
+	if err := cfg.Unmarshal("", &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks the manifest against the chips and lines available on the
+// system.
+//
+// The lines must exist and not already be in use, each line may only be
+// included in the manifest once, and the configuration of each group must be
+// able to be mapped to the kernel uAPI.
+func (m *Manifest) Validate() error {
+	_, err := m.resolve()
+	return err
+}
+
+// Request validates the manifest and requests the lines for each group.
+//
+// If any group cannot be requested then any groups already requested are
+// released and the error returned.
+func (m *Manifest) Request(options ...Option) (Handles, error) {
+	ro := requestOptions{groupOptions: map[string][]gpiod.LineReqOption{}}
+	for _, option := range options {
+		option(&ro)
+	}
+	rgg, err := m.resolve()
+	if err != nil {
+		return nil, err
+	}
+	h := Handles{}
+	for _, rg := range rgg {
+		opts := append(rg.options, ro.groupOptions[rg.name]...)
+		l, err := gpiod.RequestLines(rg.chip, rg.offsets, opts...)
+		if err != nil {
+			h.Close()
+			return nil, ErrInvalidGroup{rg.name, err}
+		}
+		h[rg.name] = l
+	}
+	return h, nil
+}
+
+// Handles contains the requested lines, keyed by group name.
+type Handles map[string]*gpiod.Lines
+
+// Close releases all the requested lines.
+func (h Handles) Close() {
+	for name, l := range h {
+		l.Close()
+		delete(h, name)
+	}
+}
+
+// Option specifies an option for the Request of a manifest.
+type Option func(*requestOptions)
+
+type requestOptions struct {
+	groupOptions map[string][]gpiod.LineReqOption
+}
+
+// WithGroupOptions provides additional options for requesting the named
+// group, such as a gpiod.WithEventHandler.
+//
+// The options are applied after the configuration from the manifest, and so
+// override it.
+func WithGroupOptions(group string, options ...gpiod.LineReqOption) Option {
+	return func(ro *requestOptions) {
+		ro.groupOptions[group] = append(ro.groupOptions[group], options...)
+	}
+}
+
+// resolvedGroup is a Group as it is to be requested.
+type resolvedGroup struct {
+	name    string
+	chip    string
+	offsets []int
+	options []gpiod.LineReqOption
+}
+
+type lineKey struct {
+	chip   string
+	offset int
+}
+
+func (m *Manifest) resolve() ([]resolvedGroup, error) {
+	var rgg []resolvedGroup
+	groups := map[string]bool{}
+	used := map[lineKey]string{}
+	for _, g := range m.Groups {
+		if len(g.Name) == 0 {
+			return nil, ErrInvalidGroup{g.Name, ErrNoName}
+		}
+		if groups[g.Name] {
+			return nil, ErrInvalidGroup{g.Name, ErrDuplicate}
+		}
+		groups[g.Name] = true
+		rg, err := m.resolveGroup(g)
+		if err != nil {
+			return nil, err
+		}
+		for i, o := range rg.offsets {
+			k := lineKey{rg.chip, o}
+			if _, ok := used[k]; ok {
+				return nil, ErrInvalidLine{g.Name, g.Lines[i].Line, ErrDuplicate}
+			}
+			used[k] = g.Name
+		}
+		rgg = append(rgg, rg)
+	}
+	return rgg, nil
+}
+
+func (m *Manifest) resolveGroup(g Group) (rg resolvedGroup, err error) {
+	if len(g.Lines) == 0 {
+		err = ErrInvalidGroup{g.Name, ErrNoLines}
+		return
+	}
+	if len(g.Lines) > uapi.LinesMax {
+		err = ErrInvalidGroup{g.Name, ErrTooManyLines}
+		return
+	}
+	defCfg, err := gpiod.ParseLineConfig(g.Config)
+	if err != nil {
+		err = ErrInvalidGroup{g.Name, err}
+		return
+	}
+	chip := g.Chip
+	if len(chip) == 0 {
+		chip, err = findChip(g.Lines[0].Line)
+		if err != nil {
+			err = ErrInvalidLine{g.Name, g.Lines[0].Line, err}
+			return
+		}
+	}
+	c, err := gpiod.NewChip(chip)
+	if err != nil {
+		err = ErrInvalidGroup{g.Name, err}
+		return
+	}
+	defer c.Close()
+	rg = resolvedGroup{name: g.Name, chip: c.Name}
+	values := make([]int, len(g.Lines))
+	var lineOpts []gpiod.LineReqOption
+	for i, l := range g.Lines {
+		var offset int
+		offset, err = lineOffset(c, l.Line)
+		if err != nil {
+			err = ErrInvalidLine{g.Name, l.Line, err}
+			return
+		}
+		var li gpiod.LineInfo
+		li, err = c.LineInfo(offset)
+		if err != nil {
+			err = ErrInvalidLine{g.Name, l.Line, err}
+			return
+		}
+		if li.Used {
+			err = ErrInvalidLine{g.Name, l.Line, ErrInUse{li.Consumer}}
+			return
+		}
+		if len(l.Config) != 0 {
+			var cfg gpiod.LineConfig
+			cfg, err = gpiod.ParseLineConfig(l.Config)
+			if err != nil {
+				err = ErrInvalidLine{g.Name, l.Line, err}
+				return
+			}
+			lineOpts = append(lineOpts, gpiod.WithLines([]int{offset}, cfg))
+		}
+		rg.offsets = append(rg.offsets, offset)
+		values[i] = l.Value
+	}
+	if len(m.Consumer) != 0 {
+		rg.options = append(rg.options, gpiod.WithConsumer(m.Consumer))
+	}
+	// AsOutput sets the values, and the direction, which defCfg then replaces.
+	rg.options = append(rg.options, gpiod.AsOutput(values...), defCfg)
+	rg.options = append(rg.options, lineOpts...)
+	if _, err = gpiod.PlanRequest(rg.offsets, rg.options...); err != nil {
+		err = ErrInvalidGroup{g.Name, err}
+	}
+	return
+}
+
+// findChip returns the name of the chip containing the named line.
+func findChip(line string) (string, error) {
+	if _, err := strconv.ParseUint(line, 10, 64); err == nil {
+		// an offset is meaningless without a chip.
+		return "", ErrNotFound
+	}
+	for _, name := range gpiod.Chips() {
+		c, err := gpiod.NewChip(name)
+		if err != nil {
+			continue
+		}
+		_, err = lineOffset(c, line)
+		c.Close()
+		if err == nil {
+			return name, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// lineOffset returns the offset of the line identified by offset or name.
+func lineOffset(c *gpiod.Chip, line string) (int, error) {
+	if o, err := strconv.ParseUint(line, 10, 64); err == nil {
+		if int(o) >= c.Lines() {
+			return 0, gpiod.ErrInvalidOffset
+		}
+		return int(o), nil
+	}
+	if len(line) == 0 {
+		return 0, ErrNotFound
+	}
+	for o := 0; o < c.Lines(); o++ {
+		li, err := c.LineInfo(o)
+		if err == nil && li.Name == line {
+			return o, nil
+		}
+	}
+	return 0, ErrNotFound
+}
+
+var (
+	// ErrDuplicate indicates a group or line appears in the manifest more than
+	// once.
+	ErrDuplicate = errors.New("duplicated")
+
+	// ErrNoLines indicates a group contains no lines.
+	ErrNoLines = errors.New("no lines")
+
+	// ErrNoName indicates a group has no name.
+	ErrNoName = errors.New("no name")
+
+	// ErrNotFound indicates a line could not be found.
+	ErrNotFound = errors.New("not found")
+
+	// ErrTooManyLines indicates a group contains more lines than can be
+	// requested at once.
+	ErrTooManyLines = errors.New("too many lines")
+
+	// ErrUnsupportedFormat indicates the format of the manifest file cannot be
+	// determined from its extension.
+	ErrUnsupportedFormat = errors.New("unsupported format")
+)
+
+// ErrInUse indicates a line is already in use.
+type ErrInUse struct {
+	Consumer string
+}
+
+func (e ErrInUse) Error() string {
+	if len(e.Consumer) == 0 {
+		return "in use"
+	}
+	return fmt.Sprintf("in use by '%s'", e.Consumer)
+}
+
+// ErrInvalidGroup indicates a group in the manifest is invalid.
+type ErrInvalidGroup struct {
+	Group string
+	Err   error
+}
+
+func (e ErrInvalidGroup) Error() string {
+	return fmt.Sprintf("group '%s': %s", e.Group, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrInvalidGroup) Unwrap() error {
+	return e.Err
+}
+
+// ErrInvalidLine indicates a line in the manifest is invalid.
+type ErrInvalidLine struct {
+	Group string
+	Line  string
+	Err   error
+}
+
+func (e ErrInvalidLine) Error() string {
+	return fmt.Sprintf("group '%s' line '%s': %s", e.Group, e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrInvalidLine) Unwrap() error {
+	return e.Err
+}
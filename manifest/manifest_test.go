@@ -0,0 +1,257 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package manifest_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/config/blob/decoder/json"
+	"github.com/warthog618/config/blob/decoder/yaml"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/manifest"
+	"github.com/warthog618/gpiod/mockup"
+)
+
+const yamlManifest = `
+consumer: controller
+groups:
+  - name: leds
+    chip: gpiochip0
+    config: output
+    lines:
+      - line: 3
+        value: 1
+      - line: gpio-mockup-A-5
+        config: output,active-low
+  - name: buttons
+    config: input,pull-up
+    lines:
+      - line: gpio-mockup-A-1
+      - line: gpio-mockup-A-2
+`
+
+const jsonManifest = `{
+  "consumer": "controller",
+  "groups": [
+    {"name": "leds", "chip": "gpiochip0", "config": "output",
+      "lines": [{"line": "3", "value": 1}, {"line": "gpio-mockup-A-5", "config": "output,active-low"}]},
+    {"name": "buttons", "config": "input,pull-up",
+      "lines": [{"line": "gpio-mockup-A-1"}, {"line": "gpio-mockup-A-2"}]}
+  ]
+}`
+
+var xManifest = manifest.Manifest{
+	Consumer: "controller",
+	Groups: []manifest.Group{
+		{
+			Name:   "leds",
+			Chip:   "gpiochip0",
+			Config: "output",
+			Lines: []manifest.Line{
+				{Line: "3", Value: 1},
+				{Line: "gpio-mockup-A-5", Config: "output,active-low"},
+			},
+		},
+		{
+			Name:   "buttons",
+			Config: "input,pull-up",
+			Lines: []manifest.Line{
+				{Line: "gpio-mockup-A-1"},
+				{Line: "gpio-mockup-A-2"},
+			},
+		},
+	},
+}
+
+func TestDecode(t *testing.T) {
+	m, err := manifest.Decode([]byte(yamlManifest), yaml.NewDecoder())
+	require.Nil(t, err)
+	assert.Equal(t, xManifest, *m)
+
+	m, err = manifest.Decode([]byte(jsonManifest), json.NewDecoder())
+	require.Nil(t, err)
+	assert.Equal(t, xManifest, *m)
+
+	m, err = manifest.Decode([]byte("{ bad json"), json.NewDecoder())
+	assert.NotNil(t, err)
+	assert.Nil(t, m)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	patterns := []struct {
+		name string
+		data string
+	}{
+		{"manifest.yaml", yamlManifest},
+		{"manifest.YML", yamlManifest},
+		{"manifest.json", jsonManifest},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			filename := filepath.Join(dir, p.name)
+			err := os.WriteFile(filename, []byte(p.data), 0644)
+			require.Nil(t, err)
+			m, err := manifest.Load(filename)
+			require.Nil(t, err)
+			assert.Equal(t, xManifest, *m)
+		}
+		t.Run(p.name, tf)
+	}
+
+	m, err := manifest.Load(filepath.Join(dir, "manifest.toml"))
+	assert.Equal(t, manifest.ErrUnsupportedFormat, err)
+	assert.Nil(t, m)
+
+	m, err = manifest.Load(filepath.Join(dir, "missing.yaml"))
+	assert.NotNil(t, err)
+	assert.Nil(t, m)
+}
+
+func TestValidate(t *testing.T) {
+	mock, err := mockup.New([]int{8}, true)
+	require.Nil(t, err)
+	defer mock.Close()
+	c, err := mock.Chip(0)
+	require.Nil(t, err)
+
+	m := xManifest
+	m.Groups = append([]manifest.Group(nil), xManifest.Groups...)
+	m.Groups[0].Chip = c.Name
+	assert.Nil(t, m.Validate())
+
+	group := func(name string, config string, lines ...string) manifest.Group {
+		g := manifest.Group{Name: name, Chip: c.Name, Config: config}
+		for _, l := range lines {
+			g.Lines = append(g.Lines, manifest.Line{Line: l})
+		}
+		return g
+	}
+	patterns := []struct {
+		name   string
+		groups []manifest.Group
+		err    error
+	}{
+		{"no name",
+			[]manifest.Group{group("", "", "1")},
+			manifest.ErrInvalidGroup{Group: "", Err: manifest.ErrNoName}},
+		{"duplicate group",
+			[]manifest.Group{group("a", "", "1"), group("a", "", "2")},
+			manifest.ErrInvalidGroup{Group: "a", Err: manifest.ErrDuplicate}},
+		{"no lines",
+			[]manifest.Group{group("a", "")},
+			manifest.ErrInvalidGroup{Group: "a", Err: manifest.ErrNoLines}},
+		{"bad config",
+			[]manifest.Group{group("a", "sideways", "1")},
+			manifest.ErrInvalidGroup{Group: "a", Err: gpiod.ErrInvalidText{Type: "line config", Text: "sideways"}}},
+		{"out of range",
+			[]manifest.Group{group("a", "", "8")},
+			manifest.ErrInvalidLine{Group: "a", Line: "8", Err: gpiod.ErrInvalidOffset}},
+		{"unknown name",
+			[]manifest.Group{group("a", "", "nonesuch")},
+			manifest.ErrInvalidLine{Group: "a", Line: "nonesuch", Err: manifest.ErrNotFound}},
+		{"offset without chip",
+			[]manifest.Group{{Name: "a", Lines: []manifest.Line{{Line: "1"}}}},
+			manifest.ErrInvalidLine{Group: "a", Line: "1", Err: manifest.ErrNotFound}},
+		{"duplicate line",
+			[]manifest.Group{group("a", "", "1", "gpio-mockup-A-1")},
+			manifest.ErrInvalidLine{Group: "a", Line: "gpio-mockup-A-1", Err: manifest.ErrDuplicate}},
+		{"duplicate line across groups",
+			[]manifest.Group{group("a", "", "1"), group("b", "", "gpio-mockup-A-1")},
+			manifest.ErrInvalidLine{Group: "b", Line: "gpio-mockup-A-1", Err: manifest.ErrDuplicate}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			m := manifest.Manifest{Groups: p.groups}
+			err := m.Validate()
+			assert.Equal(t, p.err, err)
+		}
+		t.Run(p.name, tf)
+	}
+
+	// overflow - 8 debounce periods and 3 sets of flags
+	g := group("a", "input")
+	biases := []string{"pull-up", "pull-down", "bias-disabled"}
+	for o := 0; o < 8; o++ {
+		g.Lines = append(g.Lines, manifest.Line{
+			Line:   fmt.Sprintf("%d", o),
+			Config: fmt.Sprintf("input,%s,debounce-period=%dms", biases[o%3], o+1),
+		})
+	}
+	m = manifest.Manifest{Groups: []manifest.Group{g, group("b", "", "gpio-mockup-A-1")}}
+	err = m.Validate()
+	assert.True(t, errors.Is(err, gpiod.ErrConfigOverflow))
+
+	// in use
+	l, err := gpiod.RequestLine(c.Name, 4, gpiod.WithConsumer("squatter"))
+	require.Nil(t, err)
+	m = manifest.Manifest{Groups: []manifest.Group{group("a", "", "4")}}
+	err = m.Validate()
+	assert.Equal(t, manifest.ErrInvalidLine{Group: "a", Line: "4", Err: manifest.ErrInUse{Consumer: "squatter"}}, err)
+	l.Close()
+	assert.Nil(t, m.Validate())
+}
+
+func TestRequest(t *testing.T) {
+	mock, err := mockup.New([]int{8}, true)
+	require.Nil(t, err)
+	defer mock.Close()
+	c, err := mock.Chip(0)
+	require.Nil(t, err)
+
+	m := xManifest
+	m.Groups = append([]manifest.Group(nil), xManifest.Groups...)
+	m.Groups[0].Chip = c.Name
+	h, err := m.Request(
+		manifest.WithGroupOptions("buttons", gpiod.WithConsumer("buttons")))
+	require.Nil(t, err)
+	require.NotNil(t, h)
+	assert.Equal(t, 2, len(h))
+
+	leds := h["leds"]
+	require.NotNil(t, leds)
+	assert.Equal(t, []int{3, 5}, leds.Offsets())
+	v, err := c.Value(3)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	v, err = c.Value(5)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v) // active-low with logical value 0
+	li, err := leds.Info()
+	require.Nil(t, err)
+	assert.Equal(t, "controller", li[0].Consumer)
+	assert.True(t, li[1].Config.ActiveLow)
+
+	buttons := h["buttons"]
+	require.NotNil(t, buttons)
+	assert.Equal(t, []int{1, 2}, buttons.Offsets())
+	li, err = buttons.Info()
+	require.Nil(t, err)
+	assert.Equal(t, "buttons", li[0].Consumer)
+	assert.Equal(t, gpiod.LineDirectionInput, li[0].Config.Direction)
+
+	// already requested
+	h2, err := m.Request()
+	assert.Equal(t, manifest.ErrInvalidLine{Group: "leds", Line: "3", Err: manifest.ErrInUse{Consumer: "controller"}}, err)
+	assert.Nil(t, h2)
+
+	h.Close()
+	assert.Empty(t, h)
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+	info, err := chip.LineInfo(3)
+	assert.Nil(t, err)
+	assert.False(t, info.Used)
+}
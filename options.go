@@ -116,9 +116,25 @@ func (lca lineConfigAttributes) append(attr uapi.LineAttribute, mask uapi.LineBi
 }
 
 func (lco lineConfigOptions) toULineConfig() (ulc uapi.LineConfig, err error) {
+	defFlags, cfgAttrs := lco.toConfigAttributes()
+	if len(cfgAttrs) > len(ulc.Attrs) {
+		err = ErrConfigOverflow
+		return
+	}
+
+	ulc.Flags = defFlags
+	ulc.NumAttrs = uint32(len(cfgAttrs))
+	copy(ulc.Attrs[:], cfgAttrs)
+	return
+}
 
+// toConfigAttributes returns the default flags and the set of attributes
+// required to represent the configuration.
+//
+// The set of attributes is not limited to the number supported by the uAPI.
+func (lco lineConfigOptions) toConfigAttributes() (defFlags uapi.LineFlagV2, cfgAttrs lineConfigAttributes) {
 	mask := uapi.NewLineBitMask(len(lco.offsets))
-	cfgAttrs := lineConfigAttributes{
+	cfgAttrs = lineConfigAttributes{
 		// first cfg slot reserved for default flags
 		uapi.LineConfigAttribute{Attr: uapi.LineFlagV2(0).Encode(), Mask: mask},
 	}
@@ -152,7 +168,6 @@ func (lco lineConfigOptions) toULineConfig() (ulc uapi.LineConfig, err error) {
 			outputMask &^= mask
 		}
 	}
-	var defFlags uapi.LineFlagV2
 	defFlags.Decode(cfgAttrs[0].Attr)
 	// replace default flags in slot 0 with outputValues
 	cfgAttrs[0].Attr = lco.outputValues().Encode()
@@ -166,15 +181,6 @@ func (lco lineConfigOptions) toULineConfig() (ulc uapi.LineConfig, err error) {
 			cfgAttrs = append(cfgAttrs, attr)
 		}
 	}
-
-	if len(cfgAttrs) > 10 {
-		err = ErrConfigOverflow
-		return
-	}
-
-	ulc.Flags = defFlags
-	ulc.NumAttrs = uint32(len(cfgAttrs))
-	copy(ulc.Attrs[:], cfgAttrs)
 	return
 }
 
@@ -263,7 +269,11 @@ func (o OutputOption) applyLineConfigOption(lco *lineConfigOptions) {
 func (o OutputOption) applySubsetLineConfigOption(offsets []int, lco *lineConfigOptions) {
 	for idx, offset := range offsets {
 		o.applyLineConfig(lco.lineConfig(offset))
-		lco.values[offset] = o[idx]
+		value := 0
+		if idx < len(o) {
+			value = o[idx]
+		}
+		lco.values[offset] = value
 	}
 }
 
@@ -652,3 +662,50 @@ func (o EventBufferSizeOption) applyLineReqOption(lro *lineReqOptions) {
 func WithEventBufferSize(size int) EventBufferSizeOption {
 	return EventBufferSizeOption(size)
 }
+
+func (o LineDirection) applyLineConfig(lc *LineConfig) {
+	switch o {
+	case LineDirectionInput:
+		InputOption(0).applyLineConfig(lc)
+	case LineDirectionOutput:
+		OutputOption(nil).applyLineConfig(lc)
+	default:
+		lc.Direction = LineDirectionUnknown
+	}
+}
+
+func (o LineDirection) applyChipOption(c *ChipOptions) {
+	o.applyLineConfig(&c.config)
+}
+
+func (o LineDirection) applyLineReqOption(lro *lineReqOptions) {
+	o.applyLineConfig(&lro.defCfg)
+}
+
+func (o LineDirection) applyLineConfigOption(lco *lineConfigOptions) {
+	o.applyLineConfig(&lco.defCfg)
+}
+
+func (o LineDirection) applySubsetLineConfigOption(offsets []int, lco *lineConfigOptions) {
+	for _, offset := range offsets {
+		o.applyLineConfig(lco.lineConfig(offset))
+	}
+}
+
+func (o LineConfig) applyChipOption(c *ChipOptions) {
+	c.config = o
+}
+
+func (o LineConfig) applyLineReqOption(lro *lineReqOptions) {
+	lro.defCfg = o
+}
+
+func (o LineConfig) applyLineConfigOption(lco *lineConfigOptions) {
+	lco.defCfg = o
+}
+
+func (o LineConfig) applySubsetLineConfigOption(offsets []int, lco *lineConfigOptions) {
+	for _, offset := range offsets {
+		*lco.lineConfig(offset) = o
+	}
+}
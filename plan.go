@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiod
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// RequestPlan describes how a line request would be mapped to the kernel uAPI
+// v2.
+type RequestPlan struct {
+	// The offsets of the requested lines.
+	Offsets []int
+
+	// The effective configuration of each line, in the same order as Offsets.
+	Config []LineConfig
+
+	// The initial values of the output lines, keyed by offset.
+	Values map[int]int
+
+	// The default flags applied to lines not covered by a flags attribute.
+	Flags uapi.LineFlagV2
+
+	// The attributes required to represent the configuration.
+	//
+	// These are in the order they would be passed to the kernel, so any
+	// beyond the uAPI limit are those that caused the overflow.
+	Attributes []PlanAttribute
+
+	// The attributes that could not be mapped to the uAPI.
+	//
+	// This is empty unless the configuration overflows.
+	Overflow []PlanAttribute
+
+	// The configuration as it would be passed to the kernel.
+	//
+	// This is only populated if the configuration does not overflow.
+	UapiConfig uapi.LineConfig
+}
+
+// PlanAttribute is a uAPI configuration attribute and the lines it applies to.
+type PlanAttribute struct {
+	uapi.LineConfigAttribute
+
+	// The offsets of the lines identified by the attribute Mask.
+	Offsets []int
+}
+
+// PlanRequest determines how a request for the lines, with the given options,
+// would be mapped to the kernel uAPI v2, without requesting the lines.
+//
+// This is intended for validating configurations, and does not access any
+// chip, so it does not check the offsets exist, nor does it apply any default
+// options that would be provided by a Chip.
+//
+// If the configuration is too complex to be mapped to the uAPI then the plan
+// is returned with the Overflow populated, along with an ErrPlanOverflow, which
+// wraps ErrConfigOverflow.
+func PlanRequest(offsets []int, options ...LineReqOption) (RequestPlan, error) {
+	if len(offsets) == 0 || len(offsets) > uapi.LinesMax {
+		return RequestPlan{}, unix.EINVAL
+	}
+	for _, o := range offsets {
+		if o < 0 {
+			return RequestPlan{}, ErrInvalidOffset
+		}
+	}
+	offsets = append([]int(nil), offsets...)
+	lro := lineReqOptions{
+		lineConfigOptions: lineConfigOptions{
+			offsets: offsets,
+			values:  map[int]int{},
+		},
+	}
+	for _, option := range options {
+		option.applyLineReqOption(&lro)
+	}
+	rp := RequestPlan{
+		Offsets: offsets,
+		Config:  make([]LineConfig, len(offsets)),
+		Values:  map[int]int{},
+	}
+	for idx, offset := range offsets {
+		cfg := lro.defCfg
+		if lc := lro.lineCfg[offset]; lc != nil {
+			cfg = *lc
+		}
+		rp.Config[idx] = cfg
+		if cfg.Direction == LineDirectionOutput {
+			rp.Values[offset] = lro.values[offset]
+		}
+	}
+	defFlags, cfgAttrs := lro.toConfigAttributes()
+	rp.Flags = defFlags
+	for _, attr := range cfgAttrs {
+		pa := PlanAttribute{LineConfigAttribute: attr}
+		for idx, offset := range offsets {
+			if attr.Mask&(uapi.LineBitmap(1)<<uint(idx)) != 0 {
+				pa.Offsets = append(pa.Offsets, offset)
+			}
+		}
+		rp.Attributes = append(rp.Attributes, pa)
+	}
+	var err error
+	if rp.UapiConfig, err = lro.toULineConfig(); err != nil {
+		rp.Overflow = rp.Attributes[len(rp.UapiConfig.Attrs):]
+		oe := ErrPlanOverflow{}
+		for _, pa := range rp.Overflow {
+			oe.Offsets = append(oe.Offsets, pa.Offsets)
+		}
+		err = oe
+	}
+	return rp, err
+}
+
+// ErrPlanOverflow indicates a planned request is too complicated to be mapped
+// to the kernel uAPI, and identifies the lines that could not be mapped.
+type ErrPlanOverflow struct {
+	// The offsets of the lines of each overflowing attribute, in the same
+	// order as the RequestPlan Overflow.
+	Offsets [][]int
+}
+
+func (e ErrPlanOverflow) Error() string {
+	attrs := make([]string, len(e.Offsets))
+	for i, offsets := range e.Offsets {
+		attrs[i] = fmt.Sprint(offsets)
+	}
+	return fmt.Sprintf("%s: no attribute for lines %s", ErrConfigOverflow, strings.Join(attrs, ", "))
+}
+
+// Unwrap returns ErrConfigOverflow.
+func (e ErrPlanOverflow) Unwrap() error {
+	return ErrConfigOverflow
+}
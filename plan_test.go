@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiod_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/uapi"
+	"golang.org/x/sys/unix"
+)
+
+func TestPlanRequest(t *testing.T) {
+	// no lines
+	_, err := gpiod.PlanRequest(nil)
+	assert.Equal(t, unix.EINVAL, err)
+
+	// negative
+	_, err = gpiod.PlanRequest([]int{1, -1})
+	assert.Equal(t, gpiod.ErrInvalidOffset, err)
+
+	// defaults
+	rp, err := gpiod.PlanRequest([]int{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, rp.Offsets)
+	assert.Equal(t, []gpiod.LineConfig{{}, {}}, rp.Config)
+	assert.Empty(t, rp.Values)
+	assert.Empty(t, rp.Attributes)
+	assert.Empty(t, rp.Overflow)
+	assert.Equal(t, uapi.LineConfig{}, rp.UapiConfig)
+
+	// outputs with subset
+	rp, err = gpiod.PlanRequest([]int{1, 2, 3},
+		gpiod.AsOutput(1, 0, 1),
+		gpiod.WithLines([]int{2}, gpiod.AsActiveLow))
+	assert.Nil(t, err)
+	xcfg := gpiod.LineConfig{Direction: gpiod.LineDirectionOutput}
+	xalcfg := xcfg
+	xalcfg.ActiveLow = true
+	assert.Equal(t, []gpiod.LineConfig{xcfg, xalcfg, xcfg}, rp.Config)
+	assert.Equal(t, map[int]int{1: 1, 2: 0, 3: 1}, rp.Values)
+	assert.Equal(t, uapi.LineFlagV2Output, rp.Flags)
+	require.Equal(t, 2, len(rp.Attributes))
+	assert.Equal(t, uapi.LineAttributeIDOutputValues, rp.Attributes[0].Attr.ID)
+	assert.Equal(t, []int{1, 2, 3}, rp.Attributes[0].Offsets)
+	assert.Equal(t, uapi.LineAttributeIDFlags, rp.Attributes[1].Attr.ID)
+	assert.Equal(t, []int{2}, rp.Attributes[1].Offsets)
+	assert.Empty(t, rp.Overflow)
+	assert.Equal(t, uint32(2), rp.UapiConfig.NumAttrs)
+	assert.Equal(t, rp.Attributes[1].LineConfigAttribute, rp.UapiConfig.Attrs[1])
+
+	// overflow
+	offsets := []int(nil)
+	opts := []gpiod.LineReqOption{gpiod.AsInput}
+	for o := 0; o < 12; o++ {
+		offsets = append(offsets, o)
+		opts = append(opts, gpiod.WithLines([]int{o},
+			gpiod.WithDebounce(time.Duration(o+1)*time.Millisecond)))
+	}
+	rp, err = gpiod.PlanRequest(offsets, opts...)
+	assert.Equal(t, gpiod.ErrPlanOverflow{Offsets: [][]int{{10}, {11}}}, err)
+	assert.True(t, errors.Is(err, gpiod.ErrConfigOverflow))
+	assert.Equal(t,
+		"configuration too complex to map to kernel uAPI: no attribute for lines [10], [11]",
+		err.Error())
+	assert.Equal(t, 12, len(rp.Attributes))
+	require.Equal(t, 2, len(rp.Overflow))
+	assert.Equal(t, []int{10}, rp.Overflow[0].Offsets)
+	assert.Equal(t, []int{11}, rp.Overflow[1].Offsets)
+	assert.Equal(t, uapi.LineConfig{}, rp.UapiConfig)
+}
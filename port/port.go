@@ -0,0 +1,360 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package port provides a parallel port abstraction over requested lines.
+//
+// A Port treats a collection of lines as the bits of a word, so parallel
+// interfaces, such as character LCDs, latches and DACs, can be driven a word
+// at a time rather than a line at a time.
+//
+// The lines of a port may span several chips, though writes are then not
+// atomic across the chips, so a strobe line should be used to latch the data
+// into the device.
+package port
+
+import (
+	"errors"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// Port represents a parallel port formed from a set of lines.
+//
+// The Port does not take ownership of the lines, which remain the
+// responsibility of the caller to close.  The Port itself should be closed
+// once no longer required.
+type Port struct {
+	// segments of the port, from least to most significant.
+	segs []segment
+
+	// total number of lines in the port
+	width int
+
+	// the first line is the most significant bit
+	msbFirst bool
+
+	strobe *gpiod.Line
+
+	// time between setting data and asserting the strobe
+	setup time.Duration
+
+	// time the strobe is asserted
+	pulse time.Duration
+
+	// time after releasing the strobe before the data may change
+	hold time.Duration
+
+	// times the strobe, if any
+	delay *timing.Delay
+
+	// options for the delay
+	timingOptions []timing.Option
+
+	// mu covers the state below, and serialises access to the lines
+	mu sync.Mutex
+
+	// the last word written to the port
+	word uint64
+
+	dir gpiod.LineDirection
+
+	closed bool
+}
+
+// segment is a part of the port contained in a single request.
+type segment struct {
+	lines *gpiod.Lines
+	// the position of the first line in the port
+	shift int
+	// the number of lines in the segment
+	width int
+}
+
+// New creates a Port from the lines.
+//
+// The lines form the least significant bits of the port, with the first line
+// being bit 0, unless WithMSBFirst is specified.
+//
+// The direction of the port is taken to be output, and should be set using
+// SetDirection if the lines were requested as inputs.
+func New(lines *gpiod.Lines, options ...Option) (*Port, error) {
+	if lines == nil {
+		return nil, ErrNoLines
+	}
+	p := Port{
+		segs: []segment{{lines: lines}},
+		dir:  gpiod.LineDirectionOutput,
+	}
+	for _, option := range options {
+		option(&p)
+	}
+	for i, s := range p.segs {
+		if s.lines == nil {
+			return nil, ErrNoLines
+		}
+		p.segs[i].shift = p.width
+		p.segs[i].width = len(s.lines.Offsets())
+		p.width += p.segs[i].width
+	}
+	if p.width > 64 {
+		return nil, ErrTooWide
+	}
+	if p.strobe != nil {
+		var err error
+		p.delay, err = timing.NewDelay(p.timingOptions...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+// Close releases the resources held by the Port.
+//
+// The lines and strobe are not released, but the Port may no longer be used to
+// access them.
+func (p *Port) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	p.closed = true
+	if p.delay != nil {
+		return p.delay.Close()
+	}
+	return nil
+}
+
+// Width returns the number of bits in the port.
+func (p *Port) Width() int {
+	return p.width
+}
+
+// Direction returns the current direction of the port.
+func (p *Port) Direction() gpiod.LineDirection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dir
+}
+
+// SetDirection switches the direction of the lines in the port.
+//
+// When switching to output the lines are driven with the last word written to
+// the port.
+//
+// If the switch fails then any segments already switched are returned to the
+// previous direction, as far as possible.
+//
+// Requires Linux v5.5 or later.
+func (p *Port) SetDirection(dir gpiod.LineDirection) error {
+	if dir != gpiod.LineDirectionInput && dir != gpiod.LineDirectionOutput {
+		return ErrInvalidDirection
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	for i, s := range p.segs {
+		if err := p.reconfigure(s, dir); err != nil {
+			for _, s := range p.segs[:i] {
+				p.reconfigure(s, p.dir)
+			}
+			return err
+		}
+	}
+	p.dir = dir
+	return nil
+}
+
+// reconfigure sets the direction of the lines in the segment.
+//
+// Assumes p is locked.
+func (p *Port) reconfigure(s segment, dir gpiod.LineDirection) error {
+	if dir == gpiod.LineDirectionInput {
+		return s.lines.Reconfigure(gpiod.AsInput)
+	}
+	return s.lines.Reconfigure(gpiod.AsOutput(s.values(p.toLines(p.word))...))
+}
+
+// WriteWord writes the word to the port.
+//
+// Bits beyond the width of the port are ignored.
+//
+// Only valid when the port is an output.
+//
+// If the port has a strobe then the strobe is pulsed after the data has been
+// written.
+func (p *Port) WriteWord(w uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	lw := p.toLines(w)
+	for _, s := range p.segs {
+		mask := uint64(1)<<uint(s.width) - 1
+		if err := s.lines.SetValuesMask(mask, lw>>uint(s.shift)); err != nil {
+			return err
+		}
+	}
+	p.word = w & p.mask()
+	if p.strobe == nil {
+		return nil
+	}
+	p.wait(p.setup)
+	if err := p.strobe.SetValue(1); err != nil {
+		return err
+	}
+	p.wait(p.pulse)
+	err := p.strobe.SetValue(0)
+	p.wait(p.hold)
+	return err
+}
+
+// ReadWord reads a word from the port.
+//
+// If the port has a strobe then the data is read after the strobe has been
+// asserted for the pulse period.
+func (p *Port) ReadWord() (uint64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, ErrClosed
+	}
+	if p.strobe != nil {
+		if err := p.strobe.SetValue(1); err != nil {
+			return 0, err
+		}
+		p.wait(p.pulse)
+	}
+	var lw uint64
+	for _, s := range p.segs {
+		mask := uint64(1)<<uint(s.width) - 1
+		v, err := s.lines.ValuesMask(mask)
+		if err != nil {
+			return 0, err
+		}
+		lw |= v << uint(s.shift)
+	}
+	if p.strobe != nil {
+		if err := p.strobe.SetValue(0); err != nil {
+			return 0, err
+		}
+		p.wait(p.hold)
+	}
+	return p.toLines(lw), nil
+}
+
+func (p *Port) mask() uint64 {
+	if p.width == 64 {
+		return ^uint64(0)
+	}
+	return uint64(1)<<uint(p.width) - 1
+}
+
+// toLines maps between words and the bits of the lines, in line order.
+//
+// The mapping is its own inverse.
+func (p *Port) toLines(w uint64) uint64 {
+	w &= p.mask()
+	if p.msbFirst {
+		return bits.Reverse64(w) >> uint(64-p.width)
+	}
+	return w
+}
+
+// values returns the values of the lines in the segment from the bits of the
+// lines in the port.
+func (s segment) values(lw uint64) []int {
+	vv := make([]int, s.width)
+	for i := range vv {
+		vv[i] = int(lw>>uint(s.shift+i)) & 1
+	}
+	return vv
+}
+
+// wait delays for the period of a strobe timing.
+//
+// Assumes p is locked.
+func (p *Port) wait(period time.Duration) {
+	if period > 0 && p.delay != nil {
+		p.delay.Wait(period)
+	}
+}
+
+// Option specifies a construction option for the Port.
+type Option func(*Port)
+
+// WithMSBFirst specifies that the first line of the port is the most
+// significant bit of the word.
+func WithMSBFirst() Option {
+	return func(p *Port) {
+		p.msbFirst = true
+	}
+}
+
+// WithUpperLines adds the lines, which may be from a different chip, as the
+// next most significant bits of the port.
+func WithUpperLines(lines *gpiod.Lines) Option {
+	return func(p *Port) {
+		p.segs = append(p.segs, segment{lines: lines})
+	}
+}
+
+// WithStrobe specifies a strobe, or latch, line that is pulsed active after
+// each write and around each read.
+//
+// The strobe should be requested as an output in its inactive state.  The
+// polarity of the strobe is determined by the active level of the line.
+func WithStrobe(strobe *gpiod.Line, pulse time.Duration) Option {
+	return func(p *Port) {
+		p.strobe = strobe
+		p.pulse = pulse
+	}
+}
+
+// WithStrobeTiming sets the setup and hold times around the strobe pulse.
+//
+// The setup is the time from the data being written until the strobe is
+// asserted.  The hold is the time after the strobe is released before the
+// port may be accessed again.
+func WithStrobeTiming(setup, hold time.Duration) Option {
+	return func(p *Port) {
+		p.setup = setup
+		p.hold = hold
+	}
+}
+
+// WithTiming sets the options for the Delay used to time the strobe.
+//
+// The Delay is only created if the Port has a strobe line.
+func WithTiming(options ...timing.Option) Option {
+	return func(p *Port) {
+		p.timingOptions = append(p.timingOptions, options...)
+	}
+}
+
+var (
+	// ErrClosed indicates the port has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrInvalidDirection indicates the port direction is neither input nor
+	// output.
+	ErrInvalidDirection = errors.New("invalid direction")
+
+	// ErrNoLines indicates the port was not provided any lines.
+	ErrNoLines = errors.New("no lines")
+
+	// ErrTooWide indicates the port contains more than 64 lines.
+	ErrTooWide = errors.New("port too wide")
+)
@@ -0,0 +1,257 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package port_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/mockup"
+	"github.com/warthog618/gpiod/port"
+	"github.com/warthog618/gpiod/timing"
+)
+
+var (
+	lo = []int{0, 1, 2, 3}
+	hi = []int{4, 5, 6, 7}
+)
+
+func TestNew(t *testing.T) {
+	p, err := port.New(nil)
+	assert.Equal(t, port.ErrNoLines, err)
+	assert.Nil(t, p)
+
+	m, c := newMockup(t, 8)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, lo, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	p, err = port.New(ll, port.WithUpperLines(nil))
+	assert.Equal(t, port.ErrNoLines, err)
+	assert.Nil(t, p)
+
+	p, err = port.New(ll)
+	assert.Nil(t, err)
+	require.NotNil(t, p)
+	assert.Equal(t, 4, p.Width())
+	assert.Equal(t, gpiod.LineDirectionOutput, p.Direction())
+}
+
+func TestWriteWord(t *testing.T) {
+	m, c := newMockup(t, 8, 8)
+	defer m.Close()
+	c1, err := m.Chip(1)
+	require.Nil(t, err)
+	ll, err := gpiod.RequestLines(c.Name, lo, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+	lh, err := gpiod.RequestLines(c1.Name, lo, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer lh.Close()
+
+	patterns := []struct {
+		name    string
+		options []port.Option
+		word    uint64
+		// values of chip 0 lines then chip 1 lines
+		values []int
+	}{
+		{"lsb", nil, 0x0b, []int{1, 1, 0, 1}},
+		{"msb", []port.Option{port.WithMSBFirst()}, 0x0b, []int{1, 0, 1, 1}},
+		{"truncated", nil, 0x1f0, []int{0, 0, 0, 0}},
+		{"cross chip",
+			[]port.Option{port.WithUpperLines(lh)},
+			0xa5,
+			[]int{1, 0, 1, 0, 0, 1, 0, 1}},
+		{"cross chip msb",
+			[]port.Option{port.WithUpperLines(lh), port.WithMSBFirst()},
+			0xa5,
+			[]int{1, 0, 1, 0, 0, 1, 0, 1}},
+		{"cross chip msb asymmetric",
+			[]port.Option{port.WithUpperLines(lh), port.WithMSBFirst()},
+			0xc1,
+			[]int{1, 1, 0, 0, 0, 0, 0, 1}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			pt, err := port.New(ll, p.options...)
+			require.Nil(t, err)
+			require.NotNil(t, pt)
+			err = pt.WriteWord(p.word)
+			assert.Nil(t, err)
+			for i, o := range lo {
+				v, err := c.Value(o)
+				assert.Nil(t, err)
+				assert.Equal(t, p.values[i], v, i)
+			}
+			if len(p.values) > len(lo) {
+				for i, o := range lo {
+					v, err := c1.Value(o)
+					assert.Nil(t, err)
+					assert.Equal(t, p.values[len(lo)+i], v, len(lo)+i)
+				}
+			}
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestReadWord(t *testing.T) {
+	m, c := newMockup(t, 8)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, lo, gpiod.AsInput)
+	require.Nil(t, err)
+	defer ll.Close()
+
+	for i, v := range []int{1, 1, 0, 1} {
+		c.SetValue(lo[i], v)
+	}
+	p, err := port.New(ll)
+	require.Nil(t, err)
+	w, err := p.ReadWord()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0x0b), w)
+
+	p, err = port.New(ll, port.WithMSBFirst())
+	require.Nil(t, err)
+	w, err = p.ReadWord()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0x0d), w)
+
+	// not an output
+	err = p.WriteWord(0)
+	assert.Equal(t, gpiod.ErrPermissionDenied, err)
+}
+
+func TestStrobe(t *testing.T) {
+	m, c := newMockup(t, 8)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, lo, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+	strobe, err := gpiod.RequestLine(c.Name, hi[0], gpiod.AsOutput(0))
+	require.Nil(t, err)
+	defer strobe.Close()
+
+	p, err := port.New(ll,
+		port.WithStrobe(strobe, time.Microsecond),
+		port.WithStrobeTiming(time.Microsecond, time.Microsecond),
+		port.WithTiming(timing.WithMode(timing.BusyWait)))
+	require.Nil(t, err)
+	require.NotNil(t, p)
+	defer p.Close()
+	start := time.Now()
+	err = p.WriteWord(0x06)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 3*time.Microsecond)
+	v, err := c.Value(hi[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+	v, err = c.Value(lo[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	_, err = p.ReadWord()
+	assert.Nil(t, err)
+	v, err = c.Value(hi[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+
+	// closed strobe
+	strobe.Close()
+	err = p.WriteWord(0x01)
+	assert.Equal(t, gpiod.ErrClosed, err)
+	_, err = p.ReadWord()
+	assert.Equal(t, gpiod.ErrClosed, err)
+
+	// closed port
+	assert.Nil(t, p.Close())
+	assert.Equal(t, port.ErrClosed, p.Close())
+	err = p.WriteWord(0x01)
+	assert.Equal(t, port.ErrClosed, err)
+	_, err = p.ReadWord()
+	assert.Equal(t, port.ErrClosed, err)
+	err = p.SetDirection(gpiod.LineDirectionInput)
+	assert.Equal(t, port.ErrClosed, err)
+}
+
+func TestSetDirection(t *testing.T) {
+	requireKernel(t, mockup.Semver{5, 5})
+	m, c := newMockup(t, 8)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, lo, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	p, err := port.New(ll)
+	require.Nil(t, err)
+	err = p.WriteWord(0x09)
+	assert.Nil(t, err)
+
+	err = p.SetDirection(gpiod.LineDirectionInput)
+	assert.Nil(t, err)
+	assert.Equal(t, gpiod.LineDirectionInput, p.Direction())
+	for i, v := range []int{0, 1, 1, 0} {
+		c.SetValue(lo[i], v)
+	}
+	w, err := p.ReadWord()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0x06), w)
+
+	// restores the last written word
+	err = p.SetDirection(gpiod.LineDirectionOutput)
+	assert.Nil(t, err)
+	assert.Equal(t, gpiod.LineDirectionOutput, p.Direction())
+	for i, xv := range []int{1, 0, 0, 1} {
+		v, err := c.Value(lo[i])
+		assert.Nil(t, err)
+		assert.Equal(t, xv, v, i)
+	}
+
+	err = p.SetDirection(gpiod.LineDirectionUnknown)
+	assert.Equal(t, port.ErrInvalidDirection, err)
+	assert.Equal(t, gpiod.LineDirectionOutput, p.Direction())
+
+	// failure rolls back the segments already switched
+	lh, err := gpiod.RequestLines(c.Name, hi, gpiod.AsOutput())
+	require.Nil(t, err)
+	p, err = port.New(ll, port.WithUpperLines(lh))
+	require.Nil(t, err)
+	err = p.WriteWord(0x09)
+	assert.Nil(t, err)
+	lh.Close()
+	err = p.SetDirection(gpiod.LineDirectionInput)
+	assert.Equal(t, gpiod.ErrClosed, err)
+	assert.Equal(t, gpiod.LineDirectionOutput, p.Direction())
+	for i, xv := range []int{1, 0, 0, 1} {
+		c.SetValue(lo[i], 1-xv)
+		v, err := c.Value(lo[i])
+		assert.Nil(t, err)
+		assert.Equal(t, xv, v, i)
+	}
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
+
+func requireKernel(t *testing.T, min mockup.Semver) {
+	t.Helper()
+	if err := mockup.CheckKernelVersion(min); err != nil {
+		t.Skip(err)
+	}
+}
@@ -0,0 +1,428 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package pwm provides software PWM on requested lines.
+//
+// This is intended for low frequency uses, such as fan control, LED dimming
+// and hobby servos, on lines that are not connected to a hardware PWM
+// controller.  Where a hardware controller is available it should be used
+// instead, via the pwmchip package.
+//
+// All the channels of a PWM share a period, and are synchronised so that their
+// pulses start at the same time.
+package pwm
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// PWM drives software PWM on a set of lines.
+//
+// Each line is a channel, with channel n being the nth line in the Lines.
+//
+// The PWM does not take ownership of the lines, which remain the
+// responsibility of the caller to close, after closing the PWM.  The lines
+// should be requested as outputs.
+type PWM struct {
+	lines *gpiod.Lines
+
+	// the number of channels
+	n int
+
+	// the delay used to time the edges
+	delay *timing.Delay
+
+	// closed once the PWM loop exits
+	doneCh chan struct{}
+
+	// mu covers the state below
+	mu sync.Mutex
+
+	period time.Duration
+
+	// the duty cycle of each channel, from 0 to 1
+	duty []float64
+
+	// the range of servo pulse widths
+	servoMin time.Duration
+	servoMax time.Duration
+
+	// the timing of edges, relative to their ideal times
+	stats timing.Stats
+
+	// the error that stopped the PWM, if any
+	err error
+
+	closed bool
+
+	// options for the delay
+	timingOptions []timing.Option
+}
+
+// New creates a PWM on the lines and starts it running.
+//
+// The default frequency is 100Hz, and all channels default to a duty cycle of
+// 0, so the lines are held inactive until a duty cycle is set.
+func New(lines *gpiod.Lines, options ...Option) (*PWM, error) {
+	if lines == nil {
+		return nil, ErrNoLines
+	}
+	p := PWM{
+		lines:    lines,
+		n:        len(lines.Offsets()),
+		period:   10 * time.Millisecond,
+		servoMin: time.Millisecond,
+		servoMax: 2 * time.Millisecond,
+	}
+	p.duty = make([]float64, p.n)
+	for _, option := range options {
+		option(&p)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	d, err := timing.NewDelay(p.timingOptions...)
+	if err != nil {
+		return nil, err
+	}
+	p.delay = d
+	p.doneCh = make(chan struct{})
+	go p.run()
+	return &p, nil
+}
+
+// NewServo creates a PWM on the lines with the 50Hz frequency typical of hobby
+// servos.
+func NewServo(lines *gpiod.Lines, options ...Option) (*PWM, error) {
+	return New(lines, append([]Option{WithFrequency(50)}, options...)...)
+}
+
+// Close stops the PWM and sets all the lines inactive.
+//
+// The PWM stops at the end of the current period, so Close may block for up to
+// a period.
+func (p *PWM) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+	<-p.doneCh
+	p.delay.Close()
+	return p.lines.SetValuesMask(p.mask(), 0)
+}
+
+// Channels returns the number of channels.
+func (p *PWM) Channels() int {
+	return p.n
+}
+
+// Period returns the period of the PWM.
+func (p *PWM) Period() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.period
+}
+
+// SetPeriod sets the period of the PWM.
+//
+// The change takes effect at the start of the next period.  The duty cycles
+// of the channels are unaltered, so their pulse widths scale with the period.
+func (p *PWM) SetPeriod(period time.Duration) error {
+	if period <= 0 {
+		return ErrInvalidPeriod
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	p.period = period
+	return nil
+}
+
+// Frequency returns the frequency of the PWM, in Hz.
+func (p *PWM) Frequency() float64 {
+	return float64(time.Second) / float64(p.Period())
+}
+
+// SetFrequency sets the frequency of the PWM, in Hz.
+//
+// This is equivalent to SetPeriod with the reciprocal of the frequency.
+func (p *PWM) SetFrequency(hz float64) error {
+	if hz <= 0 {
+		return ErrInvalidPeriod
+	}
+	return p.SetPeriod(time.Duration(float64(time.Second) / hz))
+}
+
+// Duty returns the duty cycle of the channel, from 0 to 1.
+func (p *PWM) Duty(ch int) (float64, error) {
+	if ch < 0 || ch >= p.n {
+		return 0, ErrInvalidChannel
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.duty[ch], nil
+}
+
+// SetDuty sets the duty cycle of the channel, from 0 to 1.
+//
+// The change takes effect at the start of the next period.
+func (p *PWM) SetDuty(ch int, duty float64) error {
+	if ch < 0 || ch >= p.n {
+		return ErrInvalidChannel
+	}
+	if duty < 0 || duty > 1 {
+		return ErrInvalidDuty
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	p.duty[ch] = duty
+	return nil
+}
+
+// SetPulseWidth sets the duty cycle of the channel to produce a pulse of the
+// width at the current period.
+func (p *PWM) SetPulseWidth(ch int, width time.Duration) error {
+	if ch < 0 || ch >= p.n {
+		return ErrInvalidChannel
+	}
+	p.mu.Lock()
+	period := p.period
+	p.mu.Unlock()
+	if width < 0 || width > period {
+		return ErrInvalidDuty
+	}
+	return p.SetDuty(ch, float64(width)/float64(period))
+}
+
+// SetServo sets the pulse width of the channel to drive a servo to the
+// position.
+//
+// The position ranges from 0 to 1, and is mapped linearly onto the servo
+// pulse width range, which defaults to 1ms to 2ms.
+func (p *PWM) SetServo(ch int, position float64) error {
+	if position < 0 || position > 1 {
+		return ErrInvalidDuty
+	}
+	p.mu.Lock()
+	width := p.servoMin + time.Duration(position*float64(p.servoMax-p.servoMin))
+	p.mu.Unlock()
+	return p.SetPulseWidth(ch, width)
+}
+
+// Jitter returns the timing of the edges generated by the PWM relative to
+// their ideal times.
+//
+// The Requested and Achieved periods are measured from the preceding edge, so
+// the MeanError is the mean lateness of the edges, and MaxLate the worst case.
+func (p *PWM) Jitter() timing.Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// ResetJitter clears the recorded jitter.
+func (p *PWM) ResetJitter() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats = timing.Stats{}
+}
+
+// Err returns the error that stopped the PWM, if any.
+//
+// The PWM stops if it is unable to set the lines, such as if the lines are
+// closed.
+func (p *PWM) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// mask returns the mask of all the channels.
+func (p *PWM) mask() uint64 {
+	if p.n == 64 {
+		return ^uint64(0)
+	}
+	return uint64(1)<<uint(p.n) - 1
+}
+
+// run generates the pulses until the PWM is closed.
+//
+// All channels with a non-zero duty cycle are set active at the start of each
+// period, and each is set inactive once its pulse width has elapsed.
+func (p *PWM) run() {
+	defer close(p.doneCh)
+	widths := make([]time.Duration, p.n)
+	var period time.Duration
+	start := timing.Now()
+	last := start
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		period = p.period
+		for i, d := range p.duty {
+			widths[i] = time.Duration(d * float64(period))
+		}
+		p.mu.Unlock()
+
+		var active uint64
+		for i, w := range widths {
+			if w > 0 {
+				active |= 1 << uint(i)
+			}
+		}
+		if !p.edge(start, last, p.mask(), active) {
+			return
+		}
+		last = start
+		// the falling edges, in time order, with channels sharing a width
+		// falling together.
+		var prev time.Duration
+		for {
+			next := period
+			for _, w := range widths {
+				if w > prev && w < next {
+					next = w
+				}
+			}
+			if next == period {
+				break
+			}
+			var falling uint64
+			for i, w := range widths {
+				if w == next {
+					falling |= 1 << uint(i)
+				}
+			}
+			p.delay.Until(start + next)
+			if !p.edge(start+next, last, falling, 0) {
+				return
+			}
+			last = start + next
+			prev = next
+		}
+		start += period
+		p.delay.Until(start)
+		if now := timing.Now(); now-start > period {
+			// fallen more than a period behind, so resynchronise rather than
+			// trying to catch up.
+			start = now
+		}
+	}
+}
+
+// edge sets the lines identified by mask to bits, and records the lateness of
+// the edge relative to the deadline.
+//
+// Returns false if the PWM should stop.
+func (p *PWM) edge(deadline, last time.Duration, mask, bits uint64) bool {
+	err := p.lines.SetValuesMask(mask, bits)
+	now := timing.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.err = err
+		return false
+	}
+	p.stats.Add(last, deadline, now)
+	return true
+}
+
+// Option specifies a construction option for the PWM.
+type Option func(*PWM)
+
+// WithPeriod sets the initial period of the PWM.
+func WithPeriod(period time.Duration) Option {
+	return func(p *PWM) {
+		if period <= 0 {
+			p.err = ErrInvalidPeriod
+			return
+		}
+		p.period = period
+	}
+}
+
+// WithFrequency sets the initial frequency of the PWM, in Hz.
+func WithFrequency(hz float64) Option {
+	return func(p *PWM) {
+		if hz <= 0 {
+			p.err = ErrInvalidPeriod
+			return
+		}
+		p.period = time.Duration(float64(time.Second) / hz)
+	}
+}
+
+// WithDuty sets the initial duty cycle of a channel.
+func WithDuty(ch int, duty float64) Option {
+	return func(p *PWM) {
+		if ch < 0 || ch >= p.n {
+			p.err = ErrInvalidChannel
+			return
+		}
+		if duty < 0 || duty > 1 {
+			p.err = ErrInvalidDuty
+			return
+		}
+		p.duty[ch] = duty
+	}
+}
+
+// WithServoRange sets the range of pulse widths used by SetServo.
+func WithServoRange(min, max time.Duration) Option {
+	return func(p *PWM) {
+		if min < 0 || max < min {
+			p.err = ErrInvalidDuty
+			return
+		}
+		p.servoMin = min
+		p.servoMax = max
+	}
+}
+
+// WithTiming sets the options for the Delay used to time the edges.
+//
+// timing.WithMode(timing.Sleep) reduces the CPU load, at the cost of jitter in
+// the edges.
+func WithTiming(options ...timing.Option) Option {
+	return func(p *PWM) {
+		p.timingOptions = append(p.timingOptions, options...)
+	}
+}
+
+var (
+	// ErrClosed indicates the PWM has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrInvalidChannel indicates the channel is not in the PWM.
+	ErrInvalidChannel = errors.New("invalid channel")
+
+	// ErrInvalidDuty indicates the duty cycle, pulse width, or servo position
+	// is out of range.
+	ErrInvalidDuty = errors.New("invalid duty")
+
+	// ErrInvalidPeriod indicates the period or frequency is not positive.
+	ErrInvalidPeriod = errors.New("invalid period")
+
+	// ErrNoLines indicates the PWM was not provided any lines.
+	ErrNoLines = errors.New("no lines")
+)
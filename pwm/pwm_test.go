@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package pwm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/mockup"
+	"github.com/warthog618/gpiod/pwm"
+)
+
+var offsets = []int{1, 2, 3}
+
+func TestNew(t *testing.T) {
+	p, err := pwm.New(nil)
+	assert.Equal(t, pwm.ErrNoLines, err)
+	assert.Nil(t, p)
+
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	patterns := []struct {
+		name    string
+		options []pwm.Option
+		err     error
+	}{
+		{"zero period", []pwm.Option{pwm.WithPeriod(0)}, pwm.ErrInvalidPeriod},
+		{"zero frequency", []pwm.Option{pwm.WithFrequency(0)}, pwm.ErrInvalidPeriod},
+		{"channel", []pwm.Option{pwm.WithDuty(3, 0.5)}, pwm.ErrInvalidChannel},
+		{"duty", []pwm.Option{pwm.WithDuty(1, 1.5)}, pwm.ErrInvalidDuty},
+		{"servo range",
+			[]pwm.Option{pwm.WithServoRange(2*time.Millisecond, time.Millisecond)},
+			pwm.ErrInvalidDuty},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			pw, err := pwm.New(ll, p.options...)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, pw)
+		}
+		t.Run(p.name, tf)
+	}
+
+	p, err = pwm.New(ll, pwm.WithFrequency(200), pwm.WithDuty(2, 0.25))
+	assert.Nil(t, err)
+	require.NotNil(t, p)
+	assert.Equal(t, 3, p.Channels())
+	assert.Equal(t, 5*time.Millisecond, p.Period())
+	assert.Equal(t, 200.0, p.Frequency())
+	d, err := p.Duty(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.25, d)
+	assert.Nil(t, p.Close())
+	assert.Equal(t, pwm.ErrClosed, p.Close())
+	assert.Equal(t, pwm.ErrClosed, p.SetDuty(0, 0.5))
+	assert.Equal(t, pwm.ErrClosed, p.SetPeriod(time.Millisecond))
+}
+
+func TestDuty(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	p, err := pwm.New(ll, pwm.WithPeriod(time.Millisecond))
+	require.Nil(t, err)
+	require.NotNil(t, p)
+	defer p.Close()
+
+	assert.Equal(t, pwm.ErrInvalidChannel, p.SetDuty(-1, 0.5))
+	assert.Equal(t, pwm.ErrInvalidChannel, p.SetDuty(3, 0.5))
+	assert.Equal(t, pwm.ErrInvalidDuty, p.SetDuty(0, -0.1))
+	assert.Equal(t, pwm.ErrInvalidDuty, p.SetDuty(0, 1.1))
+	_, err = p.Duty(3)
+	assert.Equal(t, pwm.ErrInvalidChannel, err)
+
+	// full and zero duty hold the lines
+	assert.Nil(t, p.SetDuty(0, 1))
+	assert.Nil(t, p.SetDuty(2, 0.5))
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		v, err := c.Value(offsets[0])
+		assert.Nil(t, err)
+		assert.Equal(t, 1, v)
+		v, err = c.Value(offsets[1])
+		assert.Nil(t, err)
+		assert.Equal(t, 0, v)
+		time.Sleep(130 * time.Microsecond)
+	}
+
+	j := p.Jitter()
+	assert.NotZero(t, j.Count)
+	assert.GreaterOrEqual(t, j.MaxLate, time.Duration(0))
+	p.ResetJitter()
+	assert.Equal(t, uint64(0), p.Jitter().Count)
+
+	// pulse widths
+	assert.Equal(t, pwm.ErrInvalidDuty, p.SetPulseWidth(1, 2*time.Millisecond))
+	assert.Nil(t, p.SetPulseWidth(1, 250*time.Microsecond))
+	d, err := p.Duty(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.25, d)
+
+	// period change retains duty
+	assert.Equal(t, pwm.ErrInvalidPeriod, p.SetFrequency(0))
+	assert.Nil(t, p.SetFrequency(500))
+	assert.Equal(t, 2*time.Millisecond, p.Period())
+	d, err = p.Duty(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.25, d)
+
+	// closing sets lines inactive
+	err = p.Close()
+	assert.Nil(t, err)
+	for _, o := range offsets {
+		v, err := c.Value(o)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, v)
+	}
+}
+
+func TestServo(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	p, err := pwm.NewServo(ll)
+	require.Nil(t, err)
+	require.NotNil(t, p)
+	defer p.Close()
+	assert.Equal(t, 20*time.Millisecond, p.Period())
+
+	assert.Equal(t, pwm.ErrInvalidDuty, p.SetServo(0, 1.5))
+	assert.Nil(t, p.SetServo(0, 0.5))
+	d, err := p.Duty(0)
+	assert.Nil(t, err)
+	assert.InDelta(t, 0.075, d, 0.0001)
+
+	p.Close()
+	p, err = pwm.NewServo(ll, pwm.WithServoRange(500*time.Microsecond, 2500*time.Microsecond))
+	require.Nil(t, err)
+	require.NotNil(t, p)
+	assert.Nil(t, p.SetServo(1, 1))
+	d, err = p.Duty(1)
+	assert.Nil(t, err)
+	assert.InDelta(t, 0.125, d, 0.0001)
+}
+
+func TestErr(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets, gpiod.AsOutput())
+	require.Nil(t, err)
+
+	p, err := pwm.New(ll, pwm.WithPeriod(time.Millisecond))
+	require.Nil(t, err)
+	require.NotNil(t, p)
+	assert.Nil(t, p.Err())
+	ll.Close()
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, gpiod.ErrClosed, p.Err())
+	assert.Equal(t, gpiod.ErrClosed, p.Close())
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
@@ -0,0 +1,450 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package pwmchip provides access to hardware PWM controllers via the Linux
+// PWM sysfs interface.
+//
+// This is a companion to gpiod for pins that are connected to a hardware PWM
+// controller, which are not accessible via the GPIO character device.  For
+// pins that are not, the pwm package provides software PWM.
+package pwmchip
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultRoot is the sysfs directory containing the PWM chips.
+const DefaultRoot = "/sys/class/pwm"
+
+// Chip represents a PWM controller.
+type Chip struct {
+	// The system name for this chip, e.g. pwmchip0.
+	Name string
+
+	// the directory containing the PWM chips.
+	root string
+
+	// the number of channels provided by the chip.
+	channels int
+
+	// the time to wait for an exported channel to appear.
+	exportTimeout time.Duration
+}
+
+// Chips returns the names of the available PWM chips.
+func Chips(options ...Option) ([]string, error) {
+	c := Chip{root: DefaultRoot}
+	for _, option := range options {
+		option(&c)
+	}
+	ee, err := os.ReadDir(c.root)
+	if err != nil {
+		return nil, err
+	}
+	cc := []string(nil)
+	for _, e := range ee {
+		if strings.HasPrefix(e.Name(), "pwmchip") {
+			cc = append(cc, e.Name())
+		}
+	}
+	sort.Slice(cc, func(i, j int) bool {
+		return chipNumber(cc[i]) < chipNumber(cc[j])
+	})
+	return cc, nil
+}
+
+// NewChip opens a PWM chip.
+//
+// The name may be the chip name, e.g. pwmchip0, or just its number.
+func NewChip(name string, options ...Option) (*Chip, error) {
+	if _, err := strconv.Atoi(name); err == nil {
+		name = "pwmchip" + name
+	}
+	c := Chip{
+		Name:          name,
+		root:          DefaultRoot,
+		exportTimeout: time.Second,
+	}
+	for _, option := range options {
+		option(&c)
+	}
+	n, err := readInt(c.path("npwm"))
+	if err != nil {
+		return nil, err
+	}
+	c.channels = n
+	return &c, nil
+}
+
+// Channels returns the number of channels provided by the chip.
+func (c *Chip) Channels() int {
+	return c.channels
+}
+
+// Export exports a channel so that it can be controlled.
+//
+// The channel remains exported until it is unexported, even after the program
+// exits.
+//
+// Returns ErrBusy if the channel is already exported, or in use by a kernel
+// driver.
+func (c *Chip) Export(channel int) (*Channel, error) {
+	if channel < 0 || channel >= c.channels {
+		return nil, ErrInvalidChannel
+	}
+	ch := Channel{chip: c, channel: channel}
+	if _, err := os.Stat(ch.path("")); err == nil {
+		return nil, ErrBusy{Chip: c.Name, Channel: channel}
+	}
+	err := writeString(c.path("export"), strconv.Itoa(channel))
+	if errors.Is(err, unix.EBUSY) {
+		return nil, ErrBusy{Chip: c.Name, Channel: channel}
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the channel directory is created asynchronously, and its permissions
+	// may be updated by udev after that, so wait for it to be writable.
+	deadline := time.Now().Add(c.exportTimeout)
+	for unix.Access(ch.path("enable"), unix.W_OK) != nil {
+		if time.Now().After(deadline) {
+			return nil, ErrTimeout
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return &ch, nil
+}
+
+// Channel returns a channel that has already been exported.
+//
+// This allows control of a channel exported by another program, or a prior
+// run of this one.
+func (c *Chip) Channel(channel int) (*Channel, error) {
+	if channel < 0 || channel >= c.channels {
+		return nil, ErrInvalidChannel
+	}
+	ch := Channel{chip: c, channel: channel}
+	if _, err := os.Stat(ch.path("")); err != nil {
+		return nil, ErrNotExported
+	}
+	return &ch, nil
+}
+
+func (c *Chip) path(attr string) string {
+	return filepath.Join(c.root, c.Name, attr)
+}
+
+// Polarity indicates the active level of a PWM signal.
+type Polarity int
+
+const (
+	// PolarityNormal indicates the signal is high during the duty cycle.
+	PolarityNormal Polarity = iota
+
+	// PolarityInversed indicates the signal is low during the duty cycle.
+	PolarityInversed
+)
+
+// String returns the sysfs representation of the polarity.
+func (p Polarity) String() string {
+	if p == PolarityInversed {
+		return "inversed"
+	}
+	return "normal"
+}
+
+// Config contains the configuration of a channel.
+type Config struct {
+	// The period of the signal.
+	Period time.Duration
+
+	// The active time of the signal within the period.
+	DutyCycle time.Duration
+
+	// The active level of the signal.
+	Polarity Polarity
+
+	// Whether the signal is being output.
+	Enabled bool
+}
+
+// Channel represents an exported PWM channel.
+type Channel struct {
+	chip    *Chip
+	channel int
+}
+
+// Chip returns the name of the chip containing the channel.
+func (ch *Channel) Chip() string {
+	return ch.chip.Name
+}
+
+// Number returns the number of the channel within the chip.
+func (ch *Channel) Number() int {
+	return ch.channel
+}
+
+// Unexport releases the channel.
+func (ch *Channel) Unexport() error {
+	return writeString(ch.chip.path("unexport"), strconv.Itoa(ch.channel))
+}
+
+// Period returns the period of the signal.
+func (ch *Channel) Period() (time.Duration, error) {
+	return ch.readDuration("period")
+}
+
+// SetPeriod sets the period of the signal.
+//
+// The period cannot be set below the duty cycle.
+func (ch *Channel) SetPeriod(period time.Duration) error {
+	if period < 0 {
+		return ErrInvalidPeriod
+	}
+	return ch.writeInt("period", int64(period))
+}
+
+// DutyCycle returns the active time of the signal within the period.
+func (ch *Channel) DutyCycle() (time.Duration, error) {
+	return ch.readDuration("duty_cycle")
+}
+
+// SetDutyCycle sets the active time of the signal within the period.
+//
+// The duty cycle cannot be set above the period.
+func (ch *Channel) SetDutyCycle(duty time.Duration) error {
+	if duty < 0 {
+		return ErrInvalidDutyCycle
+	}
+	return ch.writeInt("duty_cycle", int64(duty))
+}
+
+// Polarity returns the active level of the signal.
+func (ch *Channel) Polarity() (Polarity, error) {
+	s, err := readString(ch.path("polarity"))
+	if err != nil {
+		return PolarityNormal, err
+	}
+	switch s {
+	case "normal":
+		return PolarityNormal, nil
+	case "inversed":
+		return PolarityInversed, nil
+	}
+	return PolarityNormal, ErrInvalidPolarity
+}
+
+// SetPolarity sets the active level of the signal.
+//
+// Most controllers only allow the polarity to be changed while the channel is
+// disabled.
+func (ch *Channel) SetPolarity(p Polarity) error {
+	if p != PolarityNormal && p != PolarityInversed {
+		return ErrInvalidPolarity
+	}
+	return writeString(ch.path("polarity"), p.String())
+}
+
+// Enabled returns true if the signal is being output.
+func (ch *Channel) Enabled() (bool, error) {
+	v, err := readInt(ch.path("enable"))
+	return v != 0, err
+}
+
+// Enable starts outputting the signal.
+func (ch *Channel) Enable() error {
+	return ch.writeInt("enable", 1)
+}
+
+// Disable stops outputting the signal.
+func (ch *Channel) Disable() error {
+	return ch.writeInt("enable", 0)
+}
+
+// Config returns the current configuration of the channel.
+func (ch *Channel) Config() (cfg Config, err error) {
+	if cfg.Period, err = ch.Period(); err != nil {
+		return
+	}
+	if cfg.DutyCycle, err = ch.DutyCycle(); err != nil {
+		return
+	}
+	if cfg.Polarity, err = ch.Polarity(); err != nil {
+		return
+	}
+	cfg.Enabled, err = ch.Enabled()
+	return
+}
+
+// Configure applies the configuration to the channel.
+//
+// The attributes are written in an order that the kernel will accept, so the
+// period and duty cycle may be changed together without the duty cycle
+// exceeding the period in between.
+func (ch *Channel) Configure(cfg Config) error {
+	if cfg.Period < 0 {
+		return ErrInvalidPeriod
+	}
+	if cfg.DutyCycle < 0 || cfg.DutyCycle > cfg.Period {
+		return ErrInvalidDutyCycle
+	}
+	if cfg.Polarity != PolarityNormal && cfg.Polarity != PolarityInversed {
+		return ErrInvalidPolarity
+	}
+	cur, err := ch.Config()
+	if err != nil {
+		return err
+	}
+	if cur.Polarity != cfg.Polarity {
+		if cur.Enabled {
+			if err := ch.Disable(); err != nil {
+				return err
+			}
+			cur.Enabled = false
+		}
+		if err := ch.SetPolarity(cfg.Polarity); err != nil {
+			return err
+		}
+	}
+	if cfg.Period < cur.DutyCycle {
+		if err := ch.SetDutyCycle(cfg.DutyCycle); err != nil {
+			return err
+		}
+		if err := ch.SetPeriod(cfg.Period); err != nil {
+			return err
+		}
+	} else {
+		if err := ch.SetPeriod(cfg.Period); err != nil {
+			return err
+		}
+		if err := ch.SetDutyCycle(cfg.DutyCycle); err != nil {
+			return err
+		}
+	}
+	if cur.Enabled != cfg.Enabled {
+		if cfg.Enabled {
+			return ch.Enable()
+		}
+		return ch.Disable()
+	}
+	return nil
+}
+
+func (ch *Channel) path(attr string) string {
+	return ch.chip.path(filepath.Join(fmt.Sprintf("pwm%d", ch.channel), attr))
+}
+
+func (ch *Channel) readDuration(attr string) (time.Duration, error) {
+	v, err := readInt(ch.path(attr))
+	return time.Duration(v), err
+}
+
+func (ch *Channel) writeInt(attr string, v int64) error {
+	return writeString(ch.path(attr), strconv.FormatInt(v, 10))
+}
+
+func chipNumber(name string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(name, "pwmchip"))
+	return n
+}
+
+func readString(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readInt(path string) (int, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(s)
+}
+
+// writeString writes the value to a sysfs attribute.
+//
+// The attribute must already exist.
+func writeString(path string, value string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(value)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Option specifies a construction option for a Chip.
+type Option func(*Chip)
+
+// WithRoot sets the directory containing the PWM chips.
+//
+// The default is DefaultRoot.  This is primarily intended for testing.
+func WithRoot(root string) Option {
+	return func(c *Chip) {
+		c.root = root
+	}
+}
+
+// WithExportTimeout sets the time Export waits for the exported channel to
+// become available.
+//
+// The default is one second.
+func WithExportTimeout(timeout time.Duration) Option {
+	return func(c *Chip) {
+		c.exportTimeout = timeout
+	}
+}
+
+// ErrBusy indicates the channel is already exported, or is in use by a kernel
+// driver.
+type ErrBusy struct {
+	// The name of the chip.
+	Chip string
+
+	// The channel number.
+	Channel int
+}
+
+func (e ErrBusy) Error() string {
+	return fmt.Sprintf("%s channel %d is busy", e.Chip, e.Channel)
+}
+
+var (
+	// ErrInvalidChannel indicates the channel is not provided by the chip.
+	ErrInvalidChannel = errors.New("invalid channel")
+
+	// ErrInvalidDutyCycle indicates the duty cycle is negative or exceeds the
+	// period.
+	ErrInvalidDutyCycle = errors.New("invalid duty cycle")
+
+	// ErrInvalidPeriod indicates the period is negative.
+	ErrInvalidPeriod = errors.New("invalid period")
+
+	// ErrInvalidPolarity indicates the polarity is not a known Polarity.
+	ErrInvalidPolarity = errors.New("invalid polarity")
+
+	// ErrNotExported indicates the channel has not been exported.
+	ErrNotExported = errors.New("channel not exported")
+
+	// ErrTimeout indicates an exported channel did not become available.
+	ErrTimeout = errors.New("timeout waiting for channel")
+)
@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package pwmchip_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod/pwmchip"
+)
+
+func TestChips(t *testing.T) {
+	root := newSysfs(t, 2, 3, 1)
+	cc, err := pwmchip.Chips(pwmchip.WithRoot(root))
+	assert.Nil(t, err)
+	// sorted numerically, not lexically
+	assert.Equal(t, []string{"pwmchip0", "pwmchip2", "pwmchip10"}, cc)
+
+	cc, err = pwmchip.Chips(pwmchip.WithRoot(filepath.Join(root, "nonexistent")))
+	assert.NotNil(t, err)
+	assert.Nil(t, cc)
+}
+
+func TestNewChip(t *testing.T) {
+	root := newSysfs(t, 2, 3, 1)
+	patterns := []struct {
+		name     string
+		chip     string
+		channels int
+	}{
+		{"name", "pwmchip2", 3},
+		{"number", "10", 1},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			c, err := pwmchip.NewChip(p.chip, pwmchip.WithRoot(root))
+			assert.Nil(t, err)
+			require.NotNil(t, c)
+			assert.Equal(t, p.channels, c.Channels())
+		}
+		t.Run(p.name, tf)
+	}
+	c, err := pwmchip.NewChip("pwmchip1", pwmchip.WithRoot(root))
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, c)
+}
+
+func TestExport(t *testing.T) {
+	root := newSysfs(t, 2)
+	c, err := pwmchip.NewChip("pwmchip0",
+		pwmchip.WithRoot(root),
+		pwmchip.WithExportTimeout(10*time.Millisecond))
+	require.Nil(t, err)
+
+	ch, err := c.Export(2)
+	assert.Equal(t, pwmchip.ErrInvalidChannel, err)
+	assert.Nil(t, ch)
+
+	_, err = c.Channel(1)
+	assert.Equal(t, pwmchip.ErrNotExported, err)
+
+	// the fake kernel never creates the channel
+	ch, err = c.Export(1)
+	assert.Equal(t, pwmchip.ErrTimeout, err)
+	assert.Nil(t, ch)
+	assert.Equal(t, "1", readAttr(t, root, "pwmchip0/export"))
+
+	done := fakeExport(t, root, "pwmchip0")
+	ch, err = c.Export(1)
+	<-done
+	assert.Nil(t, err)
+	require.NotNil(t, ch)
+	assert.Equal(t, "pwmchip0", ch.Chip())
+	assert.Equal(t, 1, ch.Number())
+
+	// already exported
+	ch2, err := c.Export(1)
+	assert.Equal(t, pwmchip.ErrBusy{Chip: "pwmchip0", Channel: 1}, err)
+	assert.Equal(t, "pwmchip0 channel 1 is busy", err.Error())
+	assert.Nil(t, ch2)
+
+	ch2, err = c.Channel(1)
+	assert.Nil(t, err)
+	assert.Equal(t, ch, ch2)
+
+	assert.Nil(t, ch.Unexport())
+	assert.Equal(t, "1", readAttr(t, root, "pwmchip0/unexport"))
+}
+
+func TestChannel(t *testing.T) {
+	root := newSysfs(t, 1)
+	ch := newChannel(t, root, 0)
+
+	assert.Equal(t, pwmchip.ErrInvalidPeriod, ch.SetPeriod(-1))
+	assert.Nil(t, ch.SetPeriod(20*time.Millisecond))
+	assert.Equal(t, "20000000", readAttr(t, root, "pwmchip0/pwm0/period"))
+	p, err := ch.Period()
+	assert.Nil(t, err)
+	assert.Equal(t, 20*time.Millisecond, p)
+
+	assert.Equal(t, pwmchip.ErrInvalidDutyCycle, ch.SetDutyCycle(-1))
+	assert.Nil(t, ch.SetDutyCycle(1500*time.Microsecond))
+	assert.Equal(t, "1500000", readAttr(t, root, "pwmchip0/pwm0/duty_cycle"))
+	d, err := ch.DutyCycle()
+	assert.Nil(t, err)
+	assert.Equal(t, 1500*time.Microsecond, d)
+
+	assert.Equal(t, pwmchip.ErrInvalidPolarity, ch.SetPolarity(pwmchip.Polarity(2)))
+	assert.Nil(t, ch.SetPolarity(pwmchip.PolarityInversed))
+	assert.Equal(t, "inversed", readAttr(t, root, "pwmchip0/pwm0/polarity"))
+	pol, err := ch.Polarity()
+	assert.Nil(t, err)
+	assert.Equal(t, pwmchip.PolarityInversed, pol)
+	writeAttr(t, root, "pwmchip0/pwm0/polarity", "bogus\n")
+	_, err = ch.Polarity()
+	assert.Equal(t, pwmchip.ErrInvalidPolarity, err)
+	assert.Nil(t, ch.SetPolarity(pwmchip.PolarityNormal))
+
+	assert.Nil(t, ch.Enable())
+	assert.Equal(t, "1", readAttr(t, root, "pwmchip0/pwm0/enable"))
+	e, err := ch.Enabled()
+	assert.Nil(t, err)
+	assert.True(t, e)
+	assert.Nil(t, ch.Disable())
+	e, err = ch.Enabled()
+	assert.Nil(t, err)
+	assert.False(t, e)
+
+	cfg, err := ch.Config()
+	assert.Nil(t, err)
+	assert.Equal(t, pwmchip.Config{
+		Period:    20 * time.Millisecond,
+		DutyCycle: 1500 * time.Microsecond,
+	}, cfg)
+
+	// attributes of an unexported channel
+	require.Nil(t, os.RemoveAll(filepath.Join(root, "pwmchip0/pwm0")))
+	assert.True(t, os.IsNotExist(ch.Enable()))
+	_, err = ch.Period()
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestConfigure(t *testing.T) {
+	root := newSysfs(t, 1)
+	ch := newChannel(t, root, 0)
+
+	patterns := []struct {
+		name string
+		cfg  pwmchip.Config
+		err  error
+	}{
+		{"period", pwmchip.Config{Period: -1}, pwmchip.ErrInvalidPeriod},
+		{"negative duty", pwmchip.Config{Period: 10, DutyCycle: -1}, pwmchip.ErrInvalidDutyCycle},
+		{"excess duty", pwmchip.Config{Period: 10, DutyCycle: 11}, pwmchip.ErrInvalidDutyCycle},
+		{"polarity", pwmchip.Config{Polarity: 3}, pwmchip.ErrInvalidPolarity},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			assert.Equal(t, p.err, ch.Configure(p.cfg))
+		}
+		t.Run(p.name, tf)
+	}
+
+	cfgs := []pwmchip.Config{
+		{Period: time.Millisecond, DutyCycle: 500 * time.Microsecond, Enabled: true},
+		// shrinking the period below the old duty cycle
+		{Period: 400 * time.Microsecond, DutyCycle: 100 * time.Microsecond, Enabled: true},
+		{Period: 400 * time.Microsecond, DutyCycle: 300 * time.Microsecond,
+			Polarity: pwmchip.PolarityInversed},
+	}
+	for _, cfg := range cfgs {
+		assert.Nil(t, ch.Configure(cfg))
+		got, err := ch.Config()
+		assert.Nil(t, err)
+		assert.Equal(t, cfg, got)
+	}
+}
+
+// newSysfs creates a fake PWM sysfs tree containing chips with the given
+// number of channels.
+//
+// Chips are numbered 0, 2, 10... to check numeric ordering.
+func newSysfs(t *testing.T, npwm ...int) string {
+	t.Helper()
+	root := t.TempDir()
+	names := []string{"pwmchip0", "pwmchip2", "pwmchip10"}
+	for i, n := range npwm {
+		dir := filepath.Join(root, names[i])
+		require.Nil(t, os.Mkdir(dir, 0755))
+		writeAttr(t, dir, "npwm", strconv.Itoa(n)+"\n")
+		writeAttr(t, dir, "export", "")
+		writeAttr(t, dir, "unexport", "")
+	}
+	return root
+}
+
+// newChannel creates and exports a channel in a fake sysfs tree.
+func newChannel(t *testing.T, root string, channel int) *pwmchip.Channel {
+	t.Helper()
+	c, err := pwmchip.NewChip("pwmchip0", pwmchip.WithRoot(root))
+	require.Nil(t, err)
+	done := fakeExport(t, root, "pwmchip0")
+	ch, err := c.Export(channel)
+	<-done
+	require.Nil(t, err)
+	return ch
+}
+
+// fakeExport emulates the kernel creating the channel directory once a
+// channel is written to the export attribute.
+func fakeExport(t *testing.T, root, chip string) <-chan struct{} {
+	t.Helper()
+	writeAttr(t, root, filepath.Join(chip, "export"), "")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		path := filepath.Join(root, chip, "export")
+		for i := 0; i < 1000; i++ {
+			b, _ := os.ReadFile(path)
+			if len(b) != 0 {
+				dir := filepath.Join(root, chip, "pwm"+string(b))
+				if os.Mkdir(dir, 0755) != nil {
+					return
+				}
+				for attr, v := range map[string]string{
+					"period":     "0\n",
+					"duty_cycle": "0\n",
+					"polarity":   "normal\n",
+					"enable":     "0\n",
+				} {
+					os.WriteFile(filepath.Join(dir, attr), []byte(v), 0644)
+				}
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	return done
+}
+
+func readAttr(t *testing.T, root, attr string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(root, attr))
+	require.Nil(t, err)
+	return strings.TrimSpace(string(b))
+}
+
+func writeAttr(t *testing.T, root, attr, value string) {
+	t.Helper()
+	err := os.WriteFile(filepath.Join(root, attr), []byte(value), 0644)
+	require.Nil(t, err)
+}
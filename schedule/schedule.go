@@ -0,0 +1,342 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package schedule provides changes to line values at absolute times.
+//
+// The times are on CLOCK_MONOTONIC, as returned by timing.Now, which is also
+// the clock of LineEvent timestamps unless the lines are configured otherwise,
+// so changes may be scheduled relative to edges, such as firing a strobe 2ms
+// after a trigger edge.
+//
+// The Scheduler blocks on a timerfd until shortly before each change is due,
+// then busy-waits for the remainder, and reports how late each change was
+// actually made.
+package schedule
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+	"golang.org/x/sys/unix"
+)
+
+// Change is a change to the values of a set of lines at a point in time.
+type Change struct {
+	// At is the time of the change, on CLOCK_MONOTONIC.
+	At time.Duration
+
+	// Mask identifies the lines to change, where bit n corresponds to the nth
+	// line in the Lines.
+	Mask uint64
+
+	// Bits contains the values for the lines identified by Mask.
+	Bits uint64
+}
+
+// Report describes the outcome of a Change.
+type Report struct {
+	Change
+
+	// Late is how long after the scheduled time the values were set.
+	Late time.Duration
+
+	// Err is the error returned when setting the values, if any.
+	Err error
+}
+
+// ReportHandler receives the report of each change made by a Scheduler.
+//
+// The handler is called from the goroutine that makes the changes, so it
+// should return promptly to avoid delaying subsequent changes.
+type ReportHandler func(Report)
+
+// Scheduler makes changes to the values of lines at scheduled times.
+//
+// The Scheduler does not take ownership of the lines, which remain the
+// responsibility of the caller to close, after closing the Scheduler.
+type Scheduler struct {
+	lines *gpiod.Lines
+
+	// the period before a change at which the scheduler starts spinning.
+	spin time.Duration
+
+	rh ReportHandler
+
+	// timerfd used to wait for changes.
+	tfd int
+
+	// eventfd used to wake the scheduler when the queue changes.
+	efd int
+
+	// closed once the scheduler exits.
+	doneCh chan struct{}
+
+	// mu covers the state below.
+	mu sync.Mutex
+
+	// changes waiting to be made, in time order.
+	queue []Change
+
+	// the error that stopped the scheduler, if any.
+	err error
+
+	closed bool
+}
+
+// New creates a Scheduler for the lines.
+//
+// Unless WithSpinPeriod is provided, the spin period is taken from the
+// timing.DefaultCalibration.
+func New(lines *gpiod.Lines, options ...Option) (*Scheduler, error) {
+	if lines == nil {
+		return nil, ErrNoLines
+	}
+	sc := Scheduler{lines: lines, spin: -1, tfd: -1, efd: -1}
+	for _, option := range options {
+		option(&sc)
+	}
+	if sc.spin < 0 {
+		cal, err := timing.DefaultCalibration()
+		if err != nil {
+			return nil, err
+		}
+		sc.spin = cal.WakeLatency
+	}
+	var err error
+	sc.tfd, err = unix.TimerfdCreate(unix.CLOCK_MONOTONIC, unix.TFD_CLOEXEC|unix.TFD_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	sc.efd, err = unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		unix.Close(sc.tfd)
+		return nil, err
+	}
+	sc.doneCh = make(chan struct{})
+	go sc.run()
+	return &sc, nil
+}
+
+// Close stops the Scheduler.
+//
+// Any pending changes are discarded.
+func (s *Scheduler) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	s.queue = nil
+	s.mu.Unlock()
+	s.wake()
+	<-s.doneCh
+	unix.Close(s.tfd)
+	unix.Close(s.efd)
+	return nil
+}
+
+// Schedule adds the changes to the schedule.
+//
+// The changes need not be in time order, and are made in time order
+// irrespective of the order they are scheduled.  Changes scheduled for the same
+// time are made in the order they are scheduled.  Changes scheduled for times
+// that have already passed are made immediately.
+//
+// If the Scheduler has stopped due to an error then that error is returned.
+func (s *Scheduler) Schedule(changes ...Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if s.err != nil {
+		return s.err
+	}
+	for _, c := range changes {
+		idx := sort.Search(len(s.queue), func(i int) bool {
+			return s.queue[i].At > c.At
+		})
+		s.queue = append(s.queue, Change{})
+		copy(s.queue[idx+1:], s.queue[idx:])
+		s.queue[idx] = c
+	}
+	s.wake()
+	return nil
+}
+
+// SetValuesAt schedules setting the values of the lines at the deadline.
+//
+// The values are applied as per Lines.SetValues.
+func (s *Scheduler) SetValuesAt(deadline time.Duration, values []int) error {
+	c := Change{At: deadline}
+	n := len(s.lines.Offsets())
+	for i := 0; i < n; i++ {
+		c.Mask |= 1 << uint(i)
+		if i < len(values) && values[i] != 0 {
+			c.Bits |= 1 << uint(i)
+		}
+	}
+	return s.Schedule(c)
+}
+
+// Err returns the error that stopped the Scheduler, if any.
+//
+// Changes pending when the Scheduler stopped are discarded, and reported with
+// the error.
+func (s *Scheduler) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Pending returns the number of changes waiting to be made.
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// Cancel discards any pending changes.
+func (s *Scheduler) Cancel() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.queue = s.queue[:0]
+	s.wake()
+}
+
+// wake signals the scheduler goroutine to re-examine the queue.
+func (s *Scheduler) wake() {
+	// the counter is written in native byte order
+	one := uint64(1)
+	unix.Write(s.efd, (*[8]byte)(unsafe.Pointer(&one))[:])
+}
+
+func (s *Scheduler) run() {
+	defer close(s.doneCh)
+	pfds := []unix.PollFd{
+		{Fd: int32(s.efd), Events: unix.POLLIN},
+		{Fd: int32(s.tfd), Events: unix.POLLIN},
+	}
+	var buf [8]byte
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		var c Change
+		pending := len(s.queue) > 0
+		if pending {
+			c = s.queue[0]
+		}
+		s.mu.Unlock()
+
+		if !pending || c.At-s.spin > timing.Now() {
+			// wait for the change to be nearly due, or the queue to change
+			var its unix.ItimerSpec
+			if pending {
+				its.Value = unix.NsecToTimespec(int64(c.At - s.spin))
+			}
+			// a zero its disarms the timer
+			err := unix.TimerfdSettime(s.tfd, unix.TFD_TIMER_ABSTIME, &its, nil)
+			if err != nil {
+				s.fail(err)
+				return
+			}
+			_, err = unix.Poll(pfds, -1)
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil {
+				s.fail(err)
+				return
+			}
+			unix.Read(s.tfd, buf[:])
+			if pfds[0].Revents&unix.POLLIN != 0 {
+				unix.Read(s.efd, buf[:])
+				continue
+			}
+			if !pending {
+				continue
+			}
+		}
+		for timing.Now() < c.At {
+		}
+
+		s.mu.Lock()
+		if len(s.queue) == 0 || s.queue[0] != c {
+			// cancelled or pre-empted while spinning
+			s.mu.Unlock()
+			continue
+		}
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		err := s.lines.SetValuesMask(c.Mask, c.Bits)
+		r := Report{Change: c, Late: timing.Now() - c.At, Err: err}
+		if r.Late < 0 {
+			r.Late = 0
+		}
+		if s.rh != nil {
+			s.rh(r)
+		}
+	}
+}
+
+// fail records the error that stopped the scheduler, and reports the pending
+// changes as failed.
+func (s *Scheduler) fail(err error) {
+	s.mu.Lock()
+	s.err = err
+	queue := s.queue
+	s.queue = nil
+	s.mu.Unlock()
+	if s.rh == nil {
+		return
+	}
+	for _, c := range queue {
+		s.rh(Report{Change: c, Err: err})
+	}
+}
+
+// Option specifies a construction option for the Scheduler.
+type Option func(*Scheduler)
+
+// WithReportHandler sets the handler for the reports of changes made by the
+// Scheduler.
+func WithReportHandler(rh ReportHandler) Option {
+	return func(s *Scheduler) {
+		s.rh = rh
+	}
+}
+
+// WithSpinPeriod sets the period before each change at which the Scheduler
+// switches from blocking to busy-waiting.
+//
+// Larger periods are more precise, but consume more CPU.
+func WithSpinPeriod(spin time.Duration) Option {
+	return func(s *Scheduler) {
+		s.spin = spin
+	}
+}
+
+var (
+	// ErrClosed indicates the Scheduler has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrNoLines indicates the Scheduler was not provided any lines.
+	ErrNoLines = errors.New("no lines")
+)
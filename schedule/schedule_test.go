@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/mockup"
+	"github.com/warthog618/gpiod/schedule"
+	"github.com/warthog618/gpiod/timing"
+)
+
+var offsets = []int{1, 2, 3}
+
+func TestNew(t *testing.T) {
+	s, err := schedule.New(nil)
+	assert.Equal(t, schedule.ErrNoLines, err)
+	assert.Nil(t, s)
+
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	s, err = schedule.New(ll)
+	assert.Nil(t, err)
+	require.NotNil(t, s)
+	assert.Zero(t, s.Pending())
+	assert.Nil(t, s.Close())
+	assert.Equal(t, schedule.ErrClosed, s.Close())
+	assert.Equal(t, schedule.ErrClosed, s.Schedule(schedule.Change{}))
+}
+
+func TestSchedule(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	rch := make(chan schedule.Report, 4)
+	s, err := schedule.New(ll, schedule.WithReportHandler(func(r schedule.Report) {
+		rch <- r
+	}))
+	require.Nil(t, err)
+	defer s.Close()
+
+	// scheduled out of order
+	now := timing.Now()
+	changes := []schedule.Change{
+		{At: now + 20*time.Millisecond, Mask: 0x07, Bits: 0x00},
+		{At: now + 10*time.Millisecond, Mask: 0x03, Bits: 0x03},
+		{At: now + 15*time.Millisecond, Mask: 0x04, Bits: 0x04},
+	}
+	err = s.Schedule(changes...)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, s.Pending())
+
+	for _, x := range []struct {
+		change schedule.Change
+		values []int
+	}{
+		{changes[1], []int{1, 1, 0}},
+		{changes[2], []int{1, 1, 1}},
+		{changes[0], []int{0, 0, 0}},
+	} {
+		r := waitReport(t, rch)
+		assert.Equal(t, x.change, r.Change)
+		assert.Nil(t, r.Err)
+		assert.GreaterOrEqual(t, r.Late, time.Duration(0))
+		assert.LessOrEqual(t, r.Late, timing.Now()-r.At)
+		for i, o := range offsets {
+			v, err := c.Value(o)
+			assert.Nil(t, err)
+			assert.Equal(t, x.values[i], v, i)
+		}
+	}
+	assert.Zero(t, s.Pending())
+
+	// in the past
+	err = s.SetValuesAt(timing.Now()-time.Millisecond, []int{1, 0, 1})
+	assert.Nil(t, err)
+	r := waitReport(t, rch)
+	assert.Nil(t, r.Err)
+	assert.Equal(t, uint64(0x07), r.Mask)
+	assert.Equal(t, uint64(0x05), r.Bits)
+}
+
+func TestCancel(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	rch := make(chan schedule.Report, 4)
+	s, err := schedule.New(ll, schedule.WithReportHandler(func(r schedule.Report) {
+		rch <- r
+	}))
+	require.Nil(t, err)
+	defer s.Close()
+
+	err = s.SetValuesAt(timing.Now()+10*time.Millisecond, []int{1, 1, 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, s.Pending())
+	s.Cancel()
+	assert.Zero(t, s.Pending())
+	select {
+	case r := <-rch:
+		assert.Fail(t, "cancelled change made", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+	v, err := c.Value(offsets[0])
+	assert.Nil(t, err)
+	assert.Zero(t, v)
+
+	// pending changes discarded on close
+	err = s.SetValuesAt(timing.Now()+10*time.Millisecond, []int{1, 1, 1})
+	assert.Nil(t, err)
+	s.Close()
+	time.Sleep(20 * time.Millisecond)
+	v, err = c.Value(offsets[0])
+	assert.Nil(t, err)
+	assert.Zero(t, v)
+}
+
+func TestSetValuesAtError(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets, gpiod.AsInput)
+	require.Nil(t, err)
+	defer ll.Close()
+
+	rch := make(chan schedule.Report, 4)
+	s, err := schedule.New(ll,
+		schedule.WithSpinPeriod(100*time.Microsecond),
+		schedule.WithReportHandler(func(r schedule.Report) {
+			rch <- r
+		}))
+	require.Nil(t, err)
+	defer s.Close()
+
+	err = s.SetValuesAt(timing.Now()+time.Millisecond, []int{1})
+	assert.Nil(t, err)
+	r := waitReport(t, rch)
+	assert.Equal(t, gpiod.ErrPermissionDenied, r.Err)
+}
+
+func waitReport(t *testing.T, rch <-chan schedule.Report) schedule.Report {
+	t.Helper()
+	select {
+	case r := <-rch:
+		return r
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for report")
+	}
+	return schedule.Report{}
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
@@ -0,0 +1,295 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package shiftreg
+
+import (
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// Input is a chain of 74HC165 parallel-in serial-out shift registers.
+//
+// The chain is read by loading the pins into the registers, then shifting them
+// out.  If an event handler is provided then the chain is also scanned
+// periodically and changes to the pins reported as events.
+type Input struct {
+	// mu serialises access to the chain and its state
+	mu sync.Mutex
+
+	tclk time.Duration
+
+	// the data (QH), clock (CLK) and latch (SH/LD) lines, in that order
+	lines *gpiod.Lines
+
+	delay *timing.Delay
+
+	// the delay used by the scanner to wait between scans
+	scanDelay *timing.Delay
+
+	// the state read by the most recent scan
+	values []byte
+
+	eh         gpiod.EventHandler
+	scanPeriod time.Duration
+
+	// the sequence numbers of the most recent events
+	seqno     uint32
+	lineSeqno []uint32
+
+	// the error that stopped the scanner
+	err error
+
+	done    chan struct{}
+	stopped chan struct{}
+	closed  bool
+}
+
+// NewInput creates an Input for a chain of length registers on the data,
+// clock and latch lines of the chip.
+//
+// The latch line is connected to the SH/LD pin of the registers, and the clock
+// inhibit (CLK INH) pins should be tied low.
+//
+// As the data line is an input and the others are outputs, this requires uAPI
+// v2.
+func NewInput(c *gpiod.Chip, data, clock, latch, length int, options ...Option) (*Input, error) {
+	if length < 1 {
+		return nil, ErrInvalidLength
+	}
+	cfg := newConfig(options)
+	if cfg.err != nil {
+		return nil, cfg.err
+	}
+	in := Input{
+		tclk:       cfg.tclk,
+		values:     make([]byte, length),
+		eh:         cfg.eh,
+		scanPeriod: cfg.scanPeriod,
+		lineSeqno:  make([]uint32, length*8),
+	}
+	var err error
+	in.delay, err = timing.NewDelay(cfg.timingOptions...)
+	if err != nil {
+		return nil, err
+	}
+	// SH/LD idles high, shifting on clock edges
+	in.lines, err = c.RequestLines([]int{data, clock, latch},
+		gpiod.AsOutput(0, 0, 1),
+		gpiod.WithLines([]int{data}, gpiod.AsInput))
+	if err != nil {
+		in.delay.Close()
+		return nil, err
+	}
+	// the initial state, against which changes are detected
+	if err = in.shiftIn(in.values); err != nil {
+		in.lines.Close()
+		in.delay.Close()
+		return nil, err
+	}
+	if in.eh != nil {
+		in.scanDelay, err = timing.NewDelay(cfg.timingOptions...)
+		if err != nil {
+			in.lines.Close()
+			in.delay.Close()
+			return nil, err
+		}
+		in.done = make(chan struct{})
+		in.stopped = make(chan struct{})
+		go in.scan()
+	}
+	return &in, nil
+}
+
+// Close stops any scanning and releases the lines.
+func (in *Input) Close() error {
+	in.mu.Lock()
+	if in.closed {
+		in.mu.Unlock()
+		return ErrClosed
+	}
+	in.closed = true
+	in.mu.Unlock()
+	if in.done != nil {
+		close(in.done)
+		<-in.stopped
+		in.scanDelay.Close()
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.delay.Close()
+	return in.lines.Close()
+}
+
+// Err returns the error that stopped the periodic scanning, if any.
+func (in *Input) Err() error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.err
+}
+
+// Pins returns the number of pins in the chain.
+func (in *Input) Pins() int {
+	return len(in.values) * 8
+}
+
+// Pin returns the virtual pin at the offset in the chain.
+func (in *Input) Pin(offset int) (*InputPin, error) {
+	if offset < 0 || offset >= in.Pins() {
+		return nil, ErrInvalidPin
+	}
+	return &InputPin{in: in, offset: offset}, nil
+}
+
+// Read reads the state of the chain.
+//
+// Any changes since the previous read or scan are reported as events.
+func (in *Input) Read() ([]byte, error) {
+	in.mu.Lock()
+	if in.closed {
+		in.mu.Unlock()
+		return nil, ErrClosed
+	}
+	events, err := in.read()
+	values := append([]byte(nil), in.values...)
+	in.mu.Unlock()
+	dispatch(in.eh, events)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Value reads the value of a pin.
+//
+// Any changes since the previous read or scan are reported as events.
+func (in *Input) Value(pin int) (int, error) {
+	if pin < 0 || pin >= in.Pins() {
+		return 0, ErrInvalidPin
+	}
+	values, err := in.Read()
+	if err != nil {
+		return 0, err
+	}
+	return bit(values, pin), nil
+}
+
+// read reads the state of the chain and returns the events for any changes.
+//
+// Must be called with the mu held.
+func (in *Input) read() ([]gpiod.LineEvent, error) {
+	ts := timing.Now()
+	values := make([]byte, len(in.values))
+	if err := in.shiftIn(values); err != nil {
+		return nil, err
+	}
+	var events []gpiod.LineEvent
+	if in.eh != nil {
+		for pin := 0; pin < in.Pins(); pin++ {
+			v := bit(values, pin)
+			if v == bit(in.values, pin) {
+				continue
+			}
+			typ := gpiod.LineEventFallingEdge
+			if v != 0 {
+				typ = gpiod.LineEventRisingEdge
+			}
+			in.seqno++
+			in.lineSeqno[pin]++
+			events = append(events, gpiod.LineEvent{
+				Offset:    pin,
+				Timestamp: ts,
+				Type:      typ,
+				Seqno:     in.seqno,
+				LineSeqno: in.lineSeqno[pin],
+			})
+		}
+	}
+	in.values = values
+	return events, nil
+}
+
+// shiftIn loads the pins into the chain, then shifts them out into values.
+//
+// Must be called with the mu held, or before the Input is shared.
+func (in *Input) shiftIn(values []byte) error {
+	if err := in.lines.SetValuesMask(latchMask, 0); err != nil {
+		return err
+	}
+	in.delay.Wait(in.tclk)
+	if err := in.lines.SetValuesMask(latchMask, latchMask); err != nil {
+		return err
+	}
+	in.delay.Wait(in.tclk)
+	// the H pin of the register nearest the controller is shifted out first
+	for i := 0; i < len(values)*8; i++ {
+		v, err := in.lines.ValuesMask(dataMask)
+		if err != nil {
+			return err
+		}
+		pin := i/8*8 + 7 - i%8
+		setBit(values, pin, int(v))
+		if err = in.lines.SetValuesMask(clockMask, clockMask); err != nil {
+			return err
+		}
+		in.delay.Wait(in.tclk)
+		if err = in.lines.SetValuesMask(clockMask, 0); err != nil {
+			return err
+		}
+		in.delay.Wait(in.tclk)
+	}
+	return nil
+}
+
+// scan periodically reads the chain until closed or a read fails.
+func (in *Input) scan() {
+	defer close(in.stopped)
+	next := timing.Now()
+	for {
+		next += in.scanPeriod
+		if now := timing.Now(); now > next {
+			next = now
+		}
+		if !in.scanDelay.UntilOrDone(next, in.done) {
+			return
+		}
+		in.mu.Lock()
+		events, err := in.read()
+		if err != nil {
+			in.err = err
+			in.mu.Unlock()
+			return
+		}
+		in.mu.Unlock()
+		dispatch(in.eh, events)
+	}
+}
+
+func dispatch(eh gpiod.EventHandler, events []gpiod.LineEvent) {
+	for _, evt := range events {
+		eh(evt)
+	}
+}
+
+// InputPin is a single pin of an Input.
+type InputPin struct {
+	in     *Input
+	offset int
+}
+
+// Offset returns the offset of the pin in the chain.
+func (p *InputPin) Offset() int {
+	return p.offset
+}
+
+// Value reads the value of the pin.
+func (p *InputPin) Value() (int, error) {
+	return p.in.Value(p.offset)
+}
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package shiftreg
+
+import (
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// Output is a chain of 74HC595 serial-in parallel-out shift registers.
+//
+// Changes to the pins are staged, and take effect together when committed,
+// with a single pulse of the latch.
+type Output struct {
+	// mu serialises access to the chain and its state
+	mu sync.Mutex
+
+	tclk time.Duration
+
+	// the data (SER), clock (SRCLK) and latch (RCLK) lines, in that order
+	lines *gpiod.Lines
+
+	delay *timing.Delay
+
+	// the state to be written by the next Commit
+	staged []byte
+
+	closed bool
+}
+
+// NewOutput creates an Output for a chain of length registers on the data,
+// clock and latch lines of the chip.
+//
+// The pins are initially inactive.
+func NewOutput(c *gpiod.Chip, data, clock, latch, length int, options ...Option) (*Output, error) {
+	if length < 1 {
+		return nil, ErrInvalidLength
+	}
+	cfg := newConfig(options)
+	if cfg.err != nil {
+		return nil, cfg.err
+	}
+	o := Output{
+		tclk:   cfg.tclk,
+		staged: make([]byte, length),
+	}
+	var err error
+	o.delay, err = timing.NewDelay(cfg.timingOptions...)
+	if err != nil {
+		return nil, err
+	}
+	o.lines, err = c.RequestLines([]int{data, clock, latch}, gpiod.AsOutput(0, 0, 0))
+	if err != nil {
+		o.delay.Close()
+		return nil, err
+	}
+	if err = o.commit(); err != nil {
+		o.lines.Close()
+		o.delay.Close()
+		return nil, err
+	}
+	return &o, nil
+}
+
+// Close releases the lines.
+//
+// The pins are left in their committed state.
+func (o *Output) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrClosed
+	}
+	o.closed = true
+	o.delay.Close()
+	return o.lines.Close()
+}
+
+// Pins returns the number of pins in the chain.
+func (o *Output) Pins() int {
+	return len(o.staged) * 8
+}
+
+// Pin returns the virtual pin at the offset in the chain.
+func (o *Output) Pin(offset int) (*OutputPin, error) {
+	if offset < 0 || offset >= o.Pins() {
+		return nil, ErrInvalidPin
+	}
+	return &OutputPin{o: o, offset: offset}, nil
+}
+
+// Value returns the value of a pin, including any staged change.
+func (o *Output) Value(pin int) (int, error) {
+	if pin < 0 || pin >= o.Pins() {
+		return 0, ErrInvalidPin
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return 0, ErrClosed
+	}
+	return bit(o.staged, pin), nil
+}
+
+// Values returns the state of the chain, including any staged changes.
+func (o *Output) Values() []byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]byte(nil), o.staged...)
+}
+
+// Set stages the value of a pin, to be written by the next Commit.
+func (o *Output) Set(pin, value int) error {
+	if pin < 0 || pin >= o.Pins() {
+		return ErrInvalidPin
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrClosed
+	}
+	setBit(o.staged, pin, value)
+	return nil
+}
+
+// SetValues stages the state of the whole chain, to be written by the next
+// Commit.
+//
+// The values must contain one byte per register.
+func (o *Output) SetValues(values []byte) error {
+	if len(values) != len(o.staged) {
+		return ErrInvalidLength
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrClosed
+	}
+	copy(o.staged, values)
+	return nil
+}
+
+// Commit shifts the staged state into the chain, then latches it to the pins.
+func (o *Output) Commit() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrClosed
+	}
+	return o.commit()
+}
+
+// commit shifts the staged state into the chain, then latches it to the pins.
+//
+// Must be called with the mu held.
+func (o *Output) commit() error {
+	// the first bit shifted ends up furthest from the controller
+	for pin := o.Pins() - 1; pin >= 0; pin-- {
+		var v uint64
+		if bit(o.staged, pin) != 0 {
+			v = dataMask
+		}
+		if err := o.lines.SetValuesMask(dataMask, v); err != nil {
+			return err
+		}
+		o.delay.Wait(o.tclk)
+		if err := o.lines.SetValuesMask(clockMask, clockMask); err != nil {
+			return err
+		}
+		o.delay.Wait(o.tclk)
+		if err := o.lines.SetValuesMask(clockMask, 0); err != nil {
+			return err
+		}
+	}
+	if err := o.lines.SetValuesMask(latchMask, latchMask); err != nil {
+		return err
+	}
+	o.delay.Wait(o.tclk)
+	return o.lines.SetValuesMask(latchMask, 0)
+}
+
+// OutputPin is a single pin of an Output.
+type OutputPin struct {
+	o      *Output
+	offset int
+}
+
+// Offset returns the offset of the pin in the chain.
+func (p *OutputPin) Offset() int {
+	return p.offset
+}
+
+// Value returns the value of the pin, including any staged change.
+func (p *OutputPin) Value() (int, error) {
+	o := p.o
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return 0, ErrClosed
+	}
+	return bit(o.staged, p.offset), nil
+}
+
+// SetValue sets the value of the pin, and commits it along with any other
+// staged changes.
+func (p *OutputPin) SetValue(value int) error {
+	o := p.o
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrClosed
+	}
+	setBit(o.staged, p.offset, value)
+	return o.commit()
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package shiftreg provides drivers for chains of 74HC595 serial-in
+// parallel-out, and 74HC165 parallel-in serial-out, shift registers.
+//
+// Each chain is driven by three lines - data, clock, and latch - requested
+// together as a single Lines.  A chain may contain any number of daisy-chained
+// registers, providing 8 pins per register.  Pins 0-7 are the A-H pins of the
+// register connected to the controller, pins 8-15 the A-H pins of the next
+// register in the chain, and so on.
+//
+// The state of a chain is represented as bytes, one per register, with bit n of
+// byte m corresponding to pin 8*m+n.
+package shiftreg
+
+import (
+	"errors"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// masks for the lines, which are requested in data, clock, latch order.
+const (
+	dataMask  = 0x1
+	clockMask = 0x2
+	latchMask = 0x4
+)
+
+// config contains the construction options common to both chain types.
+type config struct {
+	// time between clock edges (i.e. half the cycle time)
+	tclk time.Duration
+
+	// options for the delay
+	timingOptions []timing.Option
+
+	// the period between scans of an Input
+	scanPeriod time.Duration
+
+	// the handler for Input events
+	eh gpiod.EventHandler
+
+	// Error from construction options
+	err error
+}
+
+func newConfig(options []Option) config {
+	cfg := config{
+		tclk:       time.Microsecond,
+		scanPeriod: 10 * time.Millisecond,
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// bit returns the value of a pin in the state.
+func bit(state []byte, pin int) int {
+	return int(state[pin/8]>>uint(pin%8)) & 1
+}
+
+// setBit sets the value of a pin in the state.
+func setBit(state []byte, pin, v int) {
+	mask := byte(1) << uint(pin%8)
+	if v != 0 {
+		state[pin/8] |= mask
+	} else {
+		state[pin/8] &^= mask
+	}
+}
+
+// Option specifies a construction option for an Output or Input.
+type Option func(*config)
+
+// WithTclk sets the clock period for the chain.
+//
+// Note that this is the half-cycle period.  The default is 1µs, which is well
+// within the limits of the 74HC family, and much shorter than the time taken to
+// set a line on most platforms.
+func WithTclk(tclk time.Duration) Option {
+	return func(c *config) {
+		c.tclk = tclk
+	}
+}
+
+// WithTiming sets the options for the Delays used to time the clock edges, and
+// the scans of an Input.
+func WithTiming(options ...timing.Option) Option {
+	return func(c *config) {
+		c.timingOptions = append(c.timingOptions, options...)
+	}
+}
+
+// WithScanPeriod sets the period between the scans of an Input that generate
+// events.
+//
+// The default is 10ms.  Only applies to Inputs with an event handler.
+func WithScanPeriod(period time.Duration) Option {
+	return func(c *config) {
+		if period <= 0 {
+			c.err = ErrInvalidPeriod
+			return
+		}
+		c.scanPeriod = period
+	}
+}
+
+// WithEventHandler indicates that an Input will be periodically scanned, and
+// changes to its pins reported as events to the provided handler function.
+//
+// The Offset of the events is the pin, and the Timestamp the start of the scan
+// that detected the change, as returned by timing.Now.  Changes that revert
+// between scans are not detected.
+//
+// The handler is called from the goroutine performing the scan, which may be
+// the caller of Read or Value, without the Input locked, so it may access the
+// Input.
+func WithEventHandler(eh gpiod.EventHandler) Option {
+	return func(c *config) {
+		c.eh = eh
+	}
+}
+
+var (
+	// ErrClosed indicates the chain has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrInvalidLength indicates the number of registers is less than 1, or
+	// does not match the chain.
+	ErrInvalidLength = errors.New("invalid length")
+
+	// ErrInvalidPeriod indicates the scan period is not positive.
+	ErrInvalidPeriod = errors.New("invalid period")
+
+	// ErrInvalidPin indicates the pin is not in the chain.
+	ErrInvalidPin = errors.New("invalid pin")
+)
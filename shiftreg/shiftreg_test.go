@@ -0,0 +1,444 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package shiftreg_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/mockup"
+	"github.com/warthog618/gpiod/shiftreg"
+)
+
+const (
+	data  = 1
+	clock = 2
+	latch = 3
+	tclk  = 2 * time.Millisecond
+)
+
+func TestNewOutput(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	patterns := []struct {
+		name    string
+		length  int
+		options []shiftreg.Option
+		err     error
+	}{
+		{"zero length", 0, nil, shiftreg.ErrInvalidLength},
+		{"invalid period", 1, []shiftreg.Option{shiftreg.WithScanPeriod(0)}, shiftreg.ErrInvalidPeriod},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			o, err := shiftreg.NewOutput(chip, data, clock, latch, p.length, p.options...)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, o)
+		}
+		t.Run(p.name, tf)
+	}
+
+	// initially inactive
+	s := newSink(c, 2)
+	o, err := shiftreg.NewOutput(chip, data, clock, latch, 2, shiftreg.WithTclk(tclk))
+	require.Nil(t, err)
+	require.NotNil(t, o)
+	assert.Equal(t, 16, o.Pins())
+	assert.Equal(t, []byte{0, 0}, s.stop(t))
+	assert.Equal(t, 1, s.latches)
+
+	// lines are busy
+	o2, err := shiftreg.NewOutput(chip, data, clock, latch, 2)
+	assert.NotNil(t, err)
+	assert.Nil(t, o2)
+
+	assert.Nil(t, o.Close())
+	assert.Equal(t, shiftreg.ErrClosed, o.Close())
+	assert.Equal(t, shiftreg.ErrClosed, o.Commit())
+	assert.Equal(t, shiftreg.ErrClosed, o.Set(0, 1))
+	assert.Equal(t, shiftreg.ErrClosed, o.SetValues([]byte{1, 2}))
+	_, err = o.Value(0)
+	assert.Equal(t, shiftreg.ErrClosed, err)
+}
+
+func TestOutput(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	o, err := shiftreg.NewOutput(chip, data, clock, latch, 3, shiftreg.WithTclk(tclk))
+	require.Nil(t, err)
+	defer o.Close()
+
+	// staged changes are only latched on commit
+	s := newSink(c, 3)
+	for _, pin := range []int{0, 7, 9, 23} {
+		assert.Nil(t, o.Set(pin, 1))
+	}
+	assert.Nil(t, o.Set(9, 0))
+	assert.Equal(t, shiftreg.ErrInvalidPin, o.Set(24, 1))
+	assert.Equal(t, shiftreg.ErrInvalidPin, o.Set(-1, 1))
+	v, err := o.Value(7)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	_, err = o.Value(24)
+	assert.Equal(t, shiftreg.ErrInvalidPin, err)
+	assert.Equal(t, []byte{0x81, 0, 0x80}, o.Values())
+	assert.Nil(t, o.Commit())
+	assert.Equal(t, []byte{0x81, 0, 0x80}, s.stop(t))
+	assert.Equal(t, 1, s.latches)
+
+	s = newSink(c, 3)
+	assert.Equal(t, shiftreg.ErrInvalidLength, o.SetValues([]byte{1, 2}))
+	assert.Nil(t, o.SetValues([]byte{0x12, 0x34, 0x56}))
+	assert.Nil(t, o.Commit())
+	assert.Equal(t, []byte{0x12, 0x34, 0x56}, s.stop(t))
+
+	// pins commit immediately
+	p, err := o.Pin(24)
+	assert.Equal(t, shiftreg.ErrInvalidPin, err)
+	assert.Nil(t, p)
+	p, err = o.Pin(8)
+	require.Nil(t, err)
+	assert.Equal(t, 8, p.Offset())
+	s = newSink(c, 3)
+	assert.Nil(t, p.SetValue(1))
+	assert.Equal(t, []byte{0x12, 0x35, 0x56}, s.stop(t))
+	v, err = p.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	assert.Nil(t, o.Close())
+	assert.Equal(t, shiftreg.ErrClosed, p.SetValue(0))
+	_, err = p.Value()
+	assert.Equal(t, shiftreg.ErrClosed, err)
+}
+
+func TestNewInput(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	in, err := shiftreg.NewInput(chip, data, clock, latch, 0)
+	assert.Equal(t, shiftreg.ErrInvalidLength, err)
+	assert.Nil(t, in)
+
+	s := newSource(c, []byte{0xa5})
+	defer s.close()
+	in, err = shiftreg.NewInput(chip, data, clock, latch, 1, shiftreg.WithTclk(tclk))
+	require.Nil(t, err)
+	require.NotNil(t, in)
+	assert.Equal(t, 8, in.Pins())
+
+	// lines are busy
+	in2, err := shiftreg.NewInput(chip, data, clock, latch, 1)
+	assert.NotNil(t, err)
+	assert.Nil(t, in2)
+
+	assert.Nil(t, in.Close())
+	assert.Equal(t, shiftreg.ErrClosed, in.Close())
+	_, err = in.Read()
+	assert.Equal(t, shiftreg.ErrClosed, err)
+}
+
+func TestInput(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	s := newSource(c, []byte{0x01, 0x80})
+	defer s.close()
+	in, err := shiftreg.NewInput(chip, data, clock, latch, 2, shiftreg.WithTclk(tclk))
+	require.Nil(t, err)
+	defer in.Close()
+
+	values, err := in.Read()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x01, 0x80}, values)
+
+	s.set([]byte{0x5a, 0x3c})
+	values, err = in.Read()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x5a, 0x3c}, values)
+
+	v, err := in.Value(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	_, err = in.Value(16)
+	assert.Equal(t, shiftreg.ErrInvalidPin, err)
+
+	p, err := in.Pin(-1)
+	assert.Equal(t, shiftreg.ErrInvalidPin, err)
+	assert.Nil(t, p)
+	p, err = in.Pin(10)
+	require.Nil(t, err)
+	assert.Equal(t, 10, p.Offset())
+	v, err = p.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	s.set([]byte{0x5a, 0x38})
+	v, err = p.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+}
+
+func TestInputEvents(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	s := newSource(c, []byte{0x0f})
+	defer s.close()
+	ech := make(chan gpiod.LineEvent, 10)
+	eh := func(evt gpiod.LineEvent) {
+		ech <- evt
+	}
+	in, err := shiftreg.NewInput(chip, data, clock, latch, 1,
+		shiftreg.WithTclk(tclk),
+		shiftreg.WithScanPeriod(50*time.Millisecond),
+		shiftreg.WithEventHandler(eh))
+	require.Nil(t, err)
+	defer in.Close()
+
+	// no change, no events
+	waitNoEvent(t, ech)
+
+	s.set([]byte{0x1e})
+	evt := waitEvent(t, ech)
+	assert.Equal(t, 0, evt.Offset)
+	assert.Equal(t, gpiod.LineEventFallingEdge, evt.Type)
+	assert.Equal(t, uint32(1), evt.Seqno)
+	assert.Equal(t, uint32(1), evt.LineSeqno)
+	evt = waitEvent(t, ech)
+	assert.Equal(t, 4, evt.Offset)
+	assert.Equal(t, gpiod.LineEventRisingEdge, evt.Type)
+	assert.Equal(t, uint32(2), evt.Seqno)
+	assert.Equal(t, uint32(1), evt.LineSeqno)
+	waitNoEvent(t, ech)
+
+	// changes detected by a read are reported
+	s.set([]byte{0x1f})
+	v, err := in.Value(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	evt = waitEvent(t, ech)
+	assert.Equal(t, 0, evt.Offset)
+	assert.Equal(t, gpiod.LineEventRisingEdge, evt.Type)
+	assert.Equal(t, uint32(3), evt.Seqno)
+	assert.Equal(t, uint32(2), evt.LineSeqno)
+
+	assert.Nil(t, in.Close())
+	assert.Nil(t, in.Err())
+	s.set([]byte{0})
+	waitNoEvent(t, ech)
+}
+
+func waitEvent(t *testing.T, ch <-chan gpiod.LineEvent) gpiod.LineEvent {
+	t.Helper()
+	select {
+	case evt := <-ch:
+		return evt
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for event")
+	}
+	return gpiod.LineEvent{}
+}
+
+func waitNoEvent(t *testing.T, ch <-chan gpiod.LineEvent) {
+	t.Helper()
+	select {
+	case evt := <-ch:
+		assert.Fail(t, "received unexpected event", evt)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// sink simulates a chain of 74HC595s by polling the mockup lines.
+type sink struct {
+	c *mockup.Chip
+
+	// the shift register, indexed by pin
+	sr []int
+
+	// the latched outputs
+	outputs []byte
+
+	// the number of times the outputs were latched
+	latches int
+
+	err  error
+	done chan struct{}
+	quit chan struct{}
+}
+
+func newSink(c *mockup.Chip, length int) *sink {
+	s := sink{
+		c:       c,
+		sr:      make([]int, length*8),
+		outputs: make([]byte, length),
+		done:    make(chan struct{}),
+		quit:    make(chan struct{}),
+	}
+	go s.run()
+	return &s
+}
+
+// stop stops the sink and returns the latched outputs.
+func (s *sink) stop(t *testing.T) []byte {
+	t.Helper()
+	close(s.quit)
+	<-s.done
+	require.Nil(t, s.err)
+	return s.outputs
+}
+
+func (s *sink) run() {
+	defer close(s.done)
+	var clk, lat int
+	for {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+		var v int
+		if v, s.err = s.c.Value(clock); s.err != nil {
+			return
+		}
+		if v == 1 && clk == 0 {
+			var d int
+			if d, s.err = s.c.Value(data); s.err != nil {
+				return
+			}
+			copy(s.sr[1:], s.sr)
+			s.sr[0] = d
+		}
+		clk = v
+		if v, s.err = s.c.Value(latch); s.err != nil {
+			return
+		}
+		if v == 1 && lat == 0 {
+			for i := range s.outputs {
+				s.outputs[i] = 0
+			}
+			for pin, b := range s.sr {
+				s.outputs[pin/8] |= byte(b << uint(pin%8))
+			}
+			s.latches++
+		}
+		lat = v
+		time.Sleep(50 * time.Microsecond)
+	}
+}
+
+// source simulates a chain of 74HC165s by polling the mockup lines, and
+// driving the data line.
+type source struct {
+	c *mockup.Chip
+
+	mu     sync.Mutex
+	inputs []byte
+
+	done chan struct{}
+	quit chan struct{}
+}
+
+func newSource(c *mockup.Chip, inputs []byte) *source {
+	s := source{
+		c:      c,
+		inputs: append([]byte(nil), inputs...),
+		done:   make(chan struct{}),
+		quit:   make(chan struct{}),
+	}
+	go s.run()
+	return &s
+}
+
+// set sets the levels applied to the parallel inputs.
+func (s *source) set(inputs []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copy(s.inputs, inputs)
+}
+
+func (s *source) close() {
+	close(s.quit)
+	<-s.done
+}
+
+func (s *source) run() {
+	defer close(s.done)
+	// the pins in the order they are shifted out
+	order := make([]int, len(s.inputs)*8)
+	for i := range order {
+		order[i] = i/8*8 + 7 - i%8
+	}
+	var sr []int
+	var clk int
+	for {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+		lat, err := s.c.Value(latch)
+		if err != nil {
+			return
+		}
+		v, err := s.c.Value(clock)
+		if err != nil {
+			return
+		}
+		if lat == 0 {
+			// parallel load
+			s.mu.Lock()
+			sr = sr[:0]
+			for _, pin := range order {
+				sr = append(sr, int(s.inputs[pin/8]>>uint(pin%8))&1)
+			}
+			s.mu.Unlock()
+		} else if v == 1 && clk == 0 && len(sr) > 0 {
+			// shift towards QH, with SER tied low
+			sr = append(sr[1:], 0)
+		}
+		clk = v
+		qh := 0
+		if len(sr) > 0 {
+			qh = sr[0]
+		}
+		if s.c.SetValue(data, qh) != nil {
+			return
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
@@ -2,7 +2,10 @@
 //
 // SPDX-License-Identifier: MIT
 
-// Package adc0832 provides a bit bashed device driver ADC0832s.
+// Package adc0832 provides a device driver for ADC0832s.
+//
+// The ADC may be connected via a bit bashed SPI on GPIO lines, or any other
+// spi.Conn, such as a kernel spidev device.
 package adc0832
 
 import (
@@ -12,148 +15,130 @@ import (
 
 	"github.com/warthog618/gpiod"
 	"github.com/warthog618/gpiod/spi"
+	"github.com/warthog618/gpiod/timing"
 )
 
 // ADC0832 reads ADC values from a connected ADC0832.
 type ADC0832 struct {
 	mu sync.Mutex
-	s  *spi.SPI
+	c  spi.Conn
+	// the bit bashed SPI, if created by New
+	s *spi.SPI
 	// time to allow mux to settle after clocking out ODD/SIGN
-	tset time.Duration
+	tset   time.Duration
+	closed bool
 }
 
-// New creates a ADC0832.
+// New creates a ADC0832 connected to a bit bashed SPI on the GPIO lines.
+//
+// The di and do may be the same line.
 func New(c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*ADC0832, error) {
 	s, err := spi.New(c, clk, csz, di, do, spi.WithTclk(2500*time.Nanosecond))
 	if err != nil {
 		return nil, err
 	}
-	a := ADC0832{s: s}
+	a := ADC0832{c: s, s: s}
 	for _, option := range options {
 		option(&a)
 	}
 	return &a, nil
 }
 
+// NewConn creates a ADC0832 using an SPI connection.
+//
+// The connection must be configured for SPI mode 0 and MSB first.
+//
+// The ADC does not take ownership of the connection, so the connection is not
+// closed when the ADC is closed.
+func NewConn(c spi.Conn, options ...Option) *ADC0832 {
+	a := ADC0832{c: c}
+	for _, option := range options {
+		option(&a)
+	}
+	return &a
+}
+
 // Close releases all resources allocated by the ADC.
 func (adc *ADC0832) Close() error {
 	adc.mu.Lock()
 	defer adc.mu.Unlock()
-	if adc.s == nil {
+	if adc.closed {
 		return ErrClosed
 	}
-	adc.s.Close()
-	adc.s = nil
+	adc.closed = true
+	if adc.s != nil {
+		adc.s.Close()
+	}
 	return nil
 }
 
+// Channels returns the number of channels of the ADC.
+func (adc *ADC0832) Channels() int {
+	return 2
+}
+
+// Resolution returns the number of bits in the values read from the ADC.
+func (adc *ADC0832) Resolution() uint {
+	return 8
+}
+
 // Read returns the value of a single channel read from the ADC.
-func (adc *ADC0832) Read(ch int) (uint8, error) {
+func (adc *ADC0832) Read(ch int) (int, error) {
 	return adc.read(ch, 1)
 }
 
 // ReadDifferential returns the value of a differential pair read from the ADC.
-func (adc *ADC0832) ReadDifferential(ch int) (uint8, error) {
+func (adc *ADC0832) ReadDifferential(ch int) (int, error) {
 	return adc.read(ch, 0)
 }
 
-// ErrClosed indicates the ADC is closed.
-var ErrClosed = errors.New("closed")
-
-func (adc *ADC0832) read(ch int, sgl int) (uint8, error) {
+// TimingStats returns the timing achieved by the delays used to clock the
+// ADC.
+//
+// This is only available for ADCs created by New.
+func (adc *ADC0832) TimingStats() (timing.Stats, error) {
 	adc.mu.Lock()
 	defer adc.mu.Unlock()
-	if adc.s == nil {
-		return 0, ErrClosed
-	}
-
-	err := selectChip(adc.s)
-	if err != nil {
-		return 0, err
-	}
-
-	err = selectChannel(adc.s, sgl, ch)
-	if err != nil {
-		return 0, err
-	}
-
-	// mux settling
-	if adc.s.Mosi == adc.s.Miso {
-		adc.s.Miso.Reconfigure(gpiod.AsInput)
+	if adc.closed {
+		return timing.Stats{}, ErrClosed
 	}
-	time.Sleep(adc.tset)
-	_, err = adc.s.ClockIn() // sample time - junk
-	if err != nil {
-		return 0, err
-	}
-
-	// MSB first byte
-	d, err := adc.clockInData()
-	if err != nil {
-		return 0, err
-	}
-
-	// ignore LSB bits - same as MSB just reversed order
-	err = deselectChip(adc.s)
-	if err != nil {
-		return 0, err
+	if adc.s == nil {
+		return timing.Stats{}, ErrNoTimingStats
 	}
-	return d, nil
+	return adc.s.Delay.Stats(), nil
 }
 
-func selectChip(s *spi.SPI) error {
-	err := s.Ssz.SetValue(1)
-	if err != nil {
-		return err
-	}
-	err = s.Sclk.SetValue(0)
-	if err != nil {
-		return err
-	}
-	if s.Mosi == s.Miso {
-		err = s.Mosi.Reconfigure(gpiod.AsOutput(1))
-	} else {
-		err = s.Mosi.SetValue(1)
-	}
-	if err != nil {
-		return err
-	}
-	time.Sleep(s.Tclk)
-	return s.Ssz.SetValue(0)
-}
+var (
+	// ErrClosed indicates the ADC is closed.
+	ErrClosed = errors.New("closed")
 
-func deselectChip(s *spi.SPI) error {
-	return s.Ssz.SetValue(1)
-}
+	// ErrNoTimingStats indicates the connection to the ADC does not provide
+	// timing stats.
+	ErrNoTimingStats = errors.New("no timing stats")
+)
 
-func selectChannel(s *spi.SPI, sgl, ch int) error {
+func (adc *ADC0832) read(ch int, sgl int) (int, error) {
+	adc.mu.Lock()
+	defer adc.mu.Unlock()
+	if adc.closed {
+		return 0, ErrClosed
+	}
 	odd := 0
 	if ch != 0 {
 		odd = 1
 	}
-	bits := []int{1, sgl, odd} // Start, SGL/DIFZ, ODD/Sign
-	for _, v := range bits {
-		err := s.ClockOut(v)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func (adc *ADC0832) clockInData() (uint8, error) {
-	var d uint8
-	for i := uint(0); i < 8; i++ {
-		v, err := adc.s.ClockIn()
-		if err != nil {
-			return 0, err
-		}
-		d = d << 1
-		if v != 0 {
-			d = d | 0x01
-		}
+	// leading zeros, then Start, SGL/DIFZ, ODD/Sign
+	cmd := []byte{byte(0x04 | sgl<<1 | odd)}
+	// mux settling (junk), then MSB first data.
+	// Ignore LSB first data - same as MSB just reversed order.
+	d := make([]byte, 2)
+	err := adc.c.TxPackets(
+		spi.Packet{W: cmd, Delay: adc.tset},
+		spi.Packet{R: d})
+	if err != nil {
+		return 0, err
 	}
-	return d, nil
+	return int(d[0]<<1 | d[1]>>7), nil
 }
 
 // Option specifies a construction option for the ADC.
@@ -162,9 +147,13 @@ type Option func(*ADC0832)
 // WithTclk sets the clock period for the ADC.
 //
 // Note that this is the half-cycle period.
+//
+// This only applies to ADCs created by New.
 func WithTclk(tclk time.Duration) Option {
 	return func(a *ADC0832) {
-		a.s.Tclk = tclk
+		if a.s != nil {
+			a.s.Tclk = tclk
+		}
 	}
 }
 
@@ -0,0 +1,322 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package spi
+
+import (
+	"sync"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// Bus represents an SPI bus shared by several devices, each with its own slave
+// select line.
+//
+// The Bus owns the clock and data lines, while each Device owns its slave
+// select.  Transactions on the Devices are serialised, so the Devices may be
+// used from different goroutines.
+type Bus struct {
+	// serialises transactions and guards the fields below
+	mu sync.Mutex
+
+	c *gpiod.Chip
+
+	// options applied to each Device before its own
+	options []Option
+
+	sclk *gpiod.Line
+	mosi *gpiod.Line
+	miso *gpiod.Line
+
+	// The request containing all the lines, if they are views.
+	lines *gpiod.Lines
+
+	delay *timing.Delay
+
+	// the polarity sclk is currently configured for
+	cpol int
+
+	// a shared mosi line is currently an output
+	mosiDriven bool
+
+	devices map[*Device]struct{}
+
+	closed bool
+}
+
+// NewBus creates a Bus using the clock and data lines.
+//
+// The options provide the defaults for the Devices on the bus, and may be
+// overridden by the options for each Device.  The timing options apply to the
+// Bus as a whole.
+func NewBus(c *gpiod.Chip, sclk, mosi, miso int, options ...Option) (*Bus, error) {
+	s := SPI{wordSize: 8}
+	for _, option := range options {
+		option(&s)
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	b := Bus{
+		c:       c,
+		options: options,
+		cpol:    s.cpol,
+		devices: make(map[*Device]struct{}),
+	}
+	var err error
+	b.delay, err = timing.NewDelay(s.timingOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if c.UapiAbiVersion() == 1 {
+		// uAPI v1 cannot reconfigure views, as required to change polarity.
+		err = b.requestLines(sclk, mosi, miso)
+	} else {
+		err = b.requestViews(sclk, mosi, miso)
+	}
+	if err != nil {
+		b.release()
+		return nil, err
+	}
+	return &b, nil
+}
+
+// requestViews requests all the lines in a single request, with each signal
+// being a view on that request.
+func (b *Bus) requestViews(sclk, mosi, miso int) error {
+	offsets := []int{sclk, miso}
+	opts := []gpiod.LineReqOption{
+		gpiod.WithLines([]int{sclk}, gpiod.AsOutput(0), sclkLevel(b.cpol)),
+		gpiod.WithLines([]int{miso}, gpiod.AsInput),
+	}
+	if miso != mosi {
+		offsets = append(offsets, mosi)
+		opts = append(opts, gpiod.WithLines([]int{mosi}, gpiod.AsOutput(0)))
+	}
+	var err error
+	b.lines, err = b.c.RequestLines(offsets, opts...)
+	if err != nil {
+		return err
+	}
+	if b.sclk, err = b.lines.Line(sclk); err != nil {
+		return err
+	}
+	if b.miso, err = b.lines.Line(miso); err != nil {
+		return err
+	}
+	if miso == mosi {
+		b.mosi = b.miso
+		return nil
+	}
+	b.mosi, err = b.lines.Line(mosi)
+	return err
+}
+
+// requestLines requests each of the lines separately.
+func (b *Bus) requestLines(sclk, mosi, miso int) error {
+	l, err := b.c.RequestLine(sclk, gpiod.AsOutput(0), sclkLevel(b.cpol))
+	if err != nil {
+		return err
+	}
+	b.sclk = l
+	l, err = b.c.RequestLine(miso, gpiod.AsInput)
+	if err != nil {
+		return err
+	}
+	b.miso = l
+	if miso == mosi {
+		b.mosi = b.miso
+		return nil
+	}
+	l, err = b.c.RequestLine(mosi, gpiod.AsOutput(0))
+	if err != nil {
+		return err
+	}
+	b.mosi = l
+	return nil
+}
+
+// sclkLevel returns the active level of sclk for the polarity, such that the
+// idle state is always a logical 0.
+func sclkLevel(cpol int) gpiod.LevelOption {
+	if cpol != 0 {
+		return gpiod.AsActiveLow
+	}
+	return gpiod.AsActiveHigh
+}
+
+// Close closes the Devices on the Bus, then releases the lines and reverts
+// the outputs to inputs.
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	b.closed = true
+	for d := range b.devices {
+		d.release()
+	}
+	b.devices = nil
+	b.release()
+	return nil
+}
+
+func (b *Bus) release() {
+	if b.sclk != nil {
+		b.sclk.Reconfigure(gpiod.AsInput)
+		b.sclk.Close()
+	}
+	if b.mosi != nil {
+		b.mosi.Reconfigure(gpiod.AsInput)
+		b.mosi.Close()
+	}
+	if b.miso != nil && b.mosi != b.miso {
+		b.miso.Close()
+	}
+	if b.lines != nil {
+		b.lines.Close()
+	}
+	if b.delay != nil {
+		b.delay.Close()
+	}
+}
+
+// Device adds a device to the Bus using the ssz line as its slave select.
+//
+// The options are applied after those of the Bus, so the mode, clock period,
+// word size and bit order may be set for each Device.
+func (b *Bus) Device(ssz int, options ...Option) (*Device, error) {
+	d := Device{bus: b}
+	d.s.wordSize = 8
+	for _, option := range b.options {
+		option(&d.s)
+	}
+	for _, option := range options {
+		option(&d.s)
+	}
+	if d.s.err != nil {
+		return nil, d.s.err
+	}
+	if d.s.Tclk == 0 {
+		// default to 1MHz full cycle.
+		d.s.Tclk = 500 * time.Nanosecond
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	l, err := b.c.RequestLine(ssz, gpiod.AsOutput(1))
+	if err != nil {
+		return nil, err
+	}
+	d.s.Ssz = l
+	d.s.Sclk = b.sclk
+	d.s.Mosi = b.mosi
+	d.s.Miso = b.miso
+	d.s.Delay = b.delay
+	b.devices[&d] = struct{}{}
+	return &d, nil
+}
+
+// setPolarity reconfigures sclk to idle for the polarity.
+//
+// Must be called with the mu held.
+func (b *Bus) setPolarity(cpol int) error {
+	if cpol == b.cpol {
+		return nil
+	}
+	if err := b.sclk.Reconfigure(gpiod.AsOutput(0), sclkLevel(cpol)); err != nil {
+		return err
+	}
+	b.cpol = cpol
+	return nil
+}
+
+// Device represents a device on a Bus.
+type Device struct {
+	bus *Bus
+
+	// the configuration of the device, sharing the lines of the bus
+	s SPI
+
+	closed bool
+}
+
+// Close removes the Device from the Bus and releases its slave select line.
+func (d *Device) Close() error {
+	d.bus.mu.Lock()
+	defer d.bus.mu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	delete(d.bus.devices, d)
+	d.release()
+	return nil
+}
+
+// release reverts the slave select to an input and releases it.
+//
+// Must be called with the bus mu held.
+func (d *Device) release() {
+	d.closed = true
+	d.s.Ssz.Reconfigure(gpiod.AsInput)
+	d.s.Ssz.Close()
+}
+
+// Transfer performs a full duplex transfer of bytes with the device, as per
+// SPI.Transfer.
+//
+// The transfer is performed with exclusive access to the Bus.
+func (d *Device) Transfer(tx, rx []byte) error {
+	return d.transaction(func() error {
+		return d.s.Transfer(tx, rx)
+	})
+}
+
+// Tx performs a full duplex transfer of bytes with the device, as per
+// Transfer.
+func (d *Device) Tx(w, r []byte) error {
+	return d.Transfer(w, r)
+}
+
+// TxPackets performs a sequence of transfers with the device, as per
+// SPI.TxPackets.
+//
+// The transfers are performed with exclusive access to the Bus.
+func (d *Device) TxPackets(packets ...Packet) error {
+	return d.transaction(func() error {
+		return d.s.TxPackets(packets...)
+	})
+}
+
+// TransferWords performs a full duplex transfer of words with the device, as
+// per SPI.TransferWords.
+//
+// The transfer is performed with exclusive access to the Bus.
+func (d *Device) TransferWords(tx, rx []uint32) error {
+	return d.transaction(func() error {
+		return d.s.TransferWords(tx, rx)
+	})
+}
+
+// transaction calls fn with exclusive access to the Bus, and with the clock
+// configured for the Device.
+func (d *Device) transaction(fn func() error) error {
+	d.bus.mu.Lock()
+	defer d.bus.mu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	if err := d.bus.setPolarity(d.s.cpol); err != nil {
+		return err
+	}
+	d.s.mosiDriven = d.bus.mosiDriven
+	err := fn()
+	d.bus.mosiDriven = d.s.mosiDriven
+	return err
+}
@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package spi_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/spi"
+)
+
+// slave select for a second device
+const ssz2 = 4
+
+func TestNewBus(t *testing.T) {
+	b, err := spi.NewBus(nil, sclk, mosi, miso, spi.WithMode(4))
+	assert.Equal(t, spi.ErrInvalidMode, err)
+	assert.Nil(t, b)
+
+	m, c := newMockup(t, 5)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	b, err = spi.NewBus(chip, sclk, mosi, miso, spi.WithMode(2))
+	require.Nil(t, err)
+	require.NotNil(t, b)
+	// idle for the bus mode
+	checkLine(t, c, sclk, 1)
+
+	// lines are busy
+	b2, err := spi.NewBus(chip, sclk, mosi, miso)
+	assert.NotNil(t, err)
+	assert.Nil(t, b2)
+
+	d, err := b.Device(ssz, spi.WithWordSize(0))
+	assert.Equal(t, spi.ErrInvalidWordSize, err)
+	assert.Nil(t, d)
+
+	// ssz is a bus line
+	d, err = b.Device(sclk)
+	assert.NotNil(t, err)
+	assert.Nil(t, d)
+
+	d, err = b.Device(ssz)
+	require.Nil(t, err)
+	checkLine(t, c, ssz, 1)
+	assert.Nil(t, d.Close())
+	assert.Equal(t, spi.ErrClosed, d.Close())
+	assert.Equal(t, spi.ErrClosed, d.Transfer([]byte{1}, nil))
+
+	d, err = b.Device(ssz)
+	require.Nil(t, err)
+	assert.Nil(t, b.Close())
+	assert.Equal(t, spi.ErrClosed, b.Close())
+	// devices are closed with the bus
+	assert.Equal(t, spi.ErrClosed, d.Transfer([]byte{1}, nil))
+	d, err = b.Device(ssz2)
+	assert.Equal(t, spi.ErrClosed, err)
+	assert.Nil(t, d)
+}
+
+func TestBusDevices(t *testing.T) {
+	m, c := newMockup(t, 5)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	b, err := spi.NewBus(chip, sclk, mosi, miso, spi.WithTclk(tclk))
+	require.Nil(t, err)
+	defer b.Close()
+
+	d1, err := b.Device(ssz)
+	require.Nil(t, err)
+	d2, err := b.Device(ssz2, spi.WithMode(3), spi.WithWordSize(12), spi.WithLSBFirst())
+	require.Nil(t, err)
+
+	// each device with its own mode
+	sl := newSlave(c, ssz, 0, 8, false, []uint32{0x96})
+	rx := make([]byte, 1)
+	assert.Nil(t, d1.Transfer([]byte{0x69}, rx))
+	assert.Equal(t, []byte{0x96}, rx)
+	assert.Equal(t, []uint32{0x69}, sl.wait(t))
+	checkLine(t, c, sclk, 0)
+
+	sl = newSlave(c, ssz2, 3, 12, true, []uint32{0x321})
+	rxw := make([]uint32, 1)
+	assert.Nil(t, d2.TransferWords([]uint32{0xabc}, rxw))
+	assert.Equal(t, []uint32{0x321}, rxw)
+	assert.Equal(t, []uint32{0xabc}, sl.wait(t))
+	checkLine(t, c, sclk, 1)
+
+	// concurrent transactions are serialised
+	sl1 := newSlave(c, ssz, 0, 8, false, []uint32{0x11, 0x22})
+	sl2 := newSlave(c, ssz2, 3, 12, true, []uint32{0x333})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	rx1 := make([]byte, 2)
+	go func() {
+		defer wg.Done()
+		assert.Nil(t, d1.Tx([]byte{0x44, 0x55}, rx1))
+	}()
+	go func() {
+		defer wg.Done()
+		assert.Nil(t, d2.TransferWords([]uint32{0x666}, rxw))
+	}()
+	wg.Wait()
+	assert.Equal(t, []byte{0x11, 0x22}, rx1)
+	assert.Equal(t, []uint32{0x333}, rxw)
+	assert.Equal(t, []uint32{0x44, 0x55}, sl1.wait(t))
+	assert.Equal(t, []uint32{0x666}, sl2.wait(t))
+	checkLine(t, c, ssz, 1)
+	checkLine(t, c, ssz2, 1)
+}
@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package spi
+
+import "time"
+
+// Conn is a connection to an SPI device.
+//
+// It is implemented by the bit bashed SPI and Device, and by the kernel
+// spidev backend in the spidev package, so device drivers written against it
+// can be prototyped on GPIO lines and deployed on a hardware SPI controller.
+type Conn interface {
+	// Tx performs a full duplex transfer of bytes with the device.
+	//
+	// The length of the transfer is the longer of w and r, with zeros written
+	// after the end of w, and bytes read after the end of r discarded.
+	// Either may be nil.
+	Tx(w, r []byte) error
+
+	// TxPackets performs a sequence of transfers with the device selected
+	// throughout.
+	TxPackets(packets ...Packet) error
+}
+
+// Packet is one part of a transaction performed by Conn.TxPackets.
+type Packet struct {
+	// The bytes to write.
+	//
+	// If nil then the packet is a read, and MOSI is not driven on connections
+	// that share MOSI and MISO.
+	W []byte
+
+	// The buffer to read into.
+	//
+	// If nil then the packet is a write, and the bytes read are discarded.
+	R []byte
+
+	// The time to wait after the packet, while the device remains selected.
+	Delay time.Duration
+}
@@ -2,8 +2,14 @@
 //
 // SPDX-License-Identifier: MIT
 
-// Package mcp3w0c provides bit bashed device drivers for MCP3004/3008/3204/3208
+// Package mcp3w0c provides device drivers for the Microchip MCP3xxx family of
 // SPI ADCs.
+//
+// Supported variants are MCP3002/3004/3008, MCP3202/3204/3208 and
+// MCP3301/3302/3304.
+//
+// The ADC may be connected via a bit bashed SPI on GPIO lines, or any other
+// spi.Conn, such as a kernel spidev device.
 package mcp3w0c
 
 import (
@@ -13,164 +19,356 @@ import (
 
 	"github.com/warthog618/gpiod"
 	"github.com/warthog618/gpiod/spi"
+	"github.com/warthog618/gpiod/timing"
 )
 
+// Model identifies a variant of the MCP3xxx family.
+type Model int
+
+const (
+	// MCP3002 is a 10-bit ADC with 2 channels.
+	MCP3002 Model = iota + 1
+
+	// MCP3004 is a 10-bit ADC with 4 channels.
+	MCP3004
+
+	// MCP3008 is a 10-bit ADC with 8 channels.
+	MCP3008
+
+	// MCP3202 is a 12-bit ADC with 2 channels.
+	MCP3202
+
+	// MCP3204 is a 12-bit ADC with 4 channels.
+	MCP3204
+
+	// MCP3208 is a 12-bit ADC with 8 channels.
+	MCP3208
+
+	// MCP3301 is a 13-bit signed ADC with a single differential input.
+	MCP3301
+
+	// MCP3302 is a 13-bit signed ADC with 4 channels.
+	MCP3302
+
+	// MCP3304 is a 13-bit signed ADC with 8 channels.
+	MCP3304
+)
+
+var modelNames = map[Model]string{
+	MCP3002: "MCP3002",
+	MCP3004: "MCP3004",
+	MCP3008: "MCP3008",
+	MCP3202: "MCP3202",
+	MCP3204: "MCP3204",
+	MCP3208: "MCP3208",
+	MCP3301: "MCP3301",
+	MCP3302: "MCP3302",
+	MCP3304: "MCP3304",
+}
+
+func (m Model) String() string {
+	if n, ok := modelNames[m]; ok {
+		return n
+	}
+	return "unknown"
+}
+
+// framing describes how a conversion is requested from, and read back from, a
+// variant.
+type framing struct {
+	// number of single ended channels
+	channels int
+
+	// number of differential pairs
+	pairs int
+
+	// number of bits in the result, including any sign bit
+	width uint
+
+	// result is two's complement in differential mode
+	signed bool
+
+	// the command is START, SGL/DIFF, ODD/SIGN, MSBF rather than
+	// START, SGL/DIFF, D2, D1, D0.
+	oddSign bool
+
+	// the device has no DIN and so no command
+	noCommand bool
+
+	// number of clocks between the command and the first data bit
+	skip uint
+}
+
+var framings = map[Model]framing{
+	MCP3002: {channels: 2, pairs: 2, width: 10, oddSign: true, skip: 1},
+	MCP3004: {channels: 4, pairs: 4, width: 10, skip: 2},
+	MCP3008: {channels: 8, pairs: 8, width: 10, skip: 2},
+	MCP3202: {channels: 2, pairs: 2, width: 12, oddSign: true, skip: 1},
+	MCP3204: {channels: 4, pairs: 4, width: 12, skip: 2},
+	MCP3208: {channels: 8, pairs: 8, width: 12, skip: 2},
+	MCP3301: {pairs: 1, width: 13, signed: true, noCommand: true, skip: 3},
+	MCP3302: {channels: 4, pairs: 4, width: 13, signed: true, skip: 2},
+	MCP3304: {channels: 8, pairs: 8, width: 13, signed: true, skip: 2},
+}
+
 // MCP3w0c reads ADC values from a connected Microchip MCP3xxx family device.
 //
-// Supported variants are MCP3004/3008/3204/3208.
-// The w indicates the width of the device (0 => 10, 2 => 12)
+// The w indicates the width of the device (0 => 10, 2 => 12, 3 => 13)
 // and the c the number of channels.
 type MCP3w0c struct {
-	mu    sync.Mutex
-	s     *spi.SPI
-	width uint
+	mu sync.Mutex
+	c  spi.Conn
+	// the bit bashed SPI, if created by New
+	s *spi.SPI
+	f framing
 	// time to allow mux to settle after clocking out channel
-	tset time.Duration
+	tset   time.Duration
+	closed bool
 }
 
-// New creates a MCP3w0c.
+// New creates a MCP3w0c connected to a bit bashed SPI on the GPIO lines.
+//
+// The device is assumed to have 8 channels and the framing of the
+// MCP3004/3008/3204/3208.  The typed constructors, such as NewMCP3008, should
+// be used for other variants.
+//
+// The di and do may be the same line.
 func New(c *gpiod.Chip, clk, csz, di, do int, width uint, options ...Option) (*MCP3w0c, error) {
+	return newADC(framing{channels: 8, pairs: 8, width: width, skip: 2},
+		c, clk, csz, di, do, options...)
+}
+
+// NewModel creates a MCP3w0c for the variant connected to a bit bashed SPI on
+// the GPIO lines.
+//
+// The di and do may be the same line, other than for the MCP3301 which has no
+// di.
+func NewModel(m Model, c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*MCP3w0c, error) {
+	f, ok := framings[m]
+	if !ok {
+		return nil, ErrInvalidModel
+	}
+	return newADC(f, c, clk, csz, di, do, options...)
+}
+
+func newADC(f framing, c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*MCP3w0c, error) {
 	s, err := spi.New(c, clk, csz, di, do, spi.WithTclk(500*time.Nanosecond))
 	if err != nil {
 		return nil, err
 	}
-	a := MCP3w0c{s: s, width: width}
+	a := MCP3w0c{c: s, s: s, f: f}
 	for _, option := range options {
 		option(&a)
 	}
 	return &a, nil
 }
 
+// NewMCP3002 creates a MCP3002.
+func NewMCP3002(c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*MCP3w0c, error) {
+	return NewModel(MCP3002, c, clk, csz, di, do, options...)
+}
+
+// NewMCP3004 creates a MCP3004.
+func NewMCP3004(c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*MCP3w0c, error) {
+	return NewModel(MCP3004, c, clk, csz, di, do, options...)
+}
+
 // NewMCP3008 creates a MCP3008.
 func NewMCP3008(c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*MCP3w0c, error) {
-	return New(c, clk, csz, di, do, 10, options...)
+	return NewModel(MCP3008, c, clk, csz, di, do, options...)
+}
+
+// NewMCP3202 creates a MCP3202.
+func NewMCP3202(c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*MCP3w0c, error) {
+	return NewModel(MCP3202, c, clk, csz, di, do, options...)
+}
+
+// NewMCP3204 creates a MCP3204.
+func NewMCP3204(c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*MCP3w0c, error) {
+	return NewModel(MCP3204, c, clk, csz, di, do, options...)
 }
 
 // NewMCP3208 creates a MCP3208.
 func NewMCP3208(c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*MCP3w0c, error) {
-	return New(c, clk, csz, di, do, 12, options...)
+	return NewModel(MCP3208, c, clk, csz, di, do, options...)
+}
+
+// NewMCP3301 creates a MCP3301.
+//
+// The MCP3301 has no di, so the do line is also passed as the di.
+func NewMCP3301(c *gpiod.Chip, clk, csz, do int, options ...Option) (*MCP3w0c, error) {
+	return NewModel(MCP3301, c, clk, csz, do, do, options...)
+}
+
+// NewMCP3302 creates a MCP3302.
+func NewMCP3302(c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*MCP3w0c, error) {
+	return NewModel(MCP3302, c, clk, csz, di, do, options...)
+}
+
+// NewMCP3304 creates a MCP3304.
+func NewMCP3304(c *gpiod.Chip, clk, csz, di, do int, options ...Option) (*MCP3w0c, error) {
+	return NewModel(MCP3304, c, clk, csz, di, do, options...)
+}
+
+// NewConn creates a MCP3w0c using an SPI connection.
+//
+// As per New, the device is assumed to have 8 channels and the framing of the
+// MCP3004/3008/3204/3208.  NewModelConn should be used for other variants.
+//
+// The connection must be configured for SPI mode 0 and MSB first.
+//
+// The ADC does not take ownership of the connection, so the connection is not
+// closed when the ADC is closed.
+func NewConn(c spi.Conn, width uint, options ...Option) *MCP3w0c {
+	a := MCP3w0c{c: c, f: framing{channels: 8, pairs: 8, width: width, skip: 2}}
+	for _, option := range options {
+		option(&a)
+	}
+	return &a
+}
+
+// NewModelConn creates a MCP3w0c for the variant using an SPI connection.
+//
+// The connection must be configured for SPI mode 0 and MSB first.
+//
+// The ADC does not take ownership of the connection, so the connection is not
+// closed when the ADC is closed.
+func NewModelConn(m Model, c spi.Conn, options ...Option) (*MCP3w0c, error) {
+	f, ok := framings[m]
+	if !ok {
+		return nil, ErrInvalidModel
+	}
+	a := MCP3w0c{c: c, f: f}
+	for _, option := range options {
+		option(&a)
+	}
+	return &a, nil
 }
 
 // Close releases all resources allocated to the ADC.
 func (adc *MCP3w0c) Close() error {
 	adc.mu.Lock()
 	defer adc.mu.Unlock()
-	if adc.s == nil {
+	if adc.closed {
 		return ErrClosed
 	}
-	adc.s.Close()
-	adc.s = nil
+	adc.closed = true
+	if adc.s != nil {
+		adc.s.Close()
+	}
 	return nil
 }
 
-// Read returns the value of a single channel read from the ADC.
-func (adc *MCP3w0c) Read(ch int) (uint16, error) {
-	return adc.read(ch, 1)
+// Channels returns the number of single ended channels of the ADC.
+func (adc *MCP3w0c) Channels() int {
+	return adc.f.channels
 }
 
-// ReadDifferential returns the value of a differential pair read from the ADC.
-func (adc *MCP3w0c) ReadDifferential(ch int) (uint16, error) {
-	return adc.read(ch, 0)
+// Pairs returns the number of differential pair configurations of the ADC.
+func (adc *MCP3w0c) Pairs() int {
+	return adc.f.pairs
 }
 
-// ErrClosed indicates the ADC is closed.
-var ErrClosed = errors.New("closed")
-
-func (adc *MCP3w0c) read(ch int, sgl int) (uint16, error) {
-	adc.mu.Lock()
-	defer adc.mu.Unlock()
-	if adc.s == nil {
-		return 0, ErrClosed
-	}
-	err := selectChip(adc.s)
-	if err != nil {
-		return 0, err
-	}
+// Width returns the number of bits in the values read from the ADC, including
+// the sign bit of signed variants.
+func (adc *MCP3w0c) Width() uint {
+	return adc.f.width
+}
 
-	err = selectChannel(adc.s, sgl, ch)
-	if err != nil {
-		return 0, err
+// Resolution returns the number of bits in the values read from single ended
+// channels, i.e. the Width less any sign bit.
+func (adc *MCP3w0c) Resolution() uint {
+	if adc.f.signed {
+		return adc.f.width - 1
 	}
+	return adc.f.width
+}
 
-	// mux settling
-	if adc.s.Mosi == adc.s.Miso {
-		adc.s.Miso.Reconfigure(gpiod.AsInput)
-	}
-	time.Sleep(adc.tset)
-	_, err = adc.s.ClockIn() // sample time - junk
-	if err != nil {
-		return 0, err
+// Read returns the value of a single channel read from the ADC.
+func (adc *MCP3w0c) Read(ch int) (int, error) {
+	if ch < 0 || ch >= adc.f.channels {
+		return 0, ErrInvalidChannel
 	}
+	return adc.read(ch, 1)
+}
 
-	_, err = adc.s.ClockIn() // null bit
-	if err != nil {
-		return 0, err
+// ReadDifferential returns the value of a differential pair read from the ADC.
+//
+// The pair selects both the channels and their polarity, e.g. for the
+// MCP3008 pair 0 is CH0 as IN+ and CH1 as IN-, while pair 1 is CH0 as IN- and
+// CH1 as IN+.
+//
+// For the signed MCP3301/3302/3304 the value is the raw two's complement code,
+// and ReadSigned should be used instead.
+func (adc *MCP3w0c) ReadDifferential(pair int) (int, error) {
+	if pair < 0 || pair >= adc.f.pairs {
+		return 0, ErrInvalidPair
 	}
+	return adc.read(pair, 0)
+}
 
-	d, err := adc.clockInData()
-	if err != nil {
-		return 0, err
+// ReadSigned returns the value of a differential pair read from the ADC, as
+// per ReadDifferential, sign extended for the signed variants.
+func (adc *MCP3w0c) ReadSigned(pair int) (int, error) {
+	v, err := adc.ReadDifferential(pair)
+	if err != nil || !adc.f.signed {
+		return v, err
 	}
-
-	err = deselectChip(adc.s)
-	if err != nil {
-		return 0, err
+	if v&(1<<(adc.f.width-1)) != 0 {
+		v -= 1 << adc.f.width
 	}
-	return d, nil
+	return v, nil
 }
 
-func selectChip(s *spi.SPI) error {
-	err := s.Ssz.SetValue(1)
-	if err != nil {
-		return err
-	}
-	err = s.Sclk.SetValue(0)
-	if err != nil {
-		return err
-	}
-	if s.Mosi == s.Miso {
-		err = s.Mosi.Reconfigure(gpiod.AsOutput(1))
-	} else {
-		err = s.Mosi.SetValue(1)
+// TimingStats returns the timing achieved by the delays used to clock the
+// ADC.
+//
+// This is only available for ADCs created by New or the typed constructors.
+func (adc *MCP3w0c) TimingStats() (timing.Stats, error) {
+	adc.mu.Lock()
+	defer adc.mu.Unlock()
+	if adc.closed {
+		return timing.Stats{}, ErrClosed
 	}
-	if err != nil {
-		return err
+	if adc.s == nil {
+		return timing.Stats{}, ErrNoTimingStats
 	}
-	time.Sleep(s.Tclk)
-	return s.Ssz.SetValue(0)
-}
-
-func deselectChip(s *spi.SPI) error {
-	return s.Ssz.SetValue(1)
+	return adc.s.Delay.Stats(), nil
 }
 
-func selectChannel(s *spi.SPI, sgl, ch int) error {
-	bits := []int{1, sgl, 0, 0, 0} // START, SGL/DIFFZ, CH.2, CH.1, CH.0
-	for i := 0; i <= 2; i++ {
-		bits[4-i] = (ch >> uint(i) & 0x01)
+func (adc *MCP3w0c) read(ch int, sgl int) (int, error) {
+	adc.mu.Lock()
+	defer adc.mu.Unlock()
+	if adc.closed {
+		return 0, ErrClosed
 	}
-	for _, b := range bits {
-		err := s.ClockOut(b)
-		if err != nil {
-			return err
+	var packets []spi.Packet
+	if !adc.f.noCommand {
+		// leading zeros, then the command
+		var cmd byte
+		if adc.f.oddSign {
+			// START, SGL/DIFF, ODD/SIGN, MSBF
+			cmd = byte(0x09 | sgl<<2 | ch<<1)
+		} else {
+			// START, SGL/DIFFZ, CH.2, CH.1, CH.0
+			cmd = byte(0x10 | sgl<<3 | ch&0x07)
 		}
+		packets = append(packets, spi.Packet{W: []byte{cmd}, Delay: adc.tset})
 	}
-	return nil
-}
-
-func (adc *MCP3w0c) clockInData() (uint16, error) {
-	var d uint16
-	for i := uint(0); i < adc.width; i++ {
-		v, err := adc.s.ClockIn()
-		if err != nil {
-			return 0, err
-		}
-		d = d << 1
-		if v != 0 {
-			d = d | 0x01
-		}
+	// sample time and null bit, then MSB first data
+	bits := adc.f.skip + adc.f.width
+	d := make([]byte, (bits+7)/8)
+	packets = append(packets, spi.Packet{R: d})
+	err := adc.c.TxPackets(packets...)
+	if err != nil {
+		return 0, err
 	}
-	return d, nil
+	var v uint32
+	for _, b := range d {
+		v = v<<8 | uint32(b)
+	}
+	v >>= uint(len(d))*8 - bits
+	return int(v & (1<<adc.f.width - 1)), nil
 }
 
 // Option specifies a construction option for the ADC.
@@ -179,15 +377,41 @@ type Option func(*MCP3w0c)
 // WithTclk sets the clock period for the ADC.
 //
 // Note that this is the half-cycle period.
+//
+// This only applies to ADCs created by New or the typed constructors.
 func WithTclk(tclk time.Duration) Option {
 	return func(a *MCP3w0c) {
-		a.s.Tclk = tclk
+		if a.s != nil {
+			a.s.Tclk = tclk
+		}
 	}
 }
 
 // WithTset sets the settling period for the ADC.
+//
+// This is the delay between clocking out the channel and sampling, so it has
+// no effect on the MCP3301.
 func WithTset(tset time.Duration) Option {
 	return func(a *MCP3w0c) {
 		a.tset = tset
 	}
 }
+
+var (
+	// ErrClosed indicates the ADC is closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrInvalidChannel indicates the channel is not supported by the ADC.
+	ErrInvalidChannel = errors.New("invalid channel")
+
+	// ErrInvalidModel indicates the model is not a supported MCP3xxx variant.
+	ErrInvalidModel = errors.New("invalid model")
+
+	// ErrInvalidPair indicates the differential pair is not supported by the
+	// ADC.
+	ErrInvalidPair = errors.New("invalid pair")
+
+	// ErrNoTimingStats indicates the connection to the ADC does not provide
+	// timing stats.
+	ErrNoTimingStats = errors.New("no timing stats")
+)
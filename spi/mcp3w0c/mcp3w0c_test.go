@@ -0,0 +1,359 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package mcp3w0c_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod/spi"
+	"github.com/warthog618/gpiod/spi/mcp3w0c"
+)
+
+func TestNewModelConn(t *testing.T) {
+	a, err := mcp3w0c.NewModelConn(mcp3w0c.Model(0), &device{})
+	assert.Equal(t, mcp3w0c.ErrInvalidModel, err)
+	assert.Nil(t, a)
+	assert.Equal(t, "unknown", mcp3w0c.Model(0).String())
+
+	patterns := []struct {
+		model    mcp3w0c.Model
+		channels int
+		pairs    int
+		width    uint
+		res      uint
+	}{
+		{mcp3w0c.MCP3002, 2, 2, 10, 10},
+		{mcp3w0c.MCP3004, 4, 4, 10, 10},
+		{mcp3w0c.MCP3008, 8, 8, 10, 10},
+		{mcp3w0c.MCP3202, 2, 2, 12, 12},
+		{mcp3w0c.MCP3204, 4, 4, 12, 12},
+		{mcp3w0c.MCP3208, 8, 8, 12, 12},
+		{mcp3w0c.MCP3301, 0, 1, 13, 12},
+		{mcp3w0c.MCP3302, 4, 4, 13, 12},
+		{mcp3w0c.MCP3304, 8, 8, 13, 12},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			a, err := mcp3w0c.NewModelConn(p.model, &device{})
+			require.Nil(t, err)
+			assert.Equal(t, p.channels, a.Channels())
+			assert.Equal(t, p.pairs, a.Pairs())
+			assert.Equal(t, p.width, a.Width())
+			assert.Equal(t, p.res, a.Resolution())
+
+			_, err = a.Read(-1)
+			assert.Equal(t, mcp3w0c.ErrInvalidChannel, err)
+			_, err = a.Read(p.channels)
+			assert.Equal(t, mcp3w0c.ErrInvalidChannel, err)
+			_, err = a.ReadDifferential(-1)
+			assert.Equal(t, mcp3w0c.ErrInvalidPair, err)
+			_, err = a.ReadSigned(p.pairs)
+			assert.Equal(t, mcp3w0c.ErrInvalidPair, err)
+
+			_, err = a.TimingStats()
+			assert.Equal(t, mcp3w0c.ErrNoTimingStats, err)
+			assert.Nil(t, a.Close())
+			assert.Equal(t, mcp3w0c.ErrClosed, a.Close())
+			_, err = a.ReadDifferential(0)
+			assert.Equal(t, mcp3w0c.ErrClosed, err)
+		}
+		t.Run(p.model.String(), tf)
+	}
+}
+
+func TestRead(t *testing.T) {
+	patterns := []struct {
+		model mcp3w0c.Model
+		// the full command clocked out, from the start bit, for channel 1
+		cmd []int
+	}{
+		{mcp3w0c.MCP3002, []int{1, 1, 1, 1}},
+		{mcp3w0c.MCP3004, []int{1, 1, 0, 0, 1}},
+		{mcp3w0c.MCP3008, []int{1, 1, 0, 0, 1}},
+		{mcp3w0c.MCP3202, []int{1, 1, 1, 1}},
+		{mcp3w0c.MCP3204, []int{1, 1, 0, 0, 1}},
+		{mcp3w0c.MCP3208, []int{1, 1, 0, 0, 1}},
+		{mcp3w0c.MCP3302, []int{1, 1, 0, 0, 1}},
+		{mcp3w0c.MCP3304, []int{1, 1, 0, 0, 1}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			d := newDevice(p.model)
+			a, err := mcp3w0c.NewModelConn(p.model, d, mcp3w0c.WithTset(time.Microsecond))
+			require.Nil(t, err)
+
+			for ch := 0; ch < a.Channels(); ch++ {
+				v, err := a.Read(ch)
+				assert.Nil(t, err)
+				assert.Equal(t, int(d.single[ch]), v, ch)
+				assert.Equal(t, ch, d.ch)
+				assert.Equal(t, 1, d.sgl)
+			}
+			_, err = a.Read(1)
+			require.Nil(t, err)
+			assert.Equal(t, p.cmd, d.cmd)
+			// settling occurs after the command
+			assert.Equal(t, time.Microsecond, d.cmdDelay)
+		}
+		t.Run(p.model.String(), tf)
+	}
+}
+
+func TestReadDifferential(t *testing.T) {
+	patterns := []struct {
+		model mcp3w0c.Model
+		// the full command clocked out, from the start bit, for pair 1
+		cmd []int
+	}{
+		{mcp3w0c.MCP3002, []int{1, 0, 1, 1}},
+		{mcp3w0c.MCP3008, []int{1, 0, 0, 0, 1}},
+		{mcp3w0c.MCP3202, []int{1, 0, 1, 1}},
+		{mcp3w0c.MCP3204, []int{1, 0, 0, 0, 1}},
+		{mcp3w0c.MCP3301, nil},
+		{mcp3w0c.MCP3302, []int{1, 0, 0, 0, 1}},
+		{mcp3w0c.MCP3304, []int{1, 0, 0, 0, 1}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			d := newDevice(p.model)
+			a, err := mcp3w0c.NewModelConn(p.model, d)
+			require.Nil(t, err)
+
+			for pair := 0; pair < a.Pairs(); pair++ {
+				v, err := a.ReadDifferential(pair)
+				assert.Nil(t, err)
+				assert.Equal(t, int(d.diff[pair]), v, pair)
+				s, err := a.ReadSigned(pair)
+				assert.Nil(t, err)
+				assert.Equal(t, d.signed(pair), s, pair)
+				if p.model != mcp3w0c.MCP3301 {
+					assert.Equal(t, pair, d.ch)
+					assert.Equal(t, 0, d.sgl)
+				}
+			}
+			if a.Pairs() > 1 {
+				_, err = a.ReadDifferential(1)
+				require.Nil(t, err)
+				assert.Equal(t, p.cmd, d.cmd)
+			}
+		}
+		t.Run(p.model.String(), tf)
+	}
+}
+
+func TestReadSigned(t *testing.T) {
+	d := newDevice(mcp3w0c.MCP3304)
+	a, err := mcp3w0c.NewModelConn(mcp3w0c.MCP3304, d)
+	require.Nil(t, err)
+
+	for _, v := range []int{-4096, -1, 0, 1, 4095} {
+		d.diff[2] = uint16(v) & 0x1fff
+		s, err := a.ReadSigned(2)
+		assert.Nil(t, err)
+		assert.Equal(t, v, s)
+	}
+
+	// conn errors are returned
+	d.err = errors.New("conn failed")
+	_, err = a.ReadSigned(2)
+	assert.Equal(t, d.err, err)
+}
+
+func TestNewConn(t *testing.T) {
+	// the legacy constructor uses MCP3004/3008/3204/3208 framing.
+	d := newDevice(mcp3w0c.MCP3208)
+	a := mcp3w0c.NewConn(d, 12)
+	assert.Equal(t, 8, a.Channels())
+	v, err := a.Read(5)
+	assert.Nil(t, err)
+	assert.Equal(t, int(d.single[5]), v)
+	assert.Equal(t, []int{1, 1, 1, 0, 1}, d.cmd)
+}
+
+// device simulates an MCP3xxx at the bit level.
+//
+// The bits clocked out are decoded as per the datasheets, and the data clocked
+// in is framed as per the datasheets, with any bits not driven by the device
+// returned as ones.
+type device struct {
+	f deviceFraming
+
+	// the values returned for each single ended channel and differential pair
+	single []uint16
+	diff   []uint16
+
+	// the command from the last conversion
+	cmd []int
+	sgl int
+	ch  int
+
+	// the delay following the command
+	cmdDelay time.Duration
+
+	err error
+}
+
+type deviceFraming struct {
+	// number of command bits after the start bit, or 0 if there is no DIN
+	cmdBits int
+	// clocks after the command, or after CS if no command, before the null
+	// bit
+	sample int
+	width  int
+}
+
+func newDevice(m mcp3w0c.Model) *device {
+	var f deviceFraming
+	var n int
+	switch m {
+	case mcp3w0c.MCP3002, mcp3w0c.MCP3202:
+		// SGL/DIFF, ODD/SIGN, MSBF, then null
+		f = deviceFraming{cmdBits: 3, sample: 0}
+		n = 2
+	case mcp3w0c.MCP3004, mcp3w0c.MCP3204, mcp3w0c.MCP3302:
+		// SGL/DIFF, D2, D1, D0, then sample, null
+		f = deviceFraming{cmdBits: 4, sample: 1}
+		n = 4
+	case mcp3w0c.MCP3008, mcp3w0c.MCP3208, mcp3w0c.MCP3304:
+		f = deviceFraming{cmdBits: 4, sample: 1}
+		n = 8
+	case mcp3w0c.MCP3301:
+		// 1.5 clocks of sample, then null
+		f = deviceFraming{sample: 2}
+		n = 1
+	}
+	switch m {
+	case mcp3w0c.MCP3002, mcp3w0c.MCP3004, mcp3w0c.MCP3008:
+		f.width = 10
+	case mcp3w0c.MCP3202, mcp3w0c.MCP3204, mcp3w0c.MCP3208:
+		f.width = 12
+	default:
+		f.width = 13
+	}
+	d := device{f: f, single: make([]uint16, n), diff: make([]uint16, n)}
+	mask := uint16(1)<<uint(f.width) - 1
+	for i := 0; i < n; i++ {
+		// distinct values with the high and low bits set
+		d.single[i] = (mask - uint16(i)*0x12) | 1 | 1<<uint(f.width-2)
+		d.diff[i] = mask - uint16(i)*0x34
+	}
+	if m == mcp3w0c.MCP3302 || m == mcp3w0c.MCP3304 {
+		// single ended values are positive
+		for i := range d.single {
+			d.single[i] &= mask >> 1
+		}
+	}
+	return &d
+}
+
+func (d *device) signed(pair int) int {
+	v := int(d.diff[pair])
+	if d.f.width == 13 && v&(1<<12) != 0 {
+		return v - 1<<13
+	}
+	return v
+}
+
+func (d *device) Tx(w, r []byte) error {
+	return d.TxPackets(spi.Packet{W: w, R: r})
+}
+
+func (d *device) TxPackets(packets ...spi.Packet) error {
+	if d.err != nil {
+		return d.err
+	}
+	// the bits clocked out, and the delay after each
+	var mosi []int
+	delays := map[int]time.Duration{}
+	for _, p := range packets {
+		n := len(p.W)
+		if len(p.R) > n {
+			n = len(p.R)
+		}
+		for i := 0; i < n*8; i++ {
+			v := 0
+			if i/8 < len(p.W) {
+				v = int(p.W[i/8]>>(7-uint(i%8))) & 1
+			}
+			mosi = append(mosi, v)
+		}
+		delays[len(mosi)] = p.Delay
+	}
+	miso, err := d.convert(mosi, delays)
+	if err != nil {
+		return err
+	}
+	clk := 0
+	for _, p := range packets {
+		n := len(p.W)
+		if len(p.R) > n {
+			n = len(p.R)
+		}
+		for i := 0; i < n; i++ {
+			var b byte
+			for j := 0; j < 8; j++ {
+				b = b<<1 | byte(miso[clk])
+				clk++
+			}
+			if i < len(p.R) {
+				p.R[i] = b
+			}
+		}
+	}
+	return nil
+}
+
+// convert returns the bits clocked in for the bits clocked out.
+func (d *device) convert(mosi []int, delays map[int]time.Duration) ([]int, error) {
+	miso := make([]int, len(mosi))
+	for i := range miso {
+		miso[i] = 1
+	}
+	clk := 0
+	d.cmd = nil
+	d.cmdDelay = 0
+	if d.f.cmdBits != 0 {
+		// the start bit is the first high bit clocked out
+		for clk < len(mosi) && mosi[clk] == 0 {
+			clk++
+		}
+		end := clk + 1 + d.f.cmdBits
+		if end > len(mosi) {
+			return nil, fmt.Errorf("incomplete command: %v", mosi[clk:])
+		}
+		d.cmd = append([]int(nil), mosi[clk:end]...)
+		d.cmdDelay = delays[end]
+		clk = end
+		d.sgl = d.cmd[1]
+		if d.f.cmdBits == 3 {
+			if d.cmd[3] != 1 {
+				return nil, fmt.Errorf("MSBF not set: %v", d.cmd)
+			}
+			d.ch = d.cmd[2]
+		} else {
+			d.ch = d.cmd[2]<<2 | d.cmd[3]<<1 | d.cmd[4]
+		}
+	}
+	clk += d.f.sample
+	if clk+1+d.f.width > len(miso) {
+		return nil, fmt.Errorf("incomplete read: %d clocks", len(miso))
+	}
+	miso[clk] = 0 // null bit
+	clk++
+	v := d.single[d.ch]
+	if d.sgl == 0 {
+		v = d.diff[d.ch]
+	}
+	for i := d.f.width - 1; i >= 0; i-- {
+		miso[clk] = int(v>>uint(i)) & 1
+		clk++
+	}
+	return miso, nil
+}
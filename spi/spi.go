@@ -9,13 +9,16 @@
 //
 // The package is not related to the SPI device drivers provided by the Linux
 // kernel.  Kernel device drivers are the preferred solution for production
-// applications.
+// applications.  Device drivers written against Conn may use either the bit
+// bashed SPI or a kernel spidev device, via the spidev package.
 package spi
 
 import (
+	"errors"
 	"time"
 
 	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
 )
 
 // SPI represents a device connected an SPI bus using 4 GPIO lines.
@@ -26,6 +29,11 @@ type SPI struct {
 	// time between clock edges (i.e. half the cycle time)
 	Tclk time.Duration
 
+	// Delay times the clock edges, and other delays required by devices.
+	//
+	// Its Stats indicate the timing actually achieved.
+	Delay *timing.Delay
+
 	// Clock line
 	Sclk *gpiod.Line
 
@@ -48,30 +56,104 @@ type SPI struct {
 	//
 	// In side reads on the opposite clock edge.
 	cpha int
+
+	// Bits per word transferred by TransferWords
+	wordSize int
+
+	// Transfer words least significant bit first
+	lsbFirst bool
+
+	// A shared Mosi line is currently an output
+	mosiDriven bool
+
+	// The request containing all the lines, if they are views.
+	lines *gpiod.Lines
+
+	// Options for the Delay.
+	timingOptions []timing.Option
+
+	// Error from construction options
+	err error
 }
 
 // New creates a SPI.
 func New(c *gpiod.Chip, sclk, ssz, mosi, miso int, options ...Option) (*SPI, error) {
-	s := SPI{}
+	s := SPI{wordSize: 8}
 	for _, option := range options {
 		option(&s)
 	}
+	if s.err != nil {
+		return nil, s.err
+	}
 	if s.Tclk == 0 {
 		// default to 1MHz full cycle.
 		s.Tclk = 500 * time.Nanosecond
 	}
 	var err error
-	var l *gpiod.Line
-	defer func() {
-		if err != nil {
-			s.Close()
-		}
-	}()
-	// hold SPI reset until needed...
-	l, err = c.RequestLine(ssz, gpiod.AsOutput(1))
+	s.Delay, err = timing.NewDelay(s.timingOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if c.UapiAbiVersion() == 1 {
+		// uAPI v1 requires all lines in a request to have the same direction.
+		err = s.requestLines(c, sclk, ssz, mosi, miso)
+	} else {
+		err = s.requestViews(c, sclk, ssz, mosi, miso)
+	}
 	if err != nil {
+		s.Close()
 		return nil, err
 	}
+	return &s, nil
+}
+
+// requestViews requests all the lines in a single request, with each signal
+// being a view on that request.
+func (s *SPI) requestViews(c *gpiod.Chip, sclk, ssz, mosi, miso int) error {
+	clkOpts := []gpiod.SubsetLineConfigOption{gpiod.AsOutput(0)}
+	if s.cpol != 0 {
+		clkOpts = append(clkOpts, gpiod.AsActiveLow)
+	}
+	offsets := []int{ssz, sclk, miso}
+	opts := []gpiod.LineReqOption{
+		// hold SPI reset until needed...
+		gpiod.WithLines([]int{ssz}, gpiod.AsOutput(1)),
+		gpiod.WithLines([]int{sclk}, clkOpts...),
+		gpiod.WithLines([]int{miso}, gpiod.AsInput),
+	}
+	if miso != mosi {
+		offsets = append(offsets, mosi)
+		opts = append(opts, gpiod.WithLines([]int{mosi}, gpiod.AsOutput(0)))
+	}
+	var err error
+	s.lines, err = c.RequestLines(offsets, opts...)
+	if err != nil {
+		return err
+	}
+	if s.Ssz, err = s.lines.Line(ssz); err != nil {
+		return err
+	}
+	if s.Sclk, err = s.lines.Line(sclk); err != nil {
+		return err
+	}
+	if s.Miso, err = s.lines.Line(miso); err != nil {
+		return err
+	}
+	if miso == mosi {
+		s.Mosi = s.Miso
+		return nil
+	}
+	s.Mosi, err = s.lines.Line(mosi)
+	return err
+}
+
+// requestLines requests each of the lines separately.
+func (s *SPI) requestLines(c *gpiod.Chip, sclk, ssz, mosi, miso int) error {
+	// hold SPI reset until needed...
+	l, err := c.RequestLine(ssz, gpiod.AsOutput(1))
+	if err != nil {
+		return err
+	}
 	s.Ssz = l
 	clkOpts := []gpiod.LineReqOption{gpiod.AsOutput(0)}
 	if s.cpol != 0 {
@@ -79,24 +161,24 @@ func New(c *gpiod.Chip, sclk, ssz, mosi, miso int, options ...Option) (*SPI, err
 	}
 	l, err = c.RequestLine(sclk, clkOpts...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	s.Sclk = l
 	l, err = c.RequestLine(miso, gpiod.AsInput)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	s.Miso = l
 	if miso == mosi {
 		s.Mosi = s.Miso
-	} else {
-		l, err := c.RequestLine(mosi, gpiod.AsOutput(0))
-		if err != nil {
-			return nil, err
-		}
-		s.Mosi = l
+		return nil
 	}
-	return &s, nil
+	l, err = c.RequestLine(mosi, gpiod.AsOutput(0))
+	if err != nil {
+		return err
+	}
+	s.Mosi = l
+	return nil
 }
 
 // Close releases allocated resources and reverts all output lines to inputs.
@@ -116,54 +198,266 @@ func (s *SPI) Close() {
 		s.Ssz.Reconfigure(gpiod.AsInput)
 		s.Ssz.Close()
 	}
+	if s.lines != nil {
+		s.lines.Close()
+	}
+	if s.Delay != nil {
+		s.Delay.Close()
+	}
 }
 
 // ClockIn clocks in a data bit from the SPI device on Miso.
 //
+// Mosi is not driven, so it may be shared with Miso.
+//
 // Starts and ends just after the falling edge of the clock.
 func (s *SPI) ClockIn() (int, error) {
-	time.Sleep(s.Tclk)
-	err := s.Sclk.SetValue(1)
-	if err != nil {
-		return 0, err
+	return s.clock(0, false, true)
+}
+
+// ClockOut clocks out a data bit to the SPI device on Mosi.
+//
+// Starts and ends just after the falling edge of the clock.
+func (s *SPI) ClockOut(v int) error {
+	_, err := s.clock(v, true, false)
+	return err
+}
+
+// Transfer performs a full duplex transfer of bytes with the SPI device.
+//
+// The bytes in tx are written to the device while the bytes read from the
+// device are written to rx.  The length of the transfer is the longer of tx
+// and rx, with zeros written after the end of tx, and bytes read after the
+// end of rx discarded.  Either may be nil.
+//
+// If Mosi and Miso share a line then the transfer is half duplex, and only
+// one of tx and rx may be provided.
+//
+// The device is selected for the duration of the transfer.
+func (s *SPI) Transfer(tx, rx []byte) error {
+	return s.TxPackets(Packet{W: tx, R: rx})
+}
+
+// Tx performs a full duplex transfer of bytes with the SPI device, as per
+// Transfer.
+//
+// This is compatible with the Tx method of common Go SPI connection
+// interfaces.
+func (s *SPI) Tx(w, r []byte) error {
+	return s.Transfer(w, r)
+}
+
+// TxPackets performs a sequence of transfers with the SPI device, as per
+// Transfer, with the device selected throughout.
+//
+// If Mosi and Miso share a line then the line is switched to an input for
+// packets that only read, and back to an output for packets that write.  The
+// switch to an input occurs at the end of the preceding packet, before its
+// delay, so the device may drive the line as soon as it is ready.
+func (s *SPI) TxPackets(packets ...Packet) error {
+	if s.Mosi == s.Miso {
+		for _, p := range packets {
+			if p.W != nil && p.R != nil {
+				return ErrHalfDuplex
+			}
+		}
 	}
-	if s.cpha == 1 {
-		time.Sleep(s.Tclk)
+	if err := s.selectDevice(); err != nil {
+		return err
 	}
-	v, err := s.Miso.Value()
-	if err != nil {
-		return 0, err
+	for i, p := range packets {
+		if err := s.transferPacket(p); err != nil {
+			s.deselectDevice()
+			return err
+		}
+		if i+1 < len(packets) && packets[i+1].W == nil {
+			if err := s.releaseMosi(); err != nil {
+				s.deselectDevice()
+				return err
+			}
+		}
+		s.Delay.Wait(p.Delay)
 	}
-	if s.cpha == 0 {
-		time.Sleep(s.Tclk)
+	return s.deselectDevice()
+}
+
+// transferPacket performs the transfer of a single packet.
+func (s *SPI) transferPacket(p Packet) error {
+	if p.W == nil {
+		if err := s.releaseMosi(); err != nil {
+			return err
+		}
+	} else if err := s.driveMosi(); err != nil {
+		return err
 	}
-	err = s.Sclk.SetValue(0)
-	if err != nil {
-		return 0, err
+	n := len(p.W)
+	if len(p.R) > n {
+		n = len(p.R)
 	}
-	return v, err
+	for i := 0; i < n; i++ {
+		var w uint32
+		if i < len(p.W) {
+			w = uint32(p.W[i])
+		}
+		r, err := s.transferWord(w, 8)
+		if err != nil {
+			return err
+		}
+		if i < len(p.R) {
+			p.R[i] = byte(r)
+		}
+	}
+	return nil
 }
 
-// ClockOut clocks out a data bit to the SPI device on Mosi.
+// driveMosi switches a shared Mosi line to an output.
+func (s *SPI) driveMosi() error {
+	if s.Mosi != s.Miso || s.mosiDriven {
+		return nil
+	}
+	if err := s.Mosi.Reconfigure(gpiod.AsOutput(0)); err != nil {
+		return err
+	}
+	s.mosiDriven = true
+	return nil
+}
+
+// releaseMosi switches a shared Mosi line to an input.
+func (s *SPI) releaseMosi() error {
+	if s.Mosi != s.Miso || !s.mosiDriven {
+		return nil
+	}
+	if err := s.Mosi.Reconfigure(gpiod.AsInput); err != nil {
+		return err
+	}
+	s.mosiDriven = false
+	return nil
+}
+
+// TransferWords performs a full duplex transfer of words with the SPI device.
 //
-// Starts and ends just after the falling edge of the clock.
-func (s *SPI) ClockOut(v int) error {
-	if s.cpha == 1 {
-		time.Sleep(s.Tclk)
+// The width of the words is set by WithWordSize, and only the lower bits of
+// each word are transferred.  The words are otherwise handled as per the
+// bytes of Transfer.
+func (s *SPI) TransferWords(tx, rx []uint32) error {
+	if s.Mosi == s.Miso && tx != nil && rx != nil {
+		return ErrHalfDuplex
 	}
-	err := s.Mosi.SetValue(v)
-	if err != nil {
+	n := len(tx)
+	if len(rx) > n {
+		n = len(rx)
+	}
+	if err := s.selectDevice(); err != nil {
 		return err
 	}
-	if s.cpha == 0 {
-		time.Sleep(s.Tclk)
+	var err error
+	if tx == nil {
+		err = s.releaseMosi()
+	} else {
+		err = s.driveMosi()
 	}
-	err = s.Sclk.SetValue(1)
 	if err != nil {
+		s.deselectDevice()
 		return err
 	}
-	time.Sleep(s.Tclk)
-	return s.Sclk.SetValue(0)
+	for i := 0; i < n; i++ {
+		var w uint32
+		if i < len(tx) {
+			w = tx[i]
+		}
+		r, err := s.transferWord(w, s.wordSize)
+		if err != nil {
+			s.deselectDevice()
+			return err
+		}
+		if i < len(rx) {
+			rx[i] = r
+		}
+	}
+	return s.deselectDevice()
+}
+
+// selectDevice asserts the slave select, allowing a clock period before the
+// first clock edge.
+func (s *SPI) selectDevice() error {
+	if err := s.Ssz.SetValue(0); err != nil {
+		return err
+	}
+	s.Delay.Wait(s.Tclk)
+	return nil
+}
+
+// deselectDevice releases the slave select, allowing a clock period after the
+// last clock edge.
+func (s *SPI) deselectDevice() error {
+	s.Delay.Wait(s.Tclk)
+	return s.Ssz.SetValue(1)
+}
+
+// transferWord clocks out the lower size bits of w while clocking in a word of
+// the same size.
+func (s *SPI) transferWord(w uint32, size int) (uint32, error) {
+	var r uint32
+	for i := 0; i < size; i++ {
+		shift := uint(size - 1 - i)
+		if s.lsbFirst {
+			shift = uint(i)
+		}
+		v, err := s.clock(int(w>>shift)&1, true, true)
+		if err != nil {
+			return 0, err
+		}
+		r |= uint32(v) << shift
+	}
+	return r, nil
+}
+
+// clock performs a single clock cycle, optionally writing a bit to Mosi and
+// reading a bit from Miso.
+//
+// The clock is active for the second half of the cycle.  For cpha 0 the out
+// side writes before the leading edge and the in side reads on it.  For cpha 1
+// the out side writes on the leading edge and the in side reads on the
+// trailing edge.
+//
+// Starts and ends just after the falling edge of the clock.
+func (s *SPI) clock(out int, write, read bool) (in int, err error) {
+	if s.cpha == 0 {
+		if write {
+			if err = s.Mosi.SetValue(out); err != nil {
+				return
+			}
+		}
+		s.Delay.Wait(s.Tclk)
+		if err = s.Sclk.SetValue(1); err != nil {
+			return
+		}
+		if read {
+			if in, err = s.Miso.Value(); err != nil {
+				return
+			}
+		}
+		s.Delay.Wait(s.Tclk)
+		err = s.Sclk.SetValue(0)
+		return
+	}
+	s.Delay.Wait(s.Tclk)
+	if err = s.Sclk.SetValue(1); err != nil {
+		return
+	}
+	if write {
+		if err = s.Mosi.SetValue(out); err != nil {
+			return
+		}
+	}
+	s.Delay.Wait(s.Tclk)
+	if read {
+		if in, err = s.Miso.Value(); err != nil {
+			return
+		}
+	}
+	err = s.Sclk.SetValue(0)
+	return
 }
 
 // Option specifies a construction option for the SPI.
@@ -183,6 +477,51 @@ func WithCPHA(cpha int) Option {
 	}
 }
 
+// WithMode sets the cpol and cpha for the SPI from the SPI mode, 0 to 3.
+func WithMode(mode int) Option {
+	return func(s *SPI) {
+		if mode < 0 || mode > 3 {
+			s.err = ErrInvalidMode
+			return
+		}
+		s.cpol = mode >> 1
+		s.cpha = mode & 1
+	}
+}
+
+// WithLSBFirst transfers words least significant bit first.
+//
+// By default words are transferred most significant bit first.  This does not
+// apply to ClockIn and ClockOut, which transfer single bits.
+func WithLSBFirst() Option {
+	return func(s *SPI) {
+		s.lsbFirst = true
+	}
+}
+
+// WithWordSize sets the number of bits in the words transferred by
+// TransferWords, from 1 to 32.
+//
+// The default is 8.
+func WithWordSize(bits int) Option {
+	return func(s *SPI) {
+		if bits < 1 || bits > 32 {
+			s.err = ErrInvalidWordSize
+			return
+		}
+		s.wordSize = bits
+	}
+}
+
+// WithTiming sets the options for the Delay used to time the clock edges.
+//
+// By default the Delay is Hybrid.
+func WithTiming(options ...timing.Option) Option {
+	return func(s *SPI) {
+		s.timingOptions = append(s.timingOptions, options...)
+	}
+}
+
 // WithTclk sets the clock period for the SPI.
 //
 // Note that this is the half-cycle period.
@@ -191,3 +530,18 @@ func WithTclk(tclk time.Duration) Option {
 		s.Tclk = tclk
 	}
 }
+
+var (
+	// ErrClosed indicates the Bus or Device has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrHalfDuplex indicates a full duplex transfer was attempted with Mosi
+	// and Miso sharing a line.
+	ErrHalfDuplex = errors.New("mosi and miso share a line")
+
+	// ErrInvalidMode indicates the SPI mode is not in the range 0 to 3.
+	ErrInvalidMode = errors.New("invalid mode")
+
+	// ErrInvalidWordSize indicates the word size is not in the range 1 to 32.
+	ErrInvalidWordSize = errors.New("invalid word size")
+)
@@ -0,0 +1,350 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package spi_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/mockup"
+	"github.com/warthog618/gpiod/spi"
+)
+
+const (
+	sclk = 0
+	ssz  = 1
+	mosi = 2
+	miso = 3
+
+	// slow enough for the slave to track the clock by polling
+	tclk = 2 * time.Millisecond
+)
+
+func TestNew(t *testing.T) {
+	patterns := []struct {
+		name   string
+		option spi.Option
+		err    error
+	}{
+		{"mode low", spi.WithMode(-1), spi.ErrInvalidMode},
+		{"mode high", spi.WithMode(4), spi.ErrInvalidMode},
+		{"word size low", spi.WithWordSize(0), spi.ErrInvalidWordSize},
+		{"word size high", spi.WithWordSize(33), spi.ErrInvalidWordSize},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			s, err := spi.New(nil, sclk, ssz, mosi, miso, p.option)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, s)
+		}
+		t.Run(p.name, tf)
+	}
+
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	// half duplex
+	s, err := spi.New(chip, sclk, ssz, mosi, mosi)
+	require.Nil(t, err)
+	assert.Equal(t, spi.ErrHalfDuplex, s.Transfer([]byte{1}, []byte{0}))
+	assert.Equal(t, spi.ErrHalfDuplex, s.TransferWords([]uint32{1}, []uint32{0}))
+	assert.Equal(t, spi.ErrHalfDuplex,
+		s.TxPackets(spi.Packet{W: []byte{1}}, spi.Packet{W: []byte{1}, R: []byte{0}}))
+	assert.Nil(t, s.Transfer([]byte{1}, nil))
+	assert.Nil(t, s.Transfer(nil, []byte{0}))
+	s.Close()
+}
+
+func TestTransfer(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	tx := []byte{0xa5, 0x01, 0x80}
+	resp := []byte{0x3c, 0x80, 0x01}
+	for mode := 0; mode < 4; mode++ {
+		for _, lsbFirst := range []bool{false, true} {
+			tf := func(t *testing.T) {
+				options := []spi.Option{spi.WithMode(mode), spi.WithTclk(tclk)}
+				if lsbFirst {
+					options = append(options, spi.WithLSBFirst())
+				}
+				s, err := spi.New(chip, sclk, ssz, mosi, miso, options...)
+				require.Nil(t, err)
+				defer s.Close()
+
+				sl := newSlave(c, ssz, mode, 8, lsbFirst, toWords(resp))
+				rx := make([]byte, len(tx))
+				assert.Nil(t, s.Transfer(tx, rx))
+				assert.Equal(t, resp, rx)
+				assert.Equal(t, toWords(tx), sl.wait(t))
+
+				// idle after the transfer
+				checkLine(t, c, ssz, 1)
+				checkLine(t, c, sclk, mode>>1)
+			}
+			t.Run(fmt.Sprintf("mode%d lsb=%t", mode, lsbFirst), tf)
+		}
+	}
+}
+
+func TestTransferLengths(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	s, err := spi.New(chip, sclk, ssz, mosi, miso, spi.WithTclk(tclk))
+	require.Nil(t, err)
+	defer s.Close()
+
+	// tx shorter than rx is padded with zeros
+	sl := newSlave(c, ssz, 0, 8, false, []uint32{0x12, 0x34})
+	rx := make([]byte, 2)
+	assert.Nil(t, s.Tx([]byte{0xff}, rx))
+	assert.Equal(t, []byte{0x12, 0x34}, rx)
+	assert.Equal(t, []uint32{0xff, 0}, sl.wait(t))
+
+	// write only
+	sl = newSlave(c, ssz, 0, 8, false, nil)
+	assert.Nil(t, s.Transfer([]byte{0x5a, 0xc3}, nil))
+	assert.Equal(t, []uint32{0x5a, 0xc3}, sl.wait(t))
+}
+
+func TestTxPackets(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	s, err := spi.New(chip, sclk, ssz, mosi, miso, spi.WithTclk(tclk))
+	require.Nil(t, err)
+	defer s.Close()
+
+	// the device remains selected across packets
+	sl := newSlave(c, ssz, 0, 8, false, []uint32{0, 0x81, 0x7e})
+	r := make([]byte, 2)
+	start := time.Now()
+	err = s.TxPackets(
+		spi.Packet{W: []byte{0x18}, Delay: 20 * time.Millisecond},
+		spi.Packet{R: r})
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond+48*tclk)
+	assert.Equal(t, []byte{0x81, 0x7e}, r)
+	assert.Equal(t, []uint32{0x18, 0, 0}, sl.wait(t))
+}
+
+func TestTransferWords(t *testing.T) {
+	m, c := newMockup(t, 4)
+	defer m.Close()
+	chip, err := gpiod.NewChip(c.Name)
+	require.Nil(t, err)
+	defer chip.Close()
+
+	for _, lsbFirst := range []bool{false, true} {
+		tf := func(t *testing.T) {
+			options := []spi.Option{
+				spi.WithMode(1),
+				spi.WithWordSize(12),
+				spi.WithTclk(tclk),
+			}
+			if lsbFirst {
+				options = append(options, spi.WithLSBFirst())
+			}
+			s, err := spi.New(chip, sclk, ssz, mosi, miso, options...)
+			require.Nil(t, err)
+			defer s.Close()
+
+			resp := []uint32{0xabc, 0x801}
+			sl := newSlave(c, ssz, 1, 12, lsbFirst, resp)
+			rx := make([]uint32, 2)
+			// upper bits are not transferred
+			assert.Nil(t, s.TransferWords([]uint32{0xf123, 0x456}, rx))
+			assert.Equal(t, resp, rx)
+			assert.Equal(t, []uint32{0x123, 0x456}, sl.wait(t))
+		}
+		t.Run(fmt.Sprintf("lsb=%t", lsbFirst), tf)
+	}
+}
+
+// slave simulates an SPI slave device by polling the mockup lines.
+type slave struct {
+	c        *mockup.Chip
+	ssz      int
+	cpol     int
+	cpha     int
+	size     int
+	lsbFirst bool
+	tx       []uint32
+	rx       []uint32
+	err      error
+	done     chan struct{}
+}
+
+func newSlave(c *mockup.Chip, ssz, mode, size int, lsbFirst bool, tx []uint32) *slave {
+	s := slave{
+		c:        c,
+		ssz:      ssz,
+		cpol:     mode >> 1,
+		cpha:     mode & 1,
+		size:     size,
+		lsbFirst: lsbFirst,
+		tx:       tx,
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return &s
+}
+
+// wait returns the words received by the slave once the master deselects it.
+func (s *slave) wait(t *testing.T) []uint32 {
+	t.Helper()
+	select {
+	case <-s.done:
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for slave")
+	}
+	require.Nil(t, s.err)
+	return s.rx
+}
+
+func (s *slave) run() {
+	defer close(s.done)
+	var selected bool
+	if selected, s.err = s.waitLine(s.ssz, 0); !selected {
+		return
+	}
+	i := 0
+	for ; ; i++ {
+		word := i / s.size
+		if word >= len(s.rx) {
+			s.rx = append(s.rx, 0)
+		}
+		shift := uint(s.size - 1 - i%s.size)
+		if s.lsbFirst {
+			shift = uint(i % s.size)
+		}
+		var out int
+		if word < len(s.tx) {
+			out = int(s.tx[word]>>shift) & 1
+		}
+		if s.cpha == 0 {
+			// present before the leading edge, sample on it
+			if s.err = s.c.SetValue(miso, out); s.err != nil {
+				return
+			}
+			if !s.edge(s.cpol ^ 1) {
+				break
+			}
+			if !s.sample(word, shift) {
+				return
+			}
+			if !s.edge(s.cpol) {
+				return
+			}
+		} else {
+			// present on the leading edge, sample on the trailing edge
+			if !s.edge(s.cpol ^ 1) {
+				break
+			}
+			if s.err = s.c.SetValue(miso, out); s.err != nil {
+				return
+			}
+			if !s.edge(s.cpol) {
+				return
+			}
+			if !s.sample(word, shift) {
+				return
+			}
+		}
+	}
+	// drop the word started when the slave was deselected
+	if i%s.size == 0 {
+		s.rx = s.rx[:len(s.rx)-1]
+	}
+}
+
+func (s *slave) sample(word int, shift uint) bool {
+	var v int
+	if v, s.err = s.c.Value(mosi); s.err != nil {
+		return false
+	}
+	s.rx[word] |= uint32(v) << shift
+	return true
+}
+
+// edge waits for the clock to reach the level, returning false if the slave
+// is deselected first.
+func (s *slave) edge(level int) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		var v int
+		if v, s.err = s.c.Value(s.ssz); s.err != nil || v == 1 {
+			return false
+		}
+		if v, s.err = s.c.Value(sclk); s.err != nil {
+			return false
+		}
+		if v == level {
+			return true
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+	s.err = fmt.Errorf("timeout waiting for clock %d", level)
+	return false
+}
+
+func (s *slave) waitLine(offset, level int) (bool, error) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		v, err := s.c.Value(offset)
+		if err != nil {
+			return false, err
+		}
+		if v == level {
+			return true, nil
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+	return false, fmt.Errorf("timeout waiting for line %d", offset)
+}
+
+func toWords(b []byte) []uint32 {
+	w := make([]uint32, len(b))
+	for i, v := range b {
+		w[i] = uint32(v)
+	}
+	return w
+}
+
+func checkLine(t *testing.T, c *mockup.Chip, offset, value int) {
+	t.Helper()
+	v, err := c.Value(offset)
+	assert.Nil(t, err)
+	assert.Equal(t, value, v, offset)
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
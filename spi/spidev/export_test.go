@@ -0,0 +1,16 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package spidev
+
+// WithMessage replaces the SPI_IOC_MESSAGE ioctl, so the device may be
+// simulated using the buffers rather than the addresses in the transfers.
+func WithMessage(fn func(fd uintptr, xfers []IocTransfer, ws, rs [][]byte) error) Option {
+	return func(d *Device) {
+		d.message = fn
+	}
+}
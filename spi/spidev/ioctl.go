@@ -0,0 +1,24 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package spidev
+
+// ioctl constants defined in ioctl_XXX
+
+func ior(t, nr, size uintptr) uintptr {
+	return (iocRead << iocDirShift) |
+		(size << iocSizeShift) |
+		(t << iocTypeShift) |
+		(nr << iocNRShift)
+}
+
+func iow(t, nr, size uintptr) uintptr {
+	return (iocWrite << iocDirShift) |
+		(size << iocSizeShift) |
+		(t << iocTypeShift) |
+		(nr << iocNRShift)
+}
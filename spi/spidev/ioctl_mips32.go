@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build mips || mipsle || mips64 || mips64le || ppc64 || ppc64le || sparc || sparc64
+// +build mips mipsle mips64 mips64le ppc64 ppc64le sparc sparc64
+
+package spidev
+
+// ioctl constants
+const (
+	iocNRBits    = 8
+	iocTypeBits  = 8
+	iocDirBits   = 3
+	iocSizeBits  = 13
+	iocNRShift   = 0
+	iocTypeShift = iocNRShift + iocNRBits
+	iocSizeShift = iocTypeShift + iocTypeBits
+	iocDirShift  = iocSizeShift + iocSizeBits
+	iocWrite     = 4
+	iocRead      = 2
+	// iocNone = 1
+)
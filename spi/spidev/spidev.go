@@ -0,0 +1,374 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package spidev provides access to SPI devices via the Linux kernel spidev
+// driver.
+//
+// The Device implements spi.Conn, so device drivers written against that
+// interface can be prototyped using the bit bashed spi.SPI and deployed on a
+// hardware SPI controller.
+package spidev
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/warthog618/gpiod/spi"
+	"golang.org/x/sys/unix"
+)
+
+// DefaultRoot is the directory containing the spidev device files.
+const DefaultRoot = "/dev"
+
+// Device represents an SPI device accessed via a spidev device file.
+type Device struct {
+	mu sync.Mutex
+
+	f *os.File
+
+	// directory containing the device files
+	root string
+
+	ioctl Ioctl
+
+	// replaces the SPI_IOC_MESSAGE ioctl, if set, for testing
+	message messageFunc
+
+	mode        int
+	lsbFirst    bool
+	bitsPerWord int
+	speed       uint32
+
+	// Error from construction options
+	err error
+}
+
+// Open opens the spidev device for the chip select on the bus,
+// i.e. /dev/spidev<bus>.<cs>.
+//
+// The device is configured with the mode, bit order, word size and clock
+// speed from the options, which default to mode 0, MSB first, 8 bits per
+// word, and the current speed of the device.
+func Open(bus, cs int, options ...Option) (*Device, error) {
+	d := Device{
+		root:        DefaultRoot,
+		ioctl:       ioctl,
+		bitsPerWord: 8,
+	}
+	for _, option := range options {
+		option(&d)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	f, err := os.OpenFile(filepath.Join(d.root, fmt.Sprintf("spidev%d.%d", bus, cs)), os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	d.f = f
+	if err = d.configure(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &d, nil
+}
+
+// configure writes the configuration to the device.
+func (d *Device) configure() error {
+	mode := uint8(d.mode)
+	if err := d.ioctl(d.f.Fd(), WrModeIoctl, unsafe.Pointer(&mode)); err != nil {
+		return err
+	}
+	var lsbFirst uint8
+	if d.lsbFirst {
+		lsbFirst = 1
+	}
+	if err := d.ioctl(d.f.Fd(), WrLSBFirstIoctl, unsafe.Pointer(&lsbFirst)); err != nil {
+		return err
+	}
+	bits := uint8(d.bitsPerWord)
+	if err := d.ioctl(d.f.Fd(), WrBitsPerWordIoctl, unsafe.Pointer(&bits)); err != nil {
+		return err
+	}
+	if d.speed == 0 {
+		return d.ioctl(d.f.Fd(), RdMaxSpeedHzIoctl, unsafe.Pointer(&d.speed))
+	}
+	return d.ioctl(d.f.Fd(), WrMaxSpeedHzIoctl, unsafe.Pointer(&d.speed))
+}
+
+// Close releases the device.
+func (d *Device) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.f == nil {
+		return ErrClosed
+	}
+	err := d.f.Close()
+	d.f = nil
+	return err
+}
+
+// Mode returns the SPI mode of the device.
+func (d *Device) Mode() int {
+	return d.mode
+}
+
+// BitsPerWord returns the word size of the device.
+func (d *Device) BitsPerWord() int {
+	return d.bitsPerWord
+}
+
+// Speed returns the maximum clock speed of the device, in Hz.
+func (d *Device) Speed() uint32 {
+	return d.speed
+}
+
+// Tx performs a full duplex transfer of bytes with the device.
+//
+// The length of the transfer is the longer of w and r, with zeros written
+// after the end of w, and bytes read after the end of r discarded.
+// Either may be nil.
+func (d *Device) Tx(w, r []byte) error {
+	return d.TxPackets(spi.Packet{W: w, R: r})
+}
+
+// TxPackets performs a sequence of transfers with the device selected
+// throughout, as a single SPI_IOC_MESSAGE.
+//
+// Delays are rounded up to the microsecond.
+func (d *Device) TxPackets(packets ...spi.Packet) error {
+	if len(packets) == 0 {
+		return nil
+	}
+	if uintptr(len(packets))*unsafe.Sizeof(IocTransfer{}) >= 1<<iocSizeBits {
+		return ErrTooManyPackets
+	}
+	xfers := make([]IocTransfer, len(packets))
+	// the buffers passed to the kernel, which may be copies padded to the
+	// length of the transfer.
+	ws := make([][]byte, len(packets))
+	rs := make([][]byte, len(packets))
+	for i, p := range packets {
+		n := len(p.W)
+		if len(p.R) > n {
+			n = len(p.R)
+		}
+		delay := (p.Delay + time.Microsecond - 1) / time.Microsecond
+		if p.Delay < 0 || delay > 0xffff {
+			return ErrInvalidDelay
+		}
+		ws[i] = p.W
+		if p.W != nil && len(p.W) < n {
+			ws[i] = make([]byte, n)
+			copy(ws[i], p.W)
+		}
+		rs[i] = p.R
+		if p.R != nil && len(p.R) < n {
+			rs[i] = make([]byte, n)
+		}
+		xfers[i] = IocTransfer{
+			TxBuf:      bufAddr(ws[i]),
+			RxBuf:      bufAddr(rs[i]),
+			Len:        uint32(n),
+			DelayUsecs: uint16(delay),
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.f == nil {
+		return ErrClosed
+	}
+	var err error
+	if d.message != nil {
+		err = d.message(d.f.Fd(), xfers, ws, rs)
+	} else {
+		err = d.ioctl(d.f.Fd(), MessageIoctl(len(xfers)), unsafe.Pointer(&xfers[0]))
+	}
+	runtime.KeepAlive(ws)
+	runtime.KeepAlive(rs)
+	if err != nil {
+		return err
+	}
+	for i, p := range packets {
+		if len(p.R) < len(rs[i]) {
+			copy(p.R, rs[i])
+		}
+	}
+	return nil
+}
+
+// bufAddr returns the address of the buffer as passed to the kernel.
+func bufAddr(b []byte) uint64 {
+	if len(b) == 0 {
+		return 0
+	}
+	return uint64(uintptr(unsafe.Pointer(&b[0])))
+}
+
+// messageFunc performs an SPI_IOC_MESSAGE with the transfers, where ws and rs
+// are the write and read buffers referenced by each transfer.
+type messageFunc func(fd uintptr, xfers []IocTransfer, ws, rs [][]byte) error
+
+// Ioctl performs an ioctl on the spidev device file.
+//
+// The default performs the ioctl system call, but it may be replaced using
+// WithIoctl, e.g. to simulate a device for testing.
+type Ioctl func(fd uintptr, req uintptr, arg unsafe.Pointer) error
+
+func ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// IocTransfer is a single transfer in an SPI_IOC_MESSAGE, i.e. the kernel
+// spi_ioc_transfer.
+type IocTransfer struct {
+	// The address of the buffer to write, or 0 to write zeros.
+	TxBuf uint64
+
+	// The address of the buffer to read into, or 0 to discard the read.
+	RxBuf uint64
+
+	// The length of the buffers, in bytes.
+	Len uint32
+
+	// Overrides the device speed for this transfer, if non-zero.
+	SpeedHz uint32
+
+	// The delay after the transfer, before any chip select change.
+	DelayUsecs uint16
+
+	// Overrides the device word size for this transfer, if non-zero.
+	BitsPerWord uint8
+
+	// Deselect the device before the next transfer.
+	CsChange uint8
+
+	// The number of bits used to write, for dual and quad SPI.
+	TxNbits uint8
+
+	// The number of bits used to read, for dual and quad SPI.
+	RxNbits uint8
+
+	// The delay between words within the transfer.
+	WordDelayUsecs uint8
+
+	pad uint8
+}
+
+// IOCTL command codes
+var (
+	RdModeIoctl        = ior('k', 1, 1)
+	WrModeIoctl        = iow('k', 1, 1)
+	RdLSBFirstIoctl    = ior('k', 2, 1)
+	WrLSBFirstIoctl    = iow('k', 2, 1)
+	RdBitsPerWordIoctl = ior('k', 3, 1)
+	WrBitsPerWordIoctl = iow('k', 3, 1)
+	RdMaxSpeedHzIoctl  = ior('k', 4, 4)
+	WrMaxSpeedHzIoctl  = iow('k', 4, 4)
+)
+
+// MessageIoctl returns the SPI_IOC_MESSAGE command code for n transfers.
+func MessageIoctl(n int) uintptr {
+	return iow('k', 0, uintptr(n)*unsafe.Sizeof(IocTransfer{}))
+}
+
+// Option specifies a construction option for the Device.
+type Option func(*Device)
+
+// WithMode sets the SPI mode, 0 to 3.
+//
+// The default is 0.
+func WithMode(mode int) Option {
+	return func(d *Device) {
+		if mode < 0 || mode > 3 {
+			d.err = ErrInvalidMode
+			return
+		}
+		d.mode = mode
+	}
+}
+
+// WithLSBFirst transfers words least significant bit first.
+//
+// By default words are transferred most significant bit first.  Not all SPI
+// controllers support this.
+func WithLSBFirst() Option {
+	return func(d *Device) {
+		d.lsbFirst = true
+	}
+}
+
+// WithBitsPerWord sets the word size, from 1 to 32.
+//
+// The default is 8.  Words larger than 8 bits are stored in the buffers as
+// native endian 16 or 32 bit values.
+func WithBitsPerWord(bits int) Option {
+	return func(d *Device) {
+		if bits < 1 || bits > 32 {
+			d.err = ErrInvalidBitsPerWord
+			return
+		}
+		d.bitsPerWord = bits
+	}
+}
+
+// WithSpeed sets the maximum clock speed, in Hz.
+//
+// The default is the speed currently configured for the device.
+func WithSpeed(hz uint32) Option {
+	return func(d *Device) {
+		d.speed = hz
+	}
+}
+
+// WithRoot sets the directory containing the spidev device files.
+//
+// The default is DefaultRoot.
+func WithRoot(root string) Option {
+	return func(d *Device) {
+		d.root = root
+	}
+}
+
+// WithIoctl replaces the ioctl system call used to access the device.
+//
+// This is intended for testing.
+func WithIoctl(fn Ioctl) Option {
+	return func(d *Device) {
+		d.ioctl = fn
+	}
+}
+
+var (
+	// ErrClosed indicates the Device has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrInvalidBitsPerWord indicates the word size is not in the range 1 to
+	// 32.
+	ErrInvalidBitsPerWord = errors.New("invalid bits per word")
+
+	// ErrInvalidDelay indicates a packet delay exceeds the 65535us supported
+	// by spidev.
+	ErrInvalidDelay = errors.New("invalid delay")
+
+	// ErrInvalidMode indicates the SPI mode is not in the range 0 to 3.
+	ErrInvalidMode = errors.New("invalid mode")
+
+	// ErrTooManyPackets indicates the packets exceed the number of transfers
+	// that can be passed to spidev in a single message.
+	ErrTooManyPackets = errors.New("too many packets")
+)
@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package spidev_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod/spi"
+	"github.com/warthog618/gpiod/spi/spidev"
+	"golang.org/x/sys/unix"
+)
+
+func TestOpen(t *testing.T) {
+	root := newRoot(t)
+
+	patterns := []struct {
+		name   string
+		option spidev.Option
+		err    error
+	}{
+		{"mode", spidev.WithMode(4), spidev.ErrInvalidMode},
+		{"bits per word", spidev.WithBitsPerWord(0), spidev.ErrInvalidBitsPerWord},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			d, err := spidev.Open(0, 1, spidev.WithRoot(root), p.option)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, d)
+		}
+		t.Run(p.name, tf)
+	}
+
+	// no such device
+	d, err := spidev.Open(1, 0, spidev.WithRoot(root))
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, d)
+
+	// defaults
+	sd := simDevice{speed: 500000}
+	d, err = spidev.Open(0, 1, spidev.WithRoot(root), spidev.WithIoctl(sd.ioctl))
+	require.Nil(t, err)
+	assert.Equal(t, 0, d.Mode())
+	assert.Equal(t, 8, d.BitsPerWord())
+	assert.Equal(t, uint32(500000), d.Speed())
+	assert.Equal(t, simDevice{bits: 8, speed: 500000}, sd)
+	assert.Nil(t, d.Close())
+	assert.Equal(t, spidev.ErrClosed, d.Close())
+	assert.Equal(t, spidev.ErrClosed, d.Tx([]byte{1}, nil))
+
+	sd = simDevice{}
+	d, err = spidev.Open(0, 1,
+		spidev.WithRoot(root),
+		spidev.WithIoctl(sd.ioctl),
+		spidev.WithMode(3),
+		spidev.WithLSBFirst(),
+		spidev.WithBitsPerWord(12),
+		spidev.WithSpeed(1000000))
+	require.Nil(t, err)
+	assert.Equal(t, 3, d.Mode())
+	assert.Equal(t, 12, d.BitsPerWord())
+	assert.Equal(t, uint32(1000000), d.Speed())
+	assert.Equal(t, simDevice{mode: 3, lsbFirst: 1, bits: 12, speed: 1000000}, sd)
+	assert.Nil(t, d.Close())
+
+	// device rejects configuration
+	sd = simDevice{err: unix.EINVAL}
+	d, err = spidev.Open(0, 1, spidev.WithRoot(root), spidev.WithIoctl(sd.ioctl))
+	assert.Equal(t, unix.EINVAL, err)
+	assert.Nil(t, d)
+}
+
+func TestTx(t *testing.T) {
+	sd := simDevice{}
+	d, err := spidev.Open(0, 1,
+		spidev.WithRoot(newRoot(t)),
+		spidev.WithIoctl(sd.ioctl),
+		spidev.WithMessage(sd.message),
+		spidev.WithSpeed(1000000))
+	require.Nil(t, err)
+	defer d.Close()
+
+	// the simulated device returns the complement of what it is sent
+	patterns := []struct {
+		name string
+		w    []byte
+		r    []byte
+		// expected bytes read
+		er []byte
+		// expected bytes seen by the device
+		ew []byte
+	}{
+		{"full duplex", []byte{0x01, 0xa5}, make([]byte, 2), []byte{0xfe, 0x5a}, []byte{0x01, 0xa5}},
+		{"short write", []byte{0x01}, make([]byte, 3), []byte{0xfe, 0xff, 0xff}, []byte{0x01, 0, 0}},
+		{"short read", []byte{0x01, 0x02, 0x03}, make([]byte, 1), []byte{0xfe}, []byte{0x01, 0x02, 0x03}},
+		{"write only", []byte{0x0f}, nil, nil, []byte{0x0f}},
+		{"read only", nil, make([]byte, 2), []byte{0xff, 0xff}, []byte{0, 0}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			sd.xfers = nil
+			sd.written = nil
+			assert.Nil(t, d.Tx(p.w, p.r))
+			assert.Equal(t, p.er, p.r)
+			assert.Equal(t, p.ew, sd.written)
+			require.Len(t, sd.xfers, 1)
+			assert.Equal(t, uint32(len(p.ew)), sd.xfers[0].Len)
+		}
+		t.Run(p.name, tf)
+	}
+
+	// device error
+	sd.err = unix.EIO
+	assert.Equal(t, unix.EIO, d.Tx([]byte{1}, nil))
+}
+
+func TestTxPackets(t *testing.T) {
+	sd := simDevice{}
+	d, err := spidev.Open(0, 1,
+		spidev.WithRoot(newRoot(t)),
+		spidev.WithIoctl(sd.ioctl),
+		spidev.WithMessage(sd.message),
+		spidev.WithSpeed(1000000))
+	require.Nil(t, err)
+	defer d.Close()
+
+	assert.Nil(t, d.TxPackets())
+	assert.Nil(t, sd.xfers)
+
+	r := make([]byte, 2)
+	err = d.TxPackets(
+		spi.Packet{W: []byte{0x18}, Delay: 1500 * time.Nanosecond},
+		spi.Packet{R: r})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xff, 0xff}, r)
+	assert.Equal(t, []byte{0x18, 0, 0}, sd.written)
+	require.Len(t, sd.xfers, 2)
+	// delays are rounded up
+	assert.Equal(t, uint16(2), sd.xfers[0].DelayUsecs)
+	assert.Zero(t, sd.xfers[0].RxBuf)
+	assert.Zero(t, sd.xfers[1].TxBuf)
+	assert.Zero(t, sd.xfers[1].CsChange)
+
+	assert.Equal(t, spidev.ErrInvalidDelay, d.TxPackets(spi.Packet{Delay: time.Second}))
+	assert.Equal(t, spidev.ErrInvalidDelay, d.TxPackets(spi.Packet{Delay: -1}))
+	packets := make([]spi.Packet, 1024)
+	assert.Equal(t, spidev.ErrTooManyPackets, d.TxPackets(packets...))
+}
+
+// simDevice simulates the spidev ioctls.
+type simDevice struct {
+	mode     uint8
+	lsbFirst uint8
+	bits     uint8
+	speed    uint32
+
+	// the transfers from the last message
+	xfers []spidev.IocTransfer
+
+	// the bytes written by the messages
+	written []byte
+
+	// the error to return from ioctls
+	err error
+}
+
+func (s *simDevice) ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	if s.err != nil {
+		return s.err
+	}
+	switch req {
+	case spidev.WrModeIoctl:
+		s.mode = *(*uint8)(arg)
+	case spidev.WrLSBFirstIoctl:
+		s.lsbFirst = *(*uint8)(arg)
+	case spidev.WrBitsPerWordIoctl:
+		s.bits = *(*uint8)(arg)
+	case spidev.WrMaxSpeedHzIoctl:
+		s.speed = *(*uint32)(arg)
+	case spidev.RdMaxSpeedHzIoctl:
+		*(*uint32)(arg) = s.speed
+	default:
+		return unix.ENOTTY
+	}
+	return nil
+}
+
+// message records the bytes written and reads back their complement.
+func (s *simDevice) message(fd uintptr, xfers []spidev.IocTransfer, ws, rs [][]byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.xfers = append([]spidev.IocTransfer(nil), xfers...)
+	for j, x := range xfers {
+		w, r := ws[j], rs[j]
+		if (w == nil) != (x.TxBuf == 0) || (r == nil) != (x.RxBuf == 0) {
+			return unix.EFAULT
+		}
+		for i := 0; i < int(x.Len); i++ {
+			var v byte
+			if w != nil {
+				v = w[i]
+			}
+			s.written = append(s.written, v)
+			if r != nil {
+				r[i] = ^v
+			}
+		}
+	}
+	return nil
+}
+
+// newRoot creates a directory containing a placeholder for spidev0.1.
+func newRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	f, err := os.Create(filepath.Join(root, "spidev0.1"))
+	require.Nil(t, err)
+	f.Close()
+	return root
+}
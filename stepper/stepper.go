@@ -0,0 +1,654 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package stepper drives stepper motors from requested lines.
+//
+// Both step/dir drivers, such as the A4988, DRV8825 and TMC2208, and the coils
+// of unipolar steppers, via drivers such as the ULN2003, are supported.
+//
+// Steps are timed on a precise timing loop, with trapezoidal acceleration and
+// deceleration ramps, and the absolute position of the motor is tracked.
+package stepper
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/timing"
+)
+
+// Drive identifies how the lines drive the motor.
+type Drive int
+
+const (
+	// StepDir drives a step/dir driver.
+	//
+	// The lines are step, dir, and optionally enable, in that order.  The
+	// dir line is active for Forward motion.
+	StepDir Drive = iota
+
+	// FullStep drives four coils with two coils energised at a time.
+	FullStep
+
+	// HalfStep drives four coils alternating between one and two coils
+	// energised, doubling the steps per revolution.
+	HalfStep
+
+	// WaveDrive drives four coils with one coil energised at a time.
+	WaveDrive
+)
+
+// Direction indicates the direction of motion.
+type Direction int
+
+const (
+	// Forward indicates motion to increasing positions.
+	Forward Direction = 1
+
+	// Reverse indicates motion to decreasing positions.
+	Reverse Direction = -1
+)
+
+// The coil sequences for the four-wire drives, with the first line as bit 0.
+var sequences = map[Drive][]uint64{
+	FullStep:  {0x3, 0x6, 0xc, 0x9},
+	HalfStep:  {0x1, 0x3, 0x2, 0x6, 0x4, 0xc, 0x8, 0x9},
+	WaveDrive: {0x1, 0x2, 0x4, 0x8},
+}
+
+const (
+	stepMask   = 0x1
+	dirMask    = 0x2
+	enableMask = 0x4
+	coilMask   = 0xf
+)
+
+// Stepper drives a stepper motor.
+//
+// The Stepper does not take ownership of the lines, which remain the
+// responsibility of the caller to close, after closing the Stepper.  The lines
+// should be requested as outputs.
+type Stepper struct {
+	lines *gpiod.Lines
+
+	drive Drive
+
+	// the coil sequence for four-wire drives
+	seq []uint64
+
+	// true if a StepDir driver has an enable line
+	hasEnable bool
+
+	// the width of step pulses
+	pulse time.Duration
+
+	// the time between setting the dir line and the first step
+	dirSetup time.Duration
+
+	// the limits in each direction, set while a limit switch is active
+	forwardLimit int32
+	reverseLimit int32
+
+	// moveMu is held for the duration of a move
+	moveMu sync.Mutex
+
+	delay *timing.Delay
+
+	// mu covers the state below
+	mu sync.Mutex
+
+	position int64
+
+	// the index into the coil sequence
+	phase int
+
+	// maximum speed in steps per second
+	speed float64
+
+	// acceleration in steps per second per second, or zero for none
+	accel float64
+
+	moving bool
+
+	// the direction of the move in progress
+	moveDir Direction
+
+	// closed to abort the move in progress
+	abort chan struct{}
+
+	closed bool
+
+	// options for the delay
+	timingOptions []timing.Option
+
+	// an error from the construction options
+	err error
+}
+
+// New creates a Stepper that drives the motor using the lines.
+//
+// StepDir requires two or three lines, and the four-wire drives require four.
+//
+// The default maximum speed is 200 steps per second, with no acceleration
+// ramp.
+func New(lines *gpiod.Lines, drive Drive, options ...Option) (*Stepper, error) {
+	if lines == nil {
+		return nil, ErrNoLines
+	}
+	n := len(lines.Offsets())
+	s := Stepper{
+		lines:    lines,
+		drive:    drive,
+		pulse:    2 * time.Microsecond,
+		dirSetup: time.Microsecond,
+		speed:    200,
+	}
+	switch drive {
+	case StepDir:
+		if n != 2 && n != 3 {
+			return nil, ErrInvalidLines
+		}
+		s.hasEnable = n == 3
+	case FullStep, HalfStep, WaveDrive:
+		if n != 4 {
+			return nil, ErrInvalidLines
+		}
+		s.seq = sequences[drive]
+	default:
+		return nil, ErrInvalidDrive
+	}
+	for _, option := range options {
+		option(&s)
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	d, err := timing.NewDelay(s.timingOptions...)
+	if err != nil {
+		return nil, err
+	}
+	s.delay = d
+	return &s, nil
+}
+
+// Close releases the motor, and stops any move in progress.
+//
+// The motor is released using Disable.
+func (s *Stepper) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	s.abortMove()
+	s.mu.Unlock()
+	// wait for any move to notice the close
+	s.moveMu.Lock()
+	defer s.moveMu.Unlock()
+	s.delay.Close()
+	return s.disable()
+}
+
+// Position returns the absolute position of the motor, in steps.
+func (s *Stepper) Position() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.position
+}
+
+// SetPosition sets the absolute position of the motor, such as after homing.
+func (s *Stepper) SetPosition(position int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.position = position
+}
+
+// MaxSpeed returns the maximum speed, in steps per second.
+func (s *Stepper) MaxSpeed() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.speed
+}
+
+// SetMaxSpeed sets the maximum speed, in steps per second.
+//
+// The change applies to subsequent moves.
+func (s *Stepper) SetMaxSpeed(speed float64) error {
+	if speed <= 0 {
+		return ErrInvalidSpeed
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.speed = speed
+	return nil
+}
+
+// Acceleration returns the acceleration, in steps per second per second.
+func (s *Stepper) Acceleration() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accel
+}
+
+// SetAcceleration sets the acceleration, and deceleration, in steps per
+// second per second.
+//
+// Zero disables the ramps, so moves run at the maximum speed throughout.  The
+// change applies to subsequent moves.
+func (s *Stepper) SetAcceleration(accel float64) error {
+	if accel < 0 {
+		return ErrInvalidAcceleration
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accel = accel
+	return nil
+}
+
+// Enable energises the motor.
+//
+// For StepDir this sets the enable line active, if there is one, so the
+// enable line should be requested active low for drivers with an active low
+// enable.  For the four-wire drives this energises the coils for the current
+// position.
+func (s *Stepper) Enable() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.drive == StepDir {
+		if !s.hasEnable {
+			return nil
+		}
+		return s.lines.SetValuesMask(enableMask, enableMask)
+	}
+	return s.lines.SetValuesMask(coilMask, s.seq[s.phase])
+}
+
+// Disable de-energises the motor, so it no longer holds its position.
+func (s *Stepper) Disable() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.disable()
+}
+
+func (s *Stepper) disable() error {
+	if s.drive == StepDir {
+		if !s.hasEnable {
+			return nil
+		}
+		return s.lines.SetValuesMask(enableMask, 0)
+	}
+	return s.lines.SetValuesMask(coilMask, 0)
+}
+
+// Limited returns true if the limit switch for the direction is active.
+func (s *Stepper) Limited(dir Direction) bool {
+	if dir == Forward {
+		return atomic.LoadInt32(&s.forwardLimit) != 0
+	}
+	return atomic.LoadInt32(&s.reverseLimit) != 0
+}
+
+// SetLimit sets or clears the limit in a direction.
+//
+// This is intended for limit switches that are not read via RequestLimit.
+func (s *Stepper) SetLimit(dir Direction, active bool) {
+	var v int32
+	if active {
+		v = 1
+	}
+	if dir == Forward {
+		atomic.StoreInt32(&s.forwardLimit, v)
+	} else {
+		atomic.StoreInt32(&s.reverseLimit, v)
+	}
+	if !active {
+		return
+	}
+	s.mu.Lock()
+	if s.moving && s.moveDir == dir {
+		s.abortMove()
+	}
+	s.mu.Unlock()
+}
+
+// RequestLimit requests a limit switch line which, while active, prevents
+// motion in the direction.
+//
+// The line is watched for edges and a move in the direction is aborted as soon
+// as the switch becomes active.  Switches that pull the line low when active
+// should be requested with gpiod.AsActiveLow.
+//
+// The line remains the responsibility of the caller to close.
+func (s *Stepper) RequestLimit(dir Direction, chip string, offset int, options ...gpiod.LineReqOption) (*gpiod.Line, error) {
+	eh := func(evt gpiod.LineEvent) {
+		s.SetLimit(dir, evt.Type == gpiod.LineEventRisingEdge)
+	}
+	lro := append([]gpiod.LineReqOption{gpiod.AsInput}, options...)
+	lro = append(lro, gpiod.WithBothEdges, gpiod.WithEventHandler(eh))
+	l, err := gpiod.RequestLine(chip, offset, lro...)
+	if err != nil {
+		return nil, err
+	}
+	v, err := l.Value()
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	s.SetLimit(dir, v == 1)
+	return l, nil
+}
+
+// Moving returns true if a move is in progress.
+func (s *Stepper) Moving() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.moving
+}
+
+// MoveTo moves the motor to the absolute position.
+//
+// Blocks until the move is complete, or is aborted.  The move is aborted
+// immediately, without deceleration, if the context is cancelled or a limit
+// switch becomes active, in which case the context error or ErrLimit is
+// returned, and the position reflects the steps taken.
+func (s *Stepper) MoveTo(ctx context.Context, position int64) error {
+	s.moveMu.Lock()
+	defer s.moveMu.Unlock()
+	return s.move(ctx, position-s.Position())
+}
+
+// Move moves the motor by a number of steps relative to the current position,
+// with positive steps moving Forward and negative steps moving Reverse.
+//
+// Blocks until the move is complete, or is aborted, as per MoveTo.
+func (s *Stepper) Move(ctx context.Context, steps int64) error {
+	s.moveMu.Lock()
+	defer s.moveMu.Unlock()
+	return s.move(ctx, steps)
+}
+
+// move performs a move with moveMu held.
+func (s *Stepper) move(ctx context.Context, steps int64) error {
+	dir := Forward
+	if steps < 0 {
+		dir = Reverse
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	p := newProfile(abs(steps), s.speed, s.accel)
+	s.moving = true
+	s.moveDir = dir
+	abort := make(chan struct{})
+	s.abort = abort
+	s.mu.Unlock()
+	finished := make(chan struct{})
+	defer func() {
+		close(finished)
+		s.mu.Lock()
+		s.moving = false
+		if s.abort == abort {
+			s.abort = nil
+		}
+		s.mu.Unlock()
+	}()
+	if steps == 0 {
+		return nil
+	}
+	if done := ctx.Done(); done != nil {
+		go func() {
+			select {
+			case <-done:
+				s.mu.Lock()
+				if s.abort == abort {
+					s.abortMove()
+				}
+				s.mu.Unlock()
+			case <-finished:
+			}
+		}()
+	}
+	if s.drive == StepDir {
+		var bits uint64
+		if dir == Forward {
+			bits = dirMask
+		}
+		if err := s.lines.SetValuesMask(dirMask, bits); err != nil {
+			return err
+		}
+		s.delay.Wait(s.dirSetup)
+	}
+	start := timing.Now()
+	for j := int64(0); j < p.steps; j++ {
+		if err := s.wait(ctx, dir, abort, start+p.at(j)); err != nil {
+			return err
+		}
+		if err := s.step(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wait waits until the deadline, or until the move is aborted.
+func (s *Stepper) wait(ctx context.Context, dir Direction, abort <-chan struct{}, deadline time.Duration) error {
+	if err := s.aborted(ctx, dir); err != nil {
+		return err
+	}
+	if s.delay.UntilOrDone(deadline, abort) {
+		return nil
+	}
+	if err := s.aborted(ctx, dir); err != nil {
+		return err
+	}
+	// the limit was cleared again before it could be checked
+	return ErrLimit
+}
+
+// aborted returns the reason the move in the direction should be aborted, if
+// any.
+func (s *Stepper) aborted(ctx context.Context, dir Direction) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.Limited(dir) {
+		return ErrLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// abortMove aborts the move in progress, if any.
+//
+// Must be called with the mu held.
+func (s *Stepper) abortMove() {
+	if s.abort != nil {
+		close(s.abort)
+		s.abort = nil
+	}
+}
+
+// step takes a single step in the direction.
+func (s *Stepper) step(dir Direction) error {
+	if s.drive == StepDir {
+		if err := s.lines.SetValuesMask(stepMask, stepMask); err != nil {
+			return err
+		}
+		s.delay.Wait(s.pulse)
+		if err := s.lines.SetValuesMask(stepMask, 0); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.position += int64(dir)
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phase := (s.phase + int(dir) + len(s.seq)) % len(s.seq)
+	if err := s.lines.SetValuesMask(coilMask, s.seq[phase]); err != nil {
+		return err
+	}
+	s.phase = phase
+	s.position += int64(dir)
+	return nil
+}
+
+// profile is a trapezoidal motion profile.
+//
+// The motor accelerates at a constant rate from rest to the maximum speed,
+// cruises, then decelerates symmetrically to rest.  If the move is too short to
+// reach the maximum speed the profile is triangular.
+type profile struct {
+	// the number of steps in the move
+	steps int64
+
+	// the distance between the first and last steps
+	dist float64
+
+	speed float64
+	accel float64
+
+	// the distance covered by each ramp
+	ramp float64
+
+	// the time spent on each ramp, in seconds
+	rampTime float64
+
+	// the time of the last step, in seconds
+	total float64
+}
+
+func newProfile(steps int64, speed, accel float64) profile {
+	p := profile{steps: steps, speed: speed, accel: accel}
+	if steps > 0 {
+		p.dist = float64(steps - 1)
+	}
+	if accel == 0 {
+		p.total = p.dist / speed
+		return p
+	}
+	p.ramp = speed * speed / (2 * accel)
+	if p.ramp > p.dist/2 {
+		p.ramp = p.dist / 2
+	}
+	p.rampTime = math.Sqrt(2 * p.ramp / accel)
+	p.total = 2*p.rampTime + (p.dist-2*p.ramp)/speed
+	return p
+}
+
+// at returns the time of step j relative to the first step.
+func (p profile) at(j int64) time.Duration {
+	x := float64(j)
+	var t float64
+	switch {
+	case p.accel == 0:
+		t = x / p.speed
+	case x <= p.ramp:
+		t = math.Sqrt(2 * x / p.accel)
+	case x >= p.dist-p.ramp:
+		t = p.total - math.Sqrt(2*(p.dist-x)/p.accel)
+	default:
+		t = p.rampTime + (x-p.ramp)/p.speed
+	}
+	return time.Duration(t * float64(time.Second))
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Option specifies a construction option for the Stepper.
+type Option func(*Stepper)
+
+// WithMaxSpeed sets the maximum speed, in steps per second.
+func WithMaxSpeed(speed float64) Option {
+	return func(s *Stepper) {
+		if speed <= 0 {
+			s.err = ErrInvalidSpeed
+			return
+		}
+		s.speed = speed
+	}
+}
+
+// WithAcceleration sets the acceleration and deceleration, in steps per second
+// per second.
+func WithAcceleration(accel float64) Option {
+	return func(s *Stepper) {
+		if accel < 0 {
+			s.err = ErrInvalidAcceleration
+			return
+		}
+		s.accel = accel
+	}
+}
+
+// WithPulseWidth sets the width of the step pulses for StepDir.
+//
+// The default is 2µs, which suits most drivers.
+func WithPulseWidth(width time.Duration) Option {
+	return func(s *Stepper) {
+		s.pulse = width
+	}
+}
+
+// WithDirSetup sets the time between setting the dir line and the first step
+// for StepDir.
+//
+// The default is 1µs.
+func WithDirSetup(setup time.Duration) Option {
+	return func(s *Stepper) {
+		s.dirSetup = setup
+	}
+}
+
+// WithTiming sets the options for the Delay used to time the steps and step
+// pulses.
+func WithTiming(options ...timing.Option) Option {
+	return func(s *Stepper) {
+		s.timingOptions = append(s.timingOptions, options...)
+	}
+}
+
+var (
+	// ErrClosed indicates the Stepper has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrInvalidAcceleration indicates the acceleration is negative.
+	ErrInvalidAcceleration = errors.New("invalid acceleration")
+
+	// ErrInvalidDrive indicates the Drive is not known.
+	ErrInvalidDrive = errors.New("invalid drive")
+
+	// ErrInvalidLines indicates the number of lines does not suit the Drive.
+	ErrInvalidLines = errors.New("invalid number of lines")
+
+	// ErrInvalidSpeed indicates the speed is not positive.
+	ErrInvalidSpeed = errors.New("invalid speed")
+
+	// ErrLimit indicates a move was prevented or aborted by a limit.
+	ErrLimit = errors.New("limit reached")
+
+	// ErrNoLines indicates the Stepper was not provided any lines.
+	ErrNoLines = errors.New("no lines")
+)
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package stepper_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/mockup"
+	"github.com/warthog618/gpiod/stepper"
+)
+
+var offsets = []int{1, 2, 3, 4}
+
+func TestNew(t *testing.T) {
+	s, err := stepper.New(nil, stepper.StepDir)
+	assert.Equal(t, stepper.ErrNoLines, err)
+	assert.Nil(t, s)
+
+	m, c := newMockup(t, 8)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	patterns := []struct {
+		name    string
+		drive   stepper.Drive
+		options []stepper.Option
+		err     error
+	}{
+		{"step/dir lines", stepper.StepDir, nil, stepper.ErrInvalidLines},
+		{"drive", stepper.Drive(7), nil, stepper.ErrInvalidDrive},
+		{"speed", stepper.FullStep,
+			[]stepper.Option{stepper.WithMaxSpeed(0)}, stepper.ErrInvalidSpeed},
+		{"acceleration", stepper.FullStep,
+			[]stepper.Option{stepper.WithAcceleration(-1)}, stepper.ErrInvalidAcceleration},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			s, err := stepper.New(ll, p.drive, p.options...)
+			assert.Equal(t, p.err, err)
+			assert.Nil(t, s)
+		}
+		t.Run(p.name, tf)
+	}
+
+	s, err = stepper.New(ll, stepper.HalfStep,
+		stepper.WithMaxSpeed(500),
+		stepper.WithAcceleration(1000))
+	assert.Nil(t, err)
+	require.NotNil(t, s)
+	assert.Equal(t, 500.0, s.MaxSpeed())
+	assert.Equal(t, 1000.0, s.Acceleration())
+	assert.Equal(t, stepper.ErrInvalidSpeed, s.SetMaxSpeed(-1))
+	assert.Equal(t, stepper.ErrInvalidAcceleration, s.SetAcceleration(-1))
+	assert.Nil(t, s.Close())
+	assert.Equal(t, stepper.ErrClosed, s.Close())
+	assert.Equal(t, stepper.ErrClosed, s.Move(context.Background(), 1))
+}
+
+func TestFourWire(t *testing.T) {
+	m, c := newMockup(t, 8)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets, gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	patterns := []struct {
+		name  string
+		drive stepper.Drive
+		// the coils after each of the first steps forward
+		coils [][]int
+	}{
+		{"full", stepper.FullStep, [][]int{{0, 1, 1, 0}, {0, 0, 1, 1}}},
+		{"half", stepper.HalfStep, [][]int{{1, 1, 0, 0}, {0, 1, 0, 0}}},
+		{"wave", stepper.WaveDrive, [][]int{{0, 1, 0, 0}, {0, 0, 1, 0}}},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			s, err := stepper.New(ll, p.drive, stepper.WithMaxSpeed(1000))
+			require.Nil(t, err)
+			defer s.Close()
+			ctx := context.Background()
+
+			for i, coils := range p.coils {
+				assert.Nil(t, s.Move(ctx, 1))
+				assert.Equal(t, int64(i+1), s.Position())
+				checkLines(t, c, coils)
+			}
+			assert.Nil(t, s.MoveTo(ctx, 0))
+			assert.Zero(t, s.Position())
+			assert.Nil(t, s.Move(ctx, 1))
+			checkLines(t, c, p.coils[0])
+
+			assert.Nil(t, s.Disable())
+			checkLines(t, c, []int{0, 0, 0, 0})
+			assert.Nil(t, s.Enable())
+			checkLines(t, c, p.coils[0])
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestStepDir(t *testing.T) {
+	m, c := newMockup(t, 8)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets[:3], gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	s, err := stepper.New(ll, stepper.StepDir,
+		stepper.WithMaxSpeed(2000),
+		stepper.WithAcceleration(20000))
+	require.Nil(t, err)
+	defer s.Close()
+	ctx := context.Background()
+
+	assert.Nil(t, s.Enable())
+	checkLines(t, c, []int{0, 0, 1})
+
+	start := time.Now()
+	assert.Nil(t, s.Move(ctx, 200))
+	elapsed := time.Since(start)
+	assert.Equal(t, int64(200), s.Position())
+	checkLines(t, c, []int{0, 1, 1})
+	// too short to reach full speed, so two ramps of 99.5 steps, each taking
+	// 99.75ms.
+	assert.InDelta(t, 2*99.75, float64(elapsed)/float64(time.Millisecond), 10)
+
+	assert.Nil(t, s.MoveTo(ctx, 150))
+	assert.Equal(t, int64(150), s.Position())
+	checkLines(t, c, []int{0, 0, 1})
+	assert.False(t, s.Moving())
+
+	s.SetPosition(0)
+	assert.Zero(t, s.Position())
+	assert.Nil(t, s.Disable())
+	checkLines(t, c, []int{0, 0, 0})
+}
+
+func TestAbort(t *testing.T) {
+	m, c := newMockup(t, 8)
+	defer m.Close()
+	ll, err := gpiod.RequestLines(c.Name, offsets[:2], gpiod.AsOutput())
+	require.Nil(t, err)
+	defer ll.Close()
+
+	s, err := stepper.New(ll, stepper.StepDir, stepper.WithMaxSpeed(100))
+	require.Nil(t, err)
+	defer s.Close()
+
+	// context
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	err = s.Move(ctx, 100)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.InDelta(t, 6, s.Position(), 1)
+
+	// limit switch
+	l, err := s.RequestLimit(stepper.Forward, c.Name, 6)
+	require.Nil(t, err)
+	defer l.Close()
+	assert.False(t, s.Limited(stepper.Forward))
+	s.SetPosition(0)
+	go func() {
+		time.Sleep(55 * time.Millisecond)
+		c.SetValue(6, 1)
+	}()
+	err = s.Move(context.Background(), 100)
+	assert.Equal(t, stepper.ErrLimit, err)
+	assert.InDelta(t, 6, s.Position(), 1)
+	assert.True(t, s.Limited(stepper.Forward))
+
+	// limited in one direction only
+	pos := s.Position()
+	assert.Equal(t, stepper.ErrLimit, s.Move(context.Background(), 1))
+	assert.Equal(t, pos, s.Position())
+	assert.Nil(t, s.Move(context.Background(), -1))
+	assert.Equal(t, pos-1, s.Position())
+
+	c.SetValue(6, 0)
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, s.Limited(stepper.Forward))
+	assert.Nil(t, s.Move(context.Background(), 1))
+
+	// closed while waiting for a step
+	require.Nil(t, s.SetMaxSpeed(0.1))
+	errs := make(chan error)
+	go func() {
+		errs <- s.Move(context.Background(), 10)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	assert.Nil(t, s.Close())
+	assert.Equal(t, stepper.ErrClosed, <-errs)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func checkLines(t *testing.T, c *mockup.Chip, values []int) {
+	t.Helper()
+	for i, v := range values {
+		lv, err := c.Value(offsets[i])
+		assert.Nil(t, err)
+		assert.Equal(t, v, lv, i)
+	}
+}
+
+func newMockup(t *testing.T, lines ...int) (*mockup.Mockup, *mockup.Chip) {
+	t.Helper()
+	m, err := mockup.New(lines, false)
+	require.Nil(t, err)
+	c, err := m.Chip(0)
+	require.Nil(t, err)
+	return m, c
+}
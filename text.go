@@ -0,0 +1,305 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiod
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The text forms of the enumerated types are lower case and hyphenated, and
+// are intended to be shared by configuration files and command line tools.
+//
+// The text form of a LineConfig is a comma separated list of the non-default
+// configuration settings, e.g. "output,active-low,open-drain,pull-up".
+
+var directionNames = []string{
+	LineDirectionUnknown: "as-is",
+	LineDirectionInput:   "input",
+	LineDirectionOutput:  "output",
+}
+
+var driveNames = []string{
+	LineDrivePushPull:   "push-pull",
+	LineDriveOpenDrain:  "open-drain",
+	LineDriveOpenSource: "open-source",
+}
+
+var biasNames = []string{
+	LineBiasUnknown:  "as-is",
+	LineBiasDisabled: "disabled",
+	LineBiasPullUp:   "pull-up",
+	LineBiasPullDown: "pull-down",
+}
+
+var edgeNames = []string{
+	LineEdgeNone:    "none",
+	LineEdgeRising:  "rising",
+	LineEdgeFalling: "falling",
+	LineEdgeBoth:    "both",
+}
+
+var eventClockNames = []string{
+	LineEventClockMonotonic: "monotonic",
+	LineEventClockRealtime:  "realtime",
+}
+
+// aliases for the text forms, as used by earlier versions of the tools.
+var (
+	directionAliases = map[string]int{"unknown": int(LineDirectionUnknown)}
+	biasAliases      = map[string]int{
+		"disable":       int(LineBiasDisabled),
+		"bias-disabled": int(LineBiasDisabled),
+		"unknown":       int(LineBiasUnknown),
+	}
+)
+
+func enumString(names []string, v int) string {
+	if v >= 0 && v < len(names) {
+		return names[v]
+	}
+	return fmt.Sprintf("%d", v)
+}
+
+func parseEnum(typ string, names []string, aliases map[string]int, text []byte) (int, error) {
+	s := strings.ToLower(strings.TrimSpace(string(text)))
+	for v, name := range names {
+		if s == name {
+			return v, nil
+		}
+	}
+	if v, ok := aliases[s]; ok {
+		return v, nil
+	}
+	return 0, ErrInvalidText{typ, string(text)}
+}
+
+// String returns the text form of the direction.
+func (d LineDirection) String() string {
+	return enumString(directionNames, int(d))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d LineDirection) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *LineDirection) UnmarshalText(text []byte) error {
+	v, err := parseEnum("direction", directionNames, directionAliases, text)
+	if err == nil {
+		*d = LineDirection(v)
+	}
+	return err
+}
+
+// String returns the text form of the drive.
+func (d LineDrive) String() string {
+	return enumString(driveNames, int(d))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d LineDrive) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *LineDrive) UnmarshalText(text []byte) error {
+	v, err := parseEnum("drive", driveNames, nil, text)
+	if err == nil {
+		*d = LineDrive(v)
+	}
+	return err
+}
+
+// String returns the text form of the bias.
+func (b LineBias) String() string {
+	return enumString(biasNames, int(b))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b LineBias) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *LineBias) UnmarshalText(text []byte) error {
+	v, err := parseEnum("bias", biasNames, biasAliases, text)
+	if err == nil {
+		*b = LineBias(v)
+	}
+	return err
+}
+
+// String returns the text form of the edge detection.
+func (e LineEdge) String() string {
+	return enumString(edgeNames, int(e))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (e LineEdge) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (e *LineEdge) UnmarshalText(text []byte) error {
+	v, err := parseEnum("edge", edgeNames, nil, text)
+	if err == nil {
+		*e = LineEdge(v)
+	}
+	return err
+}
+
+// String returns the text form of the event clock.
+func (c LineEventClock) String() string {
+	return enumString(eventClockNames, int(c))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c LineEventClock) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *LineEventClock) UnmarshalText(text []byte) error {
+	v, err := parseEnum("event clock", eventClockNames, nil, text)
+	if err == nil {
+		*c = LineEventClock(v)
+	}
+	return err
+}
+
+// String returns the text form of the configuration.
+//
+// Settings with default values are omitted, so the default configuration is an
+// empty string.
+func (lc LineConfig) String() string {
+	var ss []string
+	if lc.Direction != LineDirectionUnknown {
+		ss = append(ss, lc.Direction.String())
+	}
+	if lc.ActiveLow {
+		ss = append(ss, "active-low")
+	}
+	if lc.Drive != LineDrivePushPull {
+		ss = append(ss, lc.Drive.String())
+	}
+	switch lc.Bias {
+	case LineBiasUnknown:
+	case LineBiasDisabled:
+		ss = append(ss, "bias-disabled")
+	default:
+		ss = append(ss, lc.Bias.String())
+	}
+	if lc.EdgeDetection != LineEdgeNone {
+		ss = append(ss, "edge="+lc.EdgeDetection.String())
+	}
+	if lc.Debounced {
+		ss = append(ss, "debounce-period="+lc.DebouncePeriod.String())
+	}
+	if lc.EventClock != LineEventClockMonotonic {
+		ss = append(ss, "event-clock="+lc.EventClock.String())
+	}
+	return strings.Join(ss, ",")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (lc LineConfig) MarshalText() ([]byte, error) {
+	return []byte(lc.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (lc *LineConfig) UnmarshalText(text []byte) error {
+	c, err := ParseLineConfig(string(text))
+	if err == nil {
+		*lc = c
+	}
+	return err
+}
+
+// ParseLineConfig parses the text form of a LineConfig.
+//
+// The text is a comma separated list of settings, applied in order.  Each
+// setting is either a bare value, such as "output", "active-low",
+// "open-drain", "pull-up" or "bias-disabled", or a key=value pair, where the
+// keys are "direction", "drive", "bias", "edge", "debounce-period" and
+// "event-clock".  The bias may be disabled with either "bias-disabled" or the
+// LineBias text form, "disabled".
+//
+// Unspecified settings take their default values.  Settings are not checked
+// for consistency, e.g. an output line with edge detection, as that is left to
+// the kernel when the configuration is applied.
+func ParseLineConfig(s string) (lc LineConfig, err error) {
+	for _, field := range strings.Split(s, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if len(field) == 0 {
+			continue
+		}
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) == 2 {
+			err = lc.setKeyValue(kv[0], kv[1])
+		} else {
+			err = lc.setValue(field)
+		}
+		if err != nil {
+			return LineConfig{}, err
+		}
+	}
+	return
+}
+
+func (lc *LineConfig) setValue(v string) error {
+	switch v {
+	case "active-low":
+		lc.ActiveLow = true
+	case "active-high":
+		lc.ActiveLow = false
+	case "input", "output":
+		return lc.Direction.UnmarshalText([]byte(v))
+	case "push-pull", "open-drain", "open-source":
+		return lc.Drive.UnmarshalText([]byte(v))
+	case "pull-up", "pull-down", "disabled", "bias-disabled":
+		return lc.Bias.UnmarshalText([]byte(v))
+	default:
+		return ErrInvalidText{"line config", v}
+	}
+	return nil
+}
+
+func (lc *LineConfig) setKeyValue(k, v string) error {
+	switch k {
+	case "direction":
+		return lc.Direction.UnmarshalText([]byte(v))
+	case "drive":
+		return lc.Drive.UnmarshalText([]byte(v))
+	case "bias":
+		return lc.Bias.UnmarshalText([]byte(v))
+	case "edge":
+		return lc.EdgeDetection.UnmarshalText([]byte(v))
+	case "event-clock":
+		return lc.EventClock.UnmarshalText([]byte(v))
+	case "debounce-period":
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return ErrInvalidText{"debounce period", v}
+		}
+		lc.Debounced = d != 0
+		lc.DebouncePeriod = d
+	default:
+		return ErrInvalidText{"line config", k + "=" + v}
+	}
+	return nil
+}
+
+// ErrInvalidText indicates the text cannot be parsed as the given type.
+type ErrInvalidText struct {
+	Type string
+	Text string
+}
+
+func (e ErrInvalidText) Error() string {
+	return fmt.Sprintf("invalid %s: '%s'", e.Type, e.Text)
+}
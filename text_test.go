@@ -0,0 +1,276 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiod_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod"
+)
+
+func TestLineDirectionText(t *testing.T) {
+	patterns := []struct {
+		text string
+		val  gpiod.LineDirection
+	}{
+		{"as-is", gpiod.LineDirectionUnknown},
+		{"input", gpiod.LineDirectionInput},
+		{"output", gpiod.LineDirectionOutput},
+	}
+	for _, p := range patterns {
+		assert.Equal(t, p.text, p.val.String())
+		var d gpiod.LineDirection
+		err := d.UnmarshalText([]byte(p.text))
+		assert.Nil(t, err)
+		assert.Equal(t, p.val, d)
+	}
+	var d gpiod.LineDirection
+	err := d.UnmarshalText([]byte("Output"))
+	assert.Nil(t, err)
+	assert.Equal(t, gpiod.LineDirectionOutput, d)
+	err = d.UnmarshalText([]byte("sideways"))
+	assert.Equal(t, gpiod.ErrInvalidText{Type: "direction", Text: "sideways"}, err)
+	assert.Equal(t, gpiod.LineDirectionOutput, d)
+	assert.Equal(t, "7", gpiod.LineDirection(7).String())
+}
+
+func TestLineDriveText(t *testing.T) {
+	patterns := []struct {
+		text string
+		val  gpiod.LineDrive
+	}{
+		{"push-pull", gpiod.LineDrivePushPull},
+		{"open-drain", gpiod.LineDriveOpenDrain},
+		{"open-source", gpiod.LineDriveOpenSource},
+	}
+	for _, p := range patterns {
+		assert.Equal(t, p.text, p.val.String())
+		var d gpiod.LineDrive
+		err := d.UnmarshalText([]byte(p.text))
+		assert.Nil(t, err)
+		assert.Equal(t, p.val, d)
+	}
+	var d gpiod.LineDrive
+	err := d.UnmarshalText([]byte("open"))
+	assert.NotNil(t, err)
+}
+
+func TestLineBiasText(t *testing.T) {
+	patterns := []struct {
+		text string
+		val  gpiod.LineBias
+	}{
+		{"as-is", gpiod.LineBiasUnknown},
+		{"disabled", gpiod.LineBiasDisabled},
+		{"pull-up", gpiod.LineBiasPullUp},
+		{"pull-down", gpiod.LineBiasPullDown},
+	}
+	for _, p := range patterns {
+		assert.Equal(t, p.text, p.val.String())
+		var b gpiod.LineBias
+		err := b.UnmarshalText([]byte(p.text))
+		assert.Nil(t, err)
+		assert.Equal(t, p.val, b)
+	}
+	// aliases
+	var b gpiod.LineBias
+	err := b.UnmarshalText([]byte("disable"))
+	assert.Nil(t, err)
+	assert.Equal(t, gpiod.LineBiasDisabled, b)
+	err = b.UnmarshalText([]byte("pull-sideways"))
+	assert.NotNil(t, err)
+
+	// round trip via the LineConfig text form
+	for _, p := range patterns[1:] {
+		text, err := p.val.MarshalText()
+		assert.Nil(t, err)
+		cfg, err := gpiod.ParseLineConfig(string(text))
+		assert.Nil(t, err)
+		assert.Equal(t, p.val, cfg.Bias, p.text)
+		cfg, err = gpiod.ParseLineConfig("bias=" + string(text))
+		assert.Nil(t, err)
+		assert.Equal(t, p.val, cfg.Bias, p.text)
+		err = b.UnmarshalText([]byte(cfg.String()))
+		assert.Nil(t, err)
+		assert.Equal(t, p.val, b, p.text)
+	}
+}
+
+func TestLineEdgeText(t *testing.T) {
+	patterns := []struct {
+		text string
+		val  gpiod.LineEdge
+	}{
+		{"none", gpiod.LineEdgeNone},
+		{"rising", gpiod.LineEdgeRising},
+		{"falling", gpiod.LineEdgeFalling},
+		{"both", gpiod.LineEdgeBoth},
+	}
+	for _, p := range patterns {
+		assert.Equal(t, p.text, p.val.String())
+		var e gpiod.LineEdge
+		err := e.UnmarshalText([]byte(p.text))
+		assert.Nil(t, err)
+		assert.Equal(t, p.val, e)
+	}
+	var e gpiod.LineEdge
+	err := e.UnmarshalText([]byte("sharp"))
+	assert.NotNil(t, err)
+}
+
+func TestLineEventClockText(t *testing.T) {
+	patterns := []struct {
+		text string
+		val  gpiod.LineEventClock
+	}{
+		{"monotonic", gpiod.LineEventClockMonotonic},
+		{"realtime", gpiod.LineEventClockRealtime},
+	}
+	for _, p := range patterns {
+		assert.Equal(t, p.text, p.val.String())
+		var c gpiod.LineEventClock
+		err := c.UnmarshalText([]byte(p.text))
+		assert.Nil(t, err)
+		assert.Equal(t, p.val, c)
+	}
+	var c gpiod.LineEventClock
+	err := c.UnmarshalText([]byte("sundial"))
+	assert.NotNil(t, err)
+}
+
+func TestParseLineConfig(t *testing.T) {
+	patterns := []struct {
+		name string
+		text string
+		cfg  gpiod.LineConfig
+		// text form when marshalled, if different
+		xtext string
+	}{
+		{"empty", "", gpiod.LineConfig{}, ""},
+		{"input", "input", gpiod.LineConfig{Direction: gpiod.LineDirectionInput}, ""},
+		{"output",
+			"output,active-low,open-drain,pull-up",
+			gpiod.LineConfig{
+				Direction: gpiod.LineDirectionOutput,
+				ActiveLow: true,
+				Drive:     gpiod.LineDriveOpenDrain,
+				Bias:      gpiod.LineBiasPullUp,
+			},
+			""},
+		{"edges",
+			"input,bias-disabled,edge=both,debounce-period=10ms,event-clock=realtime",
+			gpiod.LineConfig{
+				Direction:      gpiod.LineDirectionInput,
+				Bias:           gpiod.LineBiasDisabled,
+				EdgeDetection:  gpiod.LineEdgeBoth,
+				Debounced:      true,
+				DebouncePeriod: 10 * time.Millisecond,
+				EventClock:     gpiod.LineEventClockRealtime,
+			},
+			""},
+		{"keys",
+			" Direction=output , drive=open-source, bias=pull-down ",
+			gpiod.LineConfig{
+				Direction: gpiod.LineDirectionOutput,
+				Drive:     gpiod.LineDriveOpenSource,
+				Bias:      gpiod.LineBiasPullDown,
+			},
+			"output,open-source,pull-down"},
+		{"in order",
+			"active-low,pull-up,active-high,push-pull,debounce-period=0",
+			gpiod.LineConfig{Bias: gpiod.LineBiasPullUp},
+			"pull-up"},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			cfg, err := gpiod.ParseLineConfig(p.text)
+			assert.Nil(t, err)
+			assert.Equal(t, p.cfg, cfg)
+			xtext := p.xtext
+			if len(xtext) == 0 {
+				xtext = p.text
+			}
+			assert.Equal(t, xtext, cfg.String())
+			b, err := cfg.MarshalText()
+			assert.Nil(t, err)
+			var rcfg gpiod.LineConfig
+			err = rcfg.UnmarshalText(b)
+			assert.Nil(t, err)
+			assert.Equal(t, cfg, rcfg)
+		}
+		t.Run(p.name, tf)
+	}
+
+	badPatterns := []struct {
+		text string
+		err  error
+	}{
+		{"input,sideways", gpiod.ErrInvalidText{Type: "line config", Text: "sideways"}},
+		{"colour=blue", gpiod.ErrInvalidText{Type: "line config", Text: "colour=blue"}},
+		{"edge=sharp", gpiod.ErrInvalidText{Type: "edge", Text: "sharp"}},
+		{"debounce-period=soon", gpiod.ErrInvalidText{Type: "debounce period", Text: "soon"}},
+		{"debounce-period=-1ms", gpiod.ErrInvalidText{Type: "debounce period", Text: "-1ms"}},
+	}
+	for _, p := range badPatterns {
+		cfg, err := gpiod.ParseLineConfig(p.text)
+		assert.Equal(t, p.err, err, p.text)
+		assert.Equal(t, gpiod.LineConfig{}, cfg)
+	}
+}
+
+func TestLineInfoJSON(t *testing.T) {
+	li := gpiod.LineInfo{
+		Offset:   3,
+		Name:     "LED",
+		Consumer: "blinker",
+		Used:     true,
+		Config: gpiod.LineConfig{
+			Direction: gpiod.LineDirectionOutput,
+			ActiveLow: true,
+		},
+	}
+	b, err := json.Marshal(li)
+	require.Nil(t, err)
+	assert.Equal(t,
+		`{"offset":3,"name":"LED","consumer":"blinker","used":true,"config":"output,active-low"}`,
+		string(b))
+	var rli gpiod.LineInfo
+	err = json.Unmarshal(b, &rli)
+	assert.Nil(t, err)
+	assert.Equal(t, li, rli)
+
+	err = json.Unmarshal([]byte(`{"offset":3,"config":"output,bogus"}`), &rli)
+	assert.NotNil(t, err)
+}
+
+func TestLineConfigOption(t *testing.T) {
+	cfg, err := gpiod.ParseLineConfig("input,pull-up,edge=falling")
+	require.Nil(t, err)
+	ocfg, err := gpiod.ParseLineConfig("output,open-drain")
+	require.Nil(t, err)
+
+	rp, err := gpiod.PlanRequest([]int{1, 2, 3},
+		gpiod.AsOutput(1, 1, 1),
+		cfg,
+		gpiod.WithLines([]int{3}, ocfg))
+	require.Nil(t, err)
+	assert.Equal(t, []gpiod.LineConfig{cfg, cfg, ocfg}, rp.Config)
+	assert.Equal(t, map[int]int{3: 1}, rp.Values)
+
+	// direction as option
+	rp, err = gpiod.PlanRequest([]int{1, 2},
+		gpiod.WithDebounce(time.Millisecond),
+		gpiod.WithLines([]int{2}, gpiod.LineDirectionOutput),
+		gpiod.LineDirectionUnknown)
+	require.Nil(t, err)
+	assert.Equal(t, []gpiod.LineConfig{
+		{Debounced: true, DebouncePeriod: time.Millisecond},
+		{Direction: gpiod.LineDirectionOutput},
+	}, rp.Config)
+}
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package timing
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// the longest UntilOrDone blocks before checking whether it is done.
+const pollPeriod = 10 * time.Millisecond
+
+// Mode determines how a Delay waits.
+type Mode int
+
+const (
+	// Hybrid blocks on a timerfd until shortly before the deadline, and then
+	// busy-waits for the remainder.
+	//
+	// This provides the precision of BusyWait for short delays without
+	// consuming a CPU for the full period of long delays.
+	Hybrid Mode = iota
+
+	// BusyWait spins on the clock until the deadline.
+	//
+	// This is the most precise, but consumes a CPU for the period of the
+	// delay.
+	BusyWait
+
+	// Timerfd blocks on a timerfd until the deadline.
+	Timerfd
+
+	// Sleep uses time.Sleep, and is provided for comparison.
+	Sleep
+)
+
+// Delay performs precise delays.
+//
+// A Delay is not safe for concurrent use.
+type Delay struct {
+	mode Mode
+
+	// the timerfd used for Hybrid and Timerfd modes.
+	fd int
+
+	// the period before the deadline at which Hybrid switches to spinning.
+	spin time.Duration
+
+	// the period before the deadline at which spinning stops, to allow for
+	// the time taken to read the clock.
+	early time.Duration
+
+	cal *Calibration
+
+	stats Stats
+}
+
+// NewDelay creates a Delay.
+//
+// Unless a calibration is provided, the Hybrid and BusyWait modes use the
+// DefaultCalibration, which calibrates the platform on first use.
+func NewDelay(options ...Option) (*Delay, error) {
+	d := Delay{fd: -1, spin: -1}
+	for _, option := range options {
+		option(&d)
+	}
+	switch d.mode {
+	case Hybrid, BusyWait:
+		if d.cal == nil {
+			cal, err := DefaultCalibration()
+			if err != nil {
+				return nil, err
+			}
+			d.cal = &cal
+		}
+		// the clock is read on average half way through the spin loop
+		d.early = d.cal.ClockOverhead / 2
+		if d.spin < 0 {
+			d.spin = d.cal.WakeLatency
+		}
+	case Timerfd, Sleep:
+	default:
+		return nil, ErrInvalidMode
+	}
+	if d.mode == BusyWait || d.mode == Sleep {
+		return &d, nil
+	}
+	fd, err := unix.TimerfdCreate(unix.CLOCK_MONOTONIC, unix.TFD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	d.fd = fd
+	return &d, nil
+}
+
+// Close releases the resources held by the Delay.
+//
+// The Delay remains usable after Close, but waits that would have blocked on
+// the timerfd block using time.Sleep instead, so are less precise.
+func (d *Delay) Close() error {
+	if d.fd < 0 {
+		return nil
+	}
+	err := unix.Close(d.fd)
+	d.fd = -1
+	return err
+}
+
+// Mode returns the mode of the Delay.
+func (d *Delay) Mode() Mode {
+	return d.mode
+}
+
+// Wait delays for the period.
+func (d *Delay) Wait(period time.Duration) {
+	start := Now()
+	d.wait(start, start+period)
+}
+
+// Until delays until the deadline on CLOCK_MONOTONIC, as returned by Now.
+//
+// Returns immediately if the deadline has already passed.
+func (d *Delay) Until(deadline time.Duration) {
+	d.wait(Now(), deadline)
+}
+
+// UntilOrDone delays until the deadline, as per Until, unless done is closed
+// first.
+//
+// Returns true if the deadline was reached, or false if done was closed.  The
+// closing of done is detected within 10ms.
+func (d *Delay) UntilOrDone(deadline time.Duration, done <-chan struct{}) bool {
+	start := Now()
+	for {
+		select {
+		case <-done:
+			return false
+		default:
+		}
+		now := Now()
+		if deadline-now <= pollPeriod {
+			break
+		}
+		d.block(now + pollPeriod)
+	}
+	d.wait(start, deadline)
+	return true
+}
+
+// Stats returns the timing achieved by the delays since the Delay was created
+// or the stats were last reset.
+func (d *Delay) Stats() Stats {
+	return d.stats
+}
+
+// ResetStats clears the recorded stats.
+func (d *Delay) ResetStats() {
+	d.stats = Stats{}
+}
+
+func (d *Delay) wait(start, deadline time.Duration) {
+	if deadline <= start {
+		return
+	}
+	switch d.mode {
+	case Sleep:
+		sleep(deadline)
+	case Timerfd:
+		d.block(deadline)
+	case Hybrid:
+		if deadline-start > d.spin {
+			d.block(deadline - d.spin)
+		}
+		spinUntil(deadline - d.early)
+	default:
+		spinUntil(deadline - d.early)
+	}
+	d.stats.Add(start, deadline, Now())
+}
+
+// block waits until the deadline without spinning, using the timerfd unless
+// the Delay has been closed.
+func (d *Delay) block(deadline time.Duration) {
+	if d.fd < 0 || sleepUntil(d.fd, deadline) != nil {
+		sleep(deadline)
+	}
+}
+
+// sleep waits until the deadline using time.Sleep.
+func sleep(deadline time.Duration) {
+	if period := deadline - Now(); period > 0 {
+		time.Sleep(period)
+	}
+}
+
+func spinUntil(deadline time.Duration) {
+	for Now() < deadline {
+	}
+}
+
+// Option specifies a construction option for a Delay.
+type Option func(*Delay)
+
+// WithMode sets the mode of the Delay.
+//
+// The default is Hybrid.
+func WithMode(mode Mode) Option {
+	return func(d *Delay) {
+		d.mode = mode
+	}
+}
+
+// WithCalibration provides the calibration for a Hybrid or BusyWait Delay,
+// rather than using the DefaultCalibration.
+func WithCalibration(cal Calibration) Option {
+	return func(d *Delay) {
+		d.cal = &cal
+	}
+}
+
+// WithSpinPeriod sets the period before the deadline at which a Hybrid Delay
+// switches from blocking to busy-waiting.
+//
+// This overrides the WakeLatency of the calibration.
+func WithSpinPeriod(spin time.Duration) Option {
+	return func(d *Delay) {
+		d.spin = spin
+	}
+}
+
+var (
+	// ErrInvalidMode indicates the mode is not a known Mode.
+	ErrInvalidMode = errors.New("invalid mode")
+)
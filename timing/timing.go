@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+// Package timing provides precise delays for bit-bashed protocols.
+//
+// The resolution of time.Sleep is limited by the Go scheduler, and in practice
+// short sleeps take tens of microseconds or longer, with considerable jitter.
+// This package provides delays that busy-wait, that block on a timerfd, or a
+// hybrid of the two, as well as waiting until absolute deadlines on
+// CLOCK_MONOTONIC, the clock used for line event timestamps.
+//
+// The achieved timing of delays is recorded, so it can be compared with the
+// requested timing.
+package timing
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Now returns the current time on CLOCK_MONOTONIC.
+//
+// This is the clock domain of line event timestamps, unless the line is
+// configured to use a different event clock.
+//
+// Unlike reading the clock with unix.ClockGettime, Now is derived from the
+// runtime monotonic clock and so is cheap enough to be called in a busy-wait.
+func Now() time.Duration {
+	return monoBase + time.Since(monoRef)
+}
+
+var (
+	// monoRef is a reference point on the runtime monotonic clock.
+	monoRef time.Time
+
+	// monoBase is the value of CLOCK_MONOTONIC at monoRef.
+	monoBase time.Duration
+)
+
+func init() {
+	monoRef, monoBase = syncMonotonic()
+}
+
+// syncMonotonic determines the offset between the runtime monotonic clock and
+// CLOCK_MONOTONIC.
+//
+// The clock is read between two readings of the runtime clock, and the
+// tightest of several such brackets is used to minimise the error.
+func syncMonotonic() (time.Time, time.Duration) {
+	ref := time.Now()
+	var base time.Duration
+	best := time.Duration(-1)
+	for i := 0; i < 16; i++ {
+		t0 := time.Now()
+		var ts unix.Timespec
+		if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+			continue
+		}
+		t1 := time.Now()
+		span := t1.Sub(t0)
+		if best < 0 || span < best {
+			best = span
+			mid := t0.Add(span / 2)
+			base = time.Duration(ts.Nano()) - mid.Sub(ref)
+		}
+	}
+	return ref, base
+}
+
+// Stats records the achieved timing of delays.
+type Stats struct {
+	// Count is the number of delays recorded.
+	Count uint64
+
+	// Requested is the total of the requested delays.
+	Requested time.Duration
+
+	// Achieved is the total of the achieved delays.
+	Achieved time.Duration
+
+	// MaxLate is the largest amount by which a delay exceeded its request.
+	MaxLate time.Duration
+}
+
+// MeanError returns the mean of the amount by which the achieved delays
+// exceeded the requested delays.
+func (s Stats) MeanError() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return (s.Achieved - s.Requested) / time.Duration(s.Count)
+}
+
+// Add records a delay that started at start, was due to end at deadline, and
+// actually ended at end.
+func (s *Stats) Add(start, deadline, end time.Duration) {
+	s.Count++
+	s.Requested += deadline - start
+	s.Achieved += end - start
+	if late := end - deadline; late > s.MaxLate {
+		s.MaxLate = late
+	}
+}
+
+// Calibration contains the measured characteristics of the platform that
+// determine how delays are performed.
+type Calibration struct {
+	// ClockOverhead is the time taken to read the clock.
+	ClockOverhead time.Duration
+
+	// WakeLatency is the time taken to wake after a timerfd expires.
+	//
+	// Hybrid delays busy-wait for this period before the deadline.
+	WakeLatency time.Duration
+}
+
+// Calibrate measures the characteristics of the platform.
+//
+// This takes several milliseconds.
+func Calibrate() (Calibration, error) {
+	var c Calibration
+	const reads = 1000
+	start := Now()
+	for i := 0; i < reads; i++ {
+		Now()
+	}
+	c.ClockOverhead = (Now() - start) / reads
+
+	fd, err := unix.TimerfdCreate(unix.CLOCK_MONOTONIC, unix.TFD_CLOEXEC)
+	if err != nil {
+		return c, err
+	}
+	defer unix.Close(fd)
+	const samples = 32
+	late := make([]time.Duration, samples)
+	for i := range late {
+		deadline := Now() + 100*time.Microsecond
+		if err := sleepUntil(fd, deadline); err != nil {
+			return c, err
+		}
+		late[i] = Now() - deadline
+	}
+	// the worst case is an outlier, so take the 90th percentile
+	sort.Slice(late, func(i, j int) bool { return late[i] < late[j] })
+	c.WakeLatency = late[samples*9/10]
+	return c, nil
+}
+
+// DefaultCalibration returns the calibration used by delays that are not
+// provided one.
+//
+// The platform is calibrated on the first call.
+func DefaultCalibration() (Calibration, error) {
+	defCal.once.Do(func() {
+		defCal.c, defCal.err = Calibrate()
+	})
+	return defCal.c, defCal.err
+}
+
+var defCal struct {
+	once sync.Once
+	c    Calibration
+	err  error
+}
+
+// sleepUntil blocks on the timerfd until the deadline on CLOCK_MONOTONIC.
+func sleepUntil(fd int, deadline time.Duration) error {
+	its := unix.ItimerSpec{Value: unix.NsecToTimespec(int64(deadline))}
+	if err := unix.TimerfdSettime(fd, unix.TFD_TIMER_ABSTIME, &its, nil); err != nil {
+		return err
+	}
+	var buf [8]byte
+	for {
+		_, err := unix.Read(fd, buf[:])
+		if err != unix.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+// +build linux
+
+package timing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/gpiod/timing"
+	"golang.org/x/sys/unix"
+)
+
+func TestNow(t *testing.T) {
+	var ts unix.Timespec
+	err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts)
+	require.Nil(t, err)
+	now := timing.Now()
+	mono := time.Duration(ts.Nano())
+	assert.InDelta(t, float64(mono), float64(now), float64(time.Millisecond))
+
+	then := now
+	now = timing.Now()
+	assert.GreaterOrEqual(t, now, then)
+}
+
+func TestCalibrate(t *testing.T) {
+	cal, err := timing.Calibrate()
+	require.Nil(t, err)
+	assert.Greater(t, cal.ClockOverhead, time.Duration(0))
+	assert.Less(t, cal.ClockOverhead, 10*time.Microsecond)
+	assert.GreaterOrEqual(t, cal.WakeLatency, time.Duration(0))
+
+	dcal, err := timing.DefaultCalibration()
+	require.Nil(t, err)
+	dcal2, err := timing.DefaultCalibration()
+	require.Nil(t, err)
+	assert.Equal(t, dcal, dcal2)
+}
+
+func TestStats(t *testing.T) {
+	var s timing.Stats
+	assert.Zero(t, s.MeanError())
+
+	s = timing.Stats{Count: 4, Requested: 40, Achieved: 48, MaxLate: 5}
+	assert.Equal(t, time.Duration(2), s.MeanError())
+}
+
+func TestNewDelay(t *testing.T) {
+	d, err := timing.NewDelay(timing.WithMode(timing.Mode(42)))
+	assert.Equal(t, timing.ErrInvalidMode, err)
+	assert.Nil(t, d)
+
+	modes := []timing.Mode{timing.Hybrid, timing.BusyWait, timing.Timerfd, timing.Sleep}
+	for _, m := range modes {
+		d, err = timing.NewDelay(timing.WithMode(m))
+		assert.Nil(t, err)
+		require.NotNil(t, d)
+		assert.Equal(t, m, d.Mode())
+		assert.Nil(t, d.Close())
+		assert.Nil(t, d.Close())
+	}
+
+	// default
+	d, err = timing.NewDelay()
+	assert.Nil(t, err)
+	require.NotNil(t, d)
+	assert.Equal(t, timing.Hybrid, d.Mode())
+	d.Close()
+}
+
+// spinning stops marginally before the deadline to allow for reading the clock.
+const slop = time.Microsecond
+
+func TestDelayWait(t *testing.T) {
+	patterns := []struct {
+		name    string
+		options []timing.Option
+		period  time.Duration
+	}{
+		{"busy short", []timing.Option{timing.WithMode(timing.BusyWait)}, time.Microsecond},
+		{"busy long", []timing.Option{timing.WithMode(timing.BusyWait)}, 500 * time.Microsecond},
+		{"hybrid short", nil, 500 * time.Nanosecond},
+		{"hybrid long", []timing.Option{timing.WithSpinPeriod(100 * time.Microsecond)}, 2 * time.Millisecond},
+		{"hybrid calibrated",
+			[]timing.Option{timing.WithCalibration(timing.Calibration{WakeLatency: 50 * time.Microsecond})},
+			time.Millisecond},
+		{"timerfd", []timing.Option{timing.WithMode(timing.Timerfd)}, time.Millisecond},
+		{"sleep", []timing.Option{timing.WithMode(timing.Sleep)}, time.Millisecond},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			d, err := timing.NewDelay(p.options...)
+			require.Nil(t, err)
+			defer d.Close()
+			for i := 0; i < 10; i++ {
+				start := time.Now()
+				d.Wait(p.period)
+				assert.GreaterOrEqual(t, time.Since(start), p.period-slop)
+			}
+			s := d.Stats()
+			assert.Equal(t, uint64(10), s.Count)
+			assert.Equal(t, 10*p.period, s.Requested)
+			assert.GreaterOrEqual(t, s.Achieved, s.Requested-10*slop)
+			d.ResetStats()
+			assert.Equal(t, timing.Stats{}, d.Stats())
+		}
+		t.Run(p.name, tf)
+	}
+}
+
+func TestDelayUntil(t *testing.T) {
+	d, err := timing.NewDelay()
+	require.Nil(t, err)
+	defer d.Close()
+
+	deadline := timing.Now() + time.Millisecond
+	d.Until(deadline)
+	assert.GreaterOrEqual(t, timing.Now(), deadline-slop)
+	assert.Equal(t, uint64(1), d.Stats().Count)
+
+	// in the past
+	d.Until(deadline)
+	assert.Equal(t, uint64(1), d.Stats().Count)
+
+	// closed falls back to sleeping
+	d.Close()
+	deadline = timing.Now() + time.Millisecond
+	d.Until(deadline)
+	assert.GreaterOrEqual(t, timing.Now(), deadline-slop)
+}
+
+func TestDelayUntilOrDone(t *testing.T) {
+	d, err := timing.NewDelay()
+	require.Nil(t, err)
+	defer d.Close()
+
+	done := make(chan struct{})
+	deadline := timing.Now() + 25*time.Millisecond
+	assert.True(t, d.UntilOrDone(deadline, done))
+	assert.GreaterOrEqual(t, timing.Now(), deadline-slop)
+	assert.Equal(t, uint64(1), d.Stats().Count)
+
+	// done while waiting
+	time.AfterFunc(20*time.Millisecond, func() { close(done) })
+	start := timing.Now()
+	assert.False(t, d.UntilOrDone(start+time.Hour, done))
+	assert.Less(t, timing.Now()-start, 100*time.Millisecond)
+
+	// already done
+	start = timing.Now()
+	assert.False(t, d.UntilOrDone(start+time.Hour, done))
+	assert.Less(t, timing.Now()-start, time.Millisecond)
+	assert.Equal(t, uint64(1), d.Stats().Count)
+}
+
+func TestDelayAllocs(t *testing.T) {
+	d, err := timing.NewDelay()
+	require.Nil(t, err)
+	defer d.Close()
+	allocs := testing.AllocsPerRun(100, func() { d.Wait(time.Microsecond) })
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkDelayWait(b *testing.B) {
+	modes := []struct {
+		name string
+		mode timing.Mode
+	}{
+		{"hybrid", timing.Hybrid},
+		{"busy", timing.BusyWait},
+		{"timerfd", timing.Timerfd},
+		{"sleep", timing.Sleep},
+	}
+	for _, m := range modes {
+		bf := func(b *testing.B) {
+			d, err := timing.NewDelay(timing.WithMode(m.mode))
+			require.Nil(b, err)
+			defer d.Close()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				d.Wait(500 * time.Nanosecond)
+			}
+			s := d.Stats()
+			b.ReportMetric(float64(s.MeanError().Nanoseconds()), "ns-late/op")
+		}
+		b.Run(m.name, bf)
+	}
+}
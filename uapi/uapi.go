@@ -11,6 +11,7 @@ package uapi
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
@@ -170,6 +171,24 @@ func (fd fdReader) Read(b []byte) (int, error) {
 	return unix.Read(int(fd), b[:])
 }
 
+// readStruct reads a single record from the fd directly into the memory
+// backing b, so avoiding the allocations of binary.Read.
+//
+// The kernel returns whole records, so a partial read is an error.
+func readStruct(fd uintptr, b []byte) error {
+	n, err := unix.Read(int(fd), b)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return io.EOF
+	}
+	if n < len(b) {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
 // ReadEvent reads a single event from a requested line.
 //
 // The fd is a requested line, as returned by GetLineEvent.
@@ -178,7 +197,7 @@ func (fd fdReader) Read(b []byte) (int, error) {
 // be ready to read.
 func ReadEvent(fd uintptr) (EventData, error) {
 	var ed EventData
-	err := binary.Read(fdReader(fd), nativeEndian, &ed)
+	err := readStruct(fd, (*[unsafe.Sizeof(ed)]byte)(unsafe.Pointer(&ed))[:])
 	return ed, err
 }
 
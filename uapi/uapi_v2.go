@@ -115,7 +115,7 @@ func WatchLineInfoV2(fd uintptr, info *LineInfoV2) error {
 // be ready to read.
 func ReadLineEvent(fd uintptr) (LineEvent, error) {
 	var le LineEvent
-	err := binary.Read(fdReader(fd), nativeEndian, &le)
+	err := readStruct(fd, (*[unsafe.Sizeof(le)]byte)(unsafe.Pointer(&le))[:])
 	return le, err
 }
 
@@ -9,10 +9,12 @@ package uapi_test
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"syscall"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -2379,6 +2381,40 @@ func readLineEventTimeout(fd int32, t time.Duration) (*uapi.LineEvent, error) {
 	return &evt, nil
 }
 
+func TestReadLineEventPipe(t *testing.T) {
+	var p [2]int
+	err := unix.Pipe2(p[:], unix.O_CLOEXEC)
+	require.Nil(t, err)
+	defer unix.Close(p[0])
+	defer unix.Close(p[1])
+
+	xevt := uapi.LineEvent{
+		Timestamp: 0x0102030405060708,
+		ID:        uapi.LineEventFallingEdge,
+		Offset:    3,
+		Seqno:     42,
+		LineSeqno: 7,
+	}
+	buf := (*[unsafe.Sizeof(xevt)]byte)(unsafe.Pointer(&xevt))[:]
+	_, err = unix.Write(p[1], buf)
+	require.Nil(t, err)
+	evt, err := uapi.ReadLineEvent(uintptr(p[0]))
+	assert.Nil(t, err)
+	assert.Equal(t, xevt, evt)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		unix.Write(p[1], buf)
+		uapi.ReadLineEvent(uintptr(p[0]))
+	})
+	assert.Zero(t, allocs)
+
+	// short read
+	_, err = unix.Write(p[1], buf[:8])
+	require.Nil(t, err)
+	_, err = uapi.ReadLineEvent(uintptr(p[0]))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
 func TestLineAttribute(t *testing.T) {
 	var la uapi.LineAttribute
 